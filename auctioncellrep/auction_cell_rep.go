@@ -9,6 +9,7 @@ import (
 
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/executor/containermetrics"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/rep"
 	"code.cloudfoundry.org/rep/evacuation/evacuation_context"
@@ -22,6 +23,12 @@ type AuctionCellClient interface {
 	Reset() error
 }
 
+// CanaryReporter provides the outcome of the cell's most recent canary
+// container, or nil if none has run yet.
+type CanaryReporter interface {
+	LastResult() *rep.CanaryResult
+}
+
 var ErrPreloadedRootFSNotFound = errors.New("preloaded rootfs path not found")
 var ErrCellUnhealthy = errors.New("internal cell healthcheck failed")
 var ErrCellIdMismatch = errors.New("workload cell ID does not match this cell")
@@ -42,6 +49,11 @@ type AuctionCellRep struct {
 	optionalPlacementTags    []string
 	proxyMemoryAllocation    int
 	enableContainerProxy     bool
+	domainQuotas             rep.DomainQuotas
+	stateCache               *ExecutorStateCache
+	canary                   CanaryReporter
+	lifecycles               *rep.LifecycleRegistry
+	reservedResources        rep.Resources
 }
 
 func New(
@@ -58,8 +70,17 @@ func New(
 	optionalPlacementTags []string,
 	proxyMemoryAllocation int,
 	enableContainerProxy bool,
+	domainQuotas rep.DomainQuotas,
+	stateCache *ExecutorStateCache,
+	canary CanaryReporter,
+	lifecycles *rep.LifecycleRegistry,
+	reservedResources rep.Resources,
 ) *AuctionCellRep {
-	return &AuctionCellRep{
+	if lifecycles == nil {
+		lifecycles = rep.NewLifecycleRegistry()
+	}
+
+	a := &AuctionCellRep{
 		cellID:                   cellID,
 		repURL:                   repURL,
 		stackPathMap:             preloadedStackPathMap,
@@ -73,7 +94,19 @@ func New(
 		optionalPlacementTags: optionalPlacementTags,
 		proxyMemoryAllocation: proxyMemoryAllocation,
 		enableContainerProxy:  enableContainerProxy,
+		domainQuotas:          domainQuotas,
+		stateCache:            stateCache,
+		canary:                canary,
+		lifecycles:            lifecycles,
+		reservedResources:     reservedResources,
 	}
+
+	lifecycles.RegisterReporter(rep.LRPLifecycle, rep.CellStateReporterFunc(a.reportLRPContainer))
+	lifecycles.RegisterReporter(rep.TaskLifecycle, rep.CellStateReporterFunc(a.reportTaskContainer))
+	lifecycles.RegisterMetricsReporter(rep.LRPLifecycle, rep.ContainerMetricsReporterFunc(a.reportLRPMetrics))
+	lifecycles.RegisterMetricsReporter(rep.TaskLifecycle, rep.ContainerMetricsReporterFunc(reportTaskMetrics))
+
+	return a
 }
 
 func rootFSProviders(preloaded rep.StackPathMap, arbitrary []string) rep.RootFSProviders {
@@ -140,39 +173,38 @@ func (a *AuctionCellRep) State(logger lager.Logger) (rep.CellState, bool, error)
 	logger = logger.Session("auction-state")
 	logger.Info("providing")
 
-	containers, err := a.client.ListContainers(logger)
+	executorState, err := a.executorState(logger)
 	if err != nil {
-		logger.Error("failed-to-fetch-containers", err)
 		return rep.CellState{}, false, err
 	}
 
-	totalResources, err := a.client.TotalResources(logger)
-	if err != nil {
-		logger.Error("failed-to-get-total-resources", err)
-		return rep.CellState{}, false, err
-	}
+	containers := executorState.Containers
+	totalResources := executorState.TotalResources
+	availableResources := executorState.RemainingResources
+	volumeDrivers := executorState.VolumeDrivers
 
-	availableResources, err := a.client.RemainingResources(logger)
-	if err != nil {
-		logger.Error("failed-to-get-remaining-resource", err)
-		return rep.CellState{}, false, err
-	}
-
-	volumeDrivers, err := a.client.VolumeDrivers(logger)
-	if err != nil {
-		logger.Error("failed-to-get-volume-drivers", err)
-		return rep.CellState{}, false, err
-	}
-
-	lrps := []rep.LRP{}
-	tasks := []rep.Task{}
-	startingContainerCount := 0
+	state := rep.NewCellState(
+		a.cellID,
+		a.repURL,
+		a.rootFSProviders,
+		a.convertResources(availableResources),
+		a.convertResources(totalResources),
+		[]rep.LRP{},
+		[]rep.Task{},
+		a.zone,
+		0,
+		a.evacuationReporter.Evacuating(),
+		volumeDrivers,
+		a.placementTags,
+		a.optionalPlacementTags,
+	)
+	a.reserveResources(&state, containers)
 
 	for i := range containers {
 		container := &containers[i]
 
 		if containerIsStarting(container) {
-			startingContainerCount++
+			state.StartingContainerCount++
 		}
 
 		if container.Tags == nil {
@@ -180,88 +212,28 @@ func (a *AuctionCellRep) State(logger lager.Logger) (rep.CellState, bool, error)
 			continue
 		}
 
-		placementTagsJSON := container.Tags[rep.PlacementTagsTag]
-		var placementTags []string
-		err := json.Unmarshal([]byte(placementTagsJSON), &placementTags)
-		if err != nil {
-			logger.Error("cannot-unmarshal-placement-tags", err, lager.Data{"placement-tags": placementTagsJSON})
-		}
-
-		volumeDriversJSON := container.Tags[rep.VolumeDriversTag]
-		var volumeDrivers []string
-		err = json.Unmarshal([]byte(volumeDriversJSON), &volumeDrivers)
-		if err != nil {
-			logger.Error("cannot-unmarshal-volume-drivers", err, lager.Data{"volume-drivers": volumeDriversJSON})
-		}
-
-		resource := rep.Resource{MemoryMB: int32(container.MemoryMB), DiskMB: int32(container.DiskMB), MaxPids: int32(container.MaxPids)}
-		placementConstraint := rep.PlacementConstraint{
-			RootFs:        rootFSURLFromPath(container.RootFSPath, a.stackPathMap),
-			VolumeDrivers: volumeDrivers,
-			PlacementTags: placementTags,
-		}
-
-		switch container.Tags[rep.LifecycleTag] {
-		case rep.LRPLifecycle:
-			key, err := rep.ActualLRPKeyFromTags(container.Tags)
-			if err != nil {
-				logger.Error("failed-to-extract-key", err)
-				continue
-			}
-			instanceKey, err := rep.ActualLRPInstanceKeyFromContainer(*container, a.cellID)
-			if err != nil {
-				logger.Error("failed-to-extract-key", err)
-				continue
-			}
-			var state string
-			switch container.State {
-			case executor.StateRunning:
-				state = models.ActualLRPStateRunning
-			case executor.StateCompleted:
-				state = "SHUTDOWN"
-				if container.RunResult.Failed {
-					state = "CRASHED"
-				}
-			default:
-				state = models.ActualLRPStateClaimed
-			}
-			lrp := rep.NewLRP(instanceKey.InstanceGuid, *key, resource, placementConstraint)
-			lrp.State = state
-			lrps = append(lrps, lrp)
-		case rep.TaskLifecycle:
-			domain := container.Tags[rep.DomainTag]
-			state := models.Task_Running
-			if container.State == executor.StateCompleted {
-				state = models.Task_Completed
-			}
-			task := rep.NewTask(container.Guid, domain, resource, placementConstraint)
-			task.State = state
-			task.Failed = container.RunResult.Failed
-			tasks = append(tasks, task)
+		reporter, ok := a.lifecycles.Reporter(container.Tags[rep.LifecycleTag])
+		if !ok {
+			continue
 		}
+		reporter.ReportContainer(logger, *container, &state)
 	}
 
-	state := rep.NewCellState(
-		a.cellID,
-		a.repURL,
-		a.rootFSProviders,
-		a.convertResources(availableResources),
-		a.convertResources(totalResources),
-		lrps,
-		tasks,
-		a.zone,
-		startingContainerCount,
-		a.evacuationReporter.Evacuating(),
-		volumeDrivers,
-		a.placementTags,
-		a.optionalPlacementTags,
-	)
+	state.DomainUsage = domainUsage(containers)
 
 	healthy := a.client.Healthy(logger)
 	if !healthy {
 		logger.Error("failed-garden-health-check", nil)
 	}
 
+	if a.canary != nil {
+		state.Canary = a.canary.LastResult()
+		if state.Canary != nil && !state.Canary.Healthy {
+			logger.Error("failed-canary-health-check", nil, lager.Data{"canary": state.Canary})
+			healthy = false
+		}
+	}
+
 	logger.Info("provided", lager.Data{
 		"available-resources": state.AvailableResources,
 		"total-resources":     state.TotalResources,
@@ -273,18 +245,135 @@ func (a *AuctionCellRep) State(logger lager.Logger) (rep.CellState, bool, error)
 	return state, healthy, nil
 }
 
+// reserveResources withholds the resources reserved for system daemons from
+// the capacity offered to the auction. Daemon containers that exist already
+// hold their share of the reservation, so only the remainder is taken from
+// the available resources.
+func (a *AuctionCellRep) reserveResources(state *rep.CellState, containers []executor.Container) {
+	unclaimed := a.reservedResources
+	for i := range containers {
+		if containers[i].Tags[rep.LifecycleTag] != rep.DaemonLifecycle {
+			continue
+		}
+		unclaimed = subtractResources(unclaimed, rep.NewResources(int32(containers[i].MemoryMB), int32(containers[i].DiskMB), 1))
+	}
+
+	state.TotalResources = subtractResources(state.TotalResources, a.reservedResources)
+	state.AvailableResources = subtractResources(state.AvailableResources, unclaimed)
+}
+
+func subtractResources(from, resources rep.Resources) rep.Resources {
+	result := rep.NewResources(from.MemoryMB-resources.MemoryMB, from.DiskMB-resources.DiskMB, from.Containers-resources.Containers)
+	if result.MemoryMB < 0 {
+		result.MemoryMB = 0
+	}
+	if result.DiskMB < 0 {
+		result.DiskMB = 0
+	}
+	if result.Containers < 0 {
+		result.Containers = 0
+	}
+	return result
+}
+
+// reportLRPContainer adds an LRP container to the cell state.
+func (a *AuctionCellRep) reportLRPContainer(logger lager.Logger, container executor.Container, state *rep.CellState) {
+	key, err := rep.ActualLRPKeyFromTags(container.Tags)
+	if err != nil {
+		logger.Error("failed-to-extract-key", err)
+		return
+	}
+	instanceKey, err := rep.ActualLRPInstanceKeyFromContainer(container, a.cellID)
+	if err != nil {
+		logger.Error("failed-to-extract-key", err)
+		return
+	}
+
+	var lrpState string
+	switch container.State {
+	case executor.StateRunning:
+		lrpState = models.ActualLRPStateRunning
+	case executor.StateCompleted:
+		lrpState = "SHUTDOWN"
+		if container.RunResult.Failed {
+			lrpState = "CRASHED"
+		}
+	default:
+		lrpState = models.ActualLRPStateClaimed
+	}
+
+	resource, placementConstraint := a.containerPlacement(logger, container)
+	lrp := rep.NewLRP(instanceKey.InstanceGuid, *key, resource, placementConstraint)
+	lrp.State = lrpState
+	state.LRPs = append(state.LRPs, lrp)
+}
+
+// reportTaskContainer adds a task container to the cell state.
+func (a *AuctionCellRep) reportTaskContainer(logger lager.Logger, container executor.Container, state *rep.CellState) {
+	domain := container.Tags[rep.DomainTag]
+	taskState := models.Task_Running
+	if container.State == executor.StateCompleted {
+		taskState = models.Task_Completed
+	}
+
+	resource, placementConstraint := a.containerPlacement(logger, container)
+	task := rep.NewTask(container.Guid, domain, resource, placementConstraint)
+	task.State = taskState
+	task.Failed = container.RunResult.Failed
+	state.Tasks = append(state.Tasks, task)
+}
+
+// containerPlacement recovers the resources and placement constraint of a
+// container from its allocation and tags.
+func (a *AuctionCellRep) containerPlacement(logger lager.Logger, container executor.Container) (rep.Resource, rep.PlacementConstraint) {
+	placementTagsJSON := container.Tags[rep.PlacementTagsTag]
+	var placementTags []string
+	err := json.Unmarshal([]byte(placementTagsJSON), &placementTags)
+	if err != nil {
+		logger.Error("cannot-unmarshal-placement-tags", err, lager.Data{"placement-tags": placementTagsJSON})
+	}
+
+	volumeDriversJSON := container.Tags[rep.VolumeDriversTag]
+	var volumeDrivers []string
+	err = json.Unmarshal([]byte(volumeDriversJSON), &volumeDrivers)
+	if err != nil {
+		logger.Error("cannot-unmarshal-volume-drivers", err, lager.Data{"volume-drivers": volumeDriversJSON})
+	}
+
+	resource := rep.Resource{MemoryMB: int32(container.MemoryMB), DiskMB: int32(container.DiskMB), MaxPids: int32(container.MaxPids)}
+	placementConstraint := rep.PlacementConstraint{
+		RootFs:        rootFSURLFromPath(container.RootFSPath, a.stackPathMap),
+		VolumeDrivers: volumeDrivers,
+		PlacementTags: placementTags,
+	}
+	return resource, placementConstraint
+}
+
 func (a *AuctionCellRep) Metrics(logger lager.Logger) (*rep.ContainerMetricsCollection, error) {
-	var lrpMetrics = []rep.LRPMetric{}
-	var taskMetrics = []rep.TaskMetric{}
+	collection := &rep.ContainerMetricsCollection{
+		CellID: a.cellID,
+		LRPs:   []rep.LRPMetric{},
+		Tasks:  []rep.TaskMetric{},
+	}
 
 	logger = logger.Session("metrics-collection")
 	logger.Info("starting")
 	defer logger.Info("complete")
 
-	containers, err := a.client.ListContainers(logger)
-	if err != nil {
-		logger.Error("failed-to-fetch-containers", err)
-		return nil, err
+	var containers []executor.Container
+	if a.stateCache != nil {
+		executorState, err := a.stateCache.Get(logger)
+		if err != nil {
+			return nil, err
+		}
+		containers = executorState.Containers
+	} else {
+		var err error
+		containers, err = a.client.ListContainers(logger)
+		if err != nil {
+			logger.Error("failed-to-fetch-containers", err)
+			return nil, err
+		}
 	}
 
 	metrics := a.containerMetricsProvider.Metrics()
@@ -302,40 +391,50 @@ func (a *AuctionCellRep) Metrics(logger lager.Logger) (*rep.ContainerMetricsColl
 			continue
 		}
 
-		switch container.Tags[rep.LifecycleTag] {
-		case rep.LRPLifecycle:
-			key, err := rep.ActualLRPKeyFromTags(container.Tags)
-			if err != nil {
-				logger.Error("failed-to-extract-key", err)
-				continue
-			}
-			instanceKey, err := rep.ActualLRPInstanceKeyFromContainer(container, a.cellID)
-			if err != nil {
-				logger.Error("failed-to-extract-key", err)
-				continue
-			}
-
-			lrpMetric := rep.LRPMetric{
-				ProcessGUID:            key.ProcessGuid,
-				Index:                  key.Index,
-				InstanceGUID:           instanceKey.InstanceGuid,
-				CachedContainerMetrics: *containerMetrics,
-			}
-			lrpMetrics = append(lrpMetrics, lrpMetric)
-		case rep.TaskLifecycle:
-			taskMetric := rep.TaskMetric{
-				TaskGUID:               container.Guid,
-				CachedContainerMetrics: *containerMetrics,
-			}
-			taskMetrics = append(taskMetrics, taskMetric)
+		reporter, ok := a.lifecycles.MetricsReporter(container.Tags[rep.LifecycleTag])
+		if !ok {
+			continue
 		}
+		reporter.ReportMetrics(logger, container, *containerMetrics, collection)
 	}
 
-	return &rep.ContainerMetricsCollection{
-		CellID: a.cellID,
-		LRPs:   lrpMetrics,
-		Tasks:  taskMetrics,
-	}, nil
+	return collection, nil
+}
+
+// reportLRPMetrics adds the metrics of an LRP container to the collection.
+func (a *AuctionCellRep) reportLRPMetrics(logger lager.Logger, container executor.Container, metrics containermetrics.CachedContainerMetrics, collection *rep.ContainerMetricsCollection) {
+	key, err := rep.ActualLRPKeyFromTags(container.Tags)
+	if err != nil {
+		logger.Error("failed-to-extract-key", err)
+		return
+	}
+	instanceKey, err := rep.ActualLRPInstanceKeyFromContainer(container, a.cellID)
+	if err != nil {
+		logger.Error("failed-to-extract-key", err)
+		return
+	}
+
+	collection.LRPs = append(collection.LRPs, rep.LRPMetric{
+		ProcessGUID:            key.ProcessGuid,
+		Index:                  key.Index,
+		InstanceGUID:           instanceKey.InstanceGuid,
+		CachedContainerMetrics: metrics,
+	})
+}
+
+// reportTaskMetrics adds the metrics of a task container to the collection.
+func reportTaskMetrics(logger lager.Logger, container executor.Container, metrics containermetrics.CachedContainerMetrics, collection *rep.ContainerMetricsCollection) {
+	collection.Tasks = append(collection.Tasks, rep.TaskMetric{
+		TaskGUID:               container.Guid,
+		CachedContainerMetrics: metrics,
+	})
+}
+
+func (a *AuctionCellRep) executorState(logger lager.Logger) (ExecutorState, error) {
+	if a.stateCache != nil {
+		return a.stateCache.Get(logger)
+	}
+	return fetchExecutorState(logger, a.client)
 }
 
 func containerIsStarting(container *executor.Container) bool {
@@ -380,6 +479,17 @@ func (a *AuctionCellRep) Perform(logger lager.Logger, work rep.Work) (rep.Work,
 		return work, nil
 	}
 
+	if len(a.domainQuotas) > 0 {
+		var overQuotaWork rep.Work
+		var err error
+		work, overQuotaWork, err = a.applyDomainQuotas(logger, work)
+		if err != nil {
+			return work, err
+		}
+		failedWork.LRPs = append(failedWork.LRPs, overQuotaWork.LRPs...)
+		failedWork.Tasks = append(failedWork.Tasks, overQuotaWork.Tasks...)
+	}
+
 	if len(work.LRPs) > 0 {
 		lrpLogger := logger.Session("lrp-allocate-instances")
 
@@ -424,6 +534,79 @@ func (a *AuctionCellRep) Perform(logger lager.Logger, work rep.Work) (rep.Work,
 	return failedWork, nil
 }
 
+// applyDomainQuotas splits work into the LRPs and tasks that fit within their
+// domain's quota, given the containers already on the cell, and those that
+// would exceed it.
+func (a *AuctionCellRep) applyDomainQuotas(logger lager.Logger, work rep.Work) (rep.Work, rep.Work, error) {
+	logger = logger.Session("apply-domain-quotas")
+
+	containers, err := a.client.ListContainers(logger)
+	if err != nil {
+		logger.Error("failed-to-fetch-containers", err)
+		return work, rep.Work{}, err
+	}
+
+	usage := domainUsage(containers)
+	admit := func(domain string, resource *rep.Resource) bool {
+		quota, ok := a.domainQuotas[domain]
+		if !ok {
+			return true
+		}
+
+		current := usage[domain]
+		problems := quota.Exceeds(current, resource)
+		if len(problems) > 0 {
+			logger.Info("domain-quota-exceeded", lager.Data{"domain": domain, "problems": problems, "usage": current})
+			return false
+		}
+
+		current.Add(resource)
+		usage[domain] = current
+		return true
+	}
+
+	admitted := rep.Work{CellID: work.CellID}
+	rejected := rep.Work{}
+
+	for _, lrp := range work.LRPs {
+		if admit(lrp.Domain, &lrp.Resource) {
+			admitted.LRPs = append(admitted.LRPs, lrp)
+		} else {
+			rejected.LRPs = append(rejected.LRPs, lrp)
+		}
+	}
+
+	for _, task := range work.Tasks {
+		if admit(task.Domain, &task.Resource) {
+			admitted.Tasks = append(admitted.Tasks, task)
+		} else {
+			rejected.Tasks = append(rejected.Tasks, task)
+		}
+	}
+
+	return admitted, rejected, nil
+}
+
+func domainUsage(containers []executor.Container) map[string]rep.Resources {
+	usage := map[string]rep.Resources{}
+	for i := range containers {
+		container := &containers[i]
+		if container.Tags == nil {
+			continue
+		}
+
+		domain, ok := container.Tags[rep.DomainTag]
+		if !ok {
+			continue
+		}
+
+		resources := usage[domain]
+		resources.Add(&rep.Resource{MemoryMB: int32(container.MemoryMB), DiskMB: int32(container.DiskMB)})
+		usage[domain] = resources
+	}
+	return usage
+}
+
 func (a *AuctionCellRep) lrpsToAllocationRequest(lrps []rep.LRP) ([]executor.AllocationRequest, map[string]*rep.LRP, []rep.LRP) {
 	requests := make([]executor.AllocationRequest, 0, len(lrps))
 	untranslatedLRPs := make([]rep.LRP, 0)
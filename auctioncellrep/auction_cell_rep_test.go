@@ -8,6 +8,7 @@ import (
 	"code.cloudfoundry.org/executor"
 	"code.cloudfoundry.org/executor/containermetrics"
 	fake_client "code.cloudfoundry.org/executor/fakes"
+	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
 	"code.cloudfoundry.org/rep"
 	"code.cloudfoundry.org/rep/auctioncellrep"
@@ -25,6 +26,14 @@ const (
 	linuxPath  = "/data/rootfs/linux"
 )
 
+type fakeCanaryReporter struct {
+	result *rep.CanaryResult
+}
+
+func (f fakeCanaryReporter) LastResult() *rep.CanaryResult {
+	return f.result
+}
+
 var _ = Describe("AuctionCellRep", func() {
 	var (
 		cellRep                      *auctioncellrep.AuctionCellRep
@@ -41,6 +50,10 @@ var _ = Describe("AuctionCellRep", func() {
 		placementTags, optionalPlacementTags []string
 		proxyMemoryAllocation                int
 		enableContainerProxy                 bool
+		domainQuotas                         rep.DomainQuotas
+		canary                               auctioncellrep.CanaryReporter
+		lifecycles                           *rep.LifecycleRegistry
+		reservedResources                    rep.Resources
 	)
 
 	BeforeEach(func() {
@@ -59,6 +72,10 @@ var _ = Describe("AuctionCellRep", func() {
 		commonErr = errors.New("Failed to fetch")
 		proxyMemoryAllocation = 0
 		enableContainerProxy = false
+		domainQuotas = nil
+		canary = nil
+		lifecycles = nil
+		reservedResources = rep.Resources{}
 		client.HealthyReturns(true)
 	})
 
@@ -77,6 +94,11 @@ var _ = Describe("AuctionCellRep", func() {
 			optionalPlacementTags,
 			proxyMemoryAllocation,
 			enableContainerProxy,
+			domainQuotas,
+			nil,
+			canary,
+			lifecycles,
+			reservedResources,
 		)
 	})
 
@@ -179,6 +201,31 @@ var _ = Describe("AuctionCellRep", func() {
 				Expect(healthy).To(BeTrue())
 			})
 
+			Context("with a lifecycle registered outside the cell rep", func() {
+				var reported []string
+
+				BeforeEach(func() {
+					reported = nil
+					lifecycles = rep.NewLifecycleRegistry()
+					lifecycles.RegisterReporter("daemon", rep.CellStateReporterFunc(func(_ lager.Logger, container executor.Container, state *rep.CellState) {
+						reported = append(reported, container.Guid)
+					}))
+					containers = []executor.Container{
+						createContainer(executor.StateRunning, "daemon"),
+						createContainer(executor.StateRunning, rep.TaskLifecycle),
+					}
+				})
+
+				It("hands the container to the registered reporter", func() {
+					Expect(reported).To(ConsistOf("some-container-guid"))
+				})
+
+				It("still reports the built-in lifecycles", func() {
+					Expect(state.Tasks).To(HaveLen(1))
+					Expect(state.LRPs).To(BeEmpty())
+				})
+			})
+
 			Context("with TaskLifecycle", func() {
 				createTaskContainer := func(state executor.State) executor.Container {
 					return createContainer(state, rep.TaskLifecycle)
@@ -454,6 +501,36 @@ var _ = Describe("AuctionCellRep", func() {
 			Expect(state.VolumeDrivers).To(ConsistOf(volumeDrivers))
 		})
 
+		Context("when resources are reserved for daemons", func() {
+			BeforeEach(func() {
+				reservedResources = rep.NewResources(256, 512, 2)
+				client.TotalResourcesReturns(executor.ExecutorResources{MemoryMB: 1024, DiskMB: 2048, Containers: 4}, nil)
+				client.RemainingResourcesReturns(executor.ExecutorResources{MemoryMB: 512, DiskMB: 1024, Containers: 2}, nil)
+			})
+
+			It("withholds the reservation from the total and available resources", func() {
+				state, _, err := cellRep.State(logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(state.TotalResources).To(Equal(rep.NewResources(768, 1536, 2)))
+				Expect(state.AvailableResources).To(Equal(rep.NewResources(256, 512, 0)))
+			})
+
+			Context("and a daemon container holds part of the reservation", func() {
+				BeforeEach(func() {
+					daemon := createContainer(executor.StateRunning, rep.DaemonLifecycle)
+					daemon.Resource = executor.NewResource(128, 256, 0, linuxPath)
+					client.ListContainersReturns([]executor.Container{daemon}, nil)
+				})
+
+				It("only withholds the unclaimed reservation from the available resources", func() {
+					state, _, err := cellRep.State(logger)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(state.TotalResources).To(Equal(rep.NewResources(768, 1536, 2)))
+					Expect(state.AvailableResources).To(Equal(rep.NewResources(384, 768, 1)))
+				})
+			})
+		})
+
 		Context("when the cell is not healthy", func() {
 			BeforeEach(func() {
 				client.HealthyReturns(false)
@@ -512,6 +589,71 @@ var _ = Describe("AuctionCellRep", func() {
 			})
 		})
 
+		Context("when containers belong to domains", func() {
+			BeforeEach(func() {
+				lrpContainer := createContainer(executor.StateRunning, rep.LRPLifecycle)
+				taskContainer := createContainer(executor.StateRunning, rep.TaskLifecycle)
+				taskContainer.Guid = "some-task-guid"
+				otherContainer := createContainer(executor.StateReserved, rep.LRPLifecycle)
+				otherContainer.Guid = "some-other-container-guid"
+				otherContainer.Tags[rep.DomainTag] = "other-domain"
+				client.ListContainersReturns([]executor.Container{lrpContainer, taskContainer, otherContainer}, nil)
+			})
+
+			It("reports the resources used by each domain", func() {
+				state, _, err := cellRep.State(logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(state.DomainUsage).To(Equal(map[string]rep.Resources{
+					"domain":       rep.NewResources(40, 20, 2),
+					"other-domain": rep.NewResources(20, 10, 1),
+				}))
+			})
+		})
+
+		Context("when a canary is configured", func() {
+			Context("when the canary has not run yet", func() {
+				BeforeEach(func() {
+					canary = fakeCanaryReporter{}
+				})
+
+				It("reports a healthy cell without a canary result", func() {
+					state, healthy, err := cellRep.State(logger)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(healthy).To(BeTrue())
+					Expect(state.Canary).To(BeNil())
+				})
+			})
+
+			Context("when the canary is healthy", func() {
+				var result *rep.CanaryResult
+
+				BeforeEach(func() {
+					result = &rep.CanaryResult{Healthy: true, Succeeded: true}
+					canary = fakeCanaryReporter{result: result}
+				})
+
+				It("reports the canary result", func() {
+					state, healthy, err := cellRep.State(logger)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(healthy).To(BeTrue())
+					Expect(state.Canary).To(Equal(result))
+				})
+			})
+
+			Context("when the canary is unhealthy", func() {
+				BeforeEach(func() {
+					canary = fakeCanaryReporter{result: &rep.CanaryResult{Healthy: false, ConsecutiveFailures: 3}}
+				})
+
+				It("reports the cell as unhealthy", func() {
+					state, healthy, err := cellRep.State(logger)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(healthy).To(BeFalse())
+					Expect(state.Canary.ConsecutiveFailures).To(Equal(3))
+				})
+			})
+		})
+
 		Context("when optional placement tags have been set", func() {
 			BeforeEach(func() {
 				optionalPlacementTags = []string{"baa", "cluck"}
@@ -567,6 +709,78 @@ var _ = Describe("AuctionCellRep", func() {
 			})
 		})
 
+		Context("when domain quotas are configured", func() {
+			var lrp1, lrp2 rep.LRP
+
+			BeforeEach(func() {
+				domainQuotas = rep.DomainQuotas{
+					"tests": rep.DomainQuota{MemoryMB: 3072},
+				}
+
+				existing := createContainer(executor.StateRunning, rep.LRPLifecycle)
+				existing.MemoryMB = 1024
+				existing.Tags[rep.DomainTag] = "tests"
+				client.ListContainersReturns([]executor.Container{existing}, nil)
+
+				lrp1 = rep.NewLRP(
+					"ig-1",
+					models.NewActualLRPKey("process-guid", 1, "tests"),
+					rep.NewResource(1024, 1024, 100),
+					rep.NewPlacementConstraint(linuxRootFSURL, nil, []string{}),
+				)
+				lrp2 = rep.NewLRP(
+					"ig-2",
+					models.NewActualLRPKey("process-guid", 2, "tests"),
+					rep.NewResource(2048, 1024, 100),
+					rep.NewPlacementConstraint(linuxRootFSURL, nil, []string{}),
+				)
+				task = rep.NewTask(
+					"the-task-guid",
+					"unlimited",
+					rep.NewResource(4096, 1024, 100),
+					rep.NewPlacementConstraint(linuxRootFSURL, nil, []string{}),
+				)
+
+				work = rep.Work{
+					LRPs:  []rep.LRP{lrp1, lrp2},
+					Tasks: []rep.Task{task},
+				}
+			})
+
+			It("rejects the work that would exceed the domain's quota", func() {
+				failedWork, err := cellRep.Perform(logger, work)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(failedWork.LRPs).To(ConsistOf(lrp2))
+				Expect(failedWork.Tasks).To(BeEmpty())
+			})
+
+			It("only allocates containers for the work within quota", func() {
+				_, err := cellRep.Perform(logger, work)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(client.AllocateContainersCallCount()).To(Equal(2))
+				_, lrpRequests := client.AllocateContainersArgsForCall(0)
+				Expect(lrpRequests).To(HaveLen(1))
+				Expect(lrpRequests[0].Tags[rep.ProcessIndexTag]).To(Equal("1"))
+
+				_, taskRequests := client.AllocateContainersArgsForCall(1)
+				Expect(taskRequests).To(HaveLen(1))
+				Expect(taskRequests[0].Guid).To(Equal(task.TaskGuid))
+			})
+
+			Context("when listing the containers fails", func() {
+				BeforeEach(func() {
+					client.ListContainersReturns(nil, commonErr)
+				})
+
+				It("returns the error and all the work", func() {
+					failedWork, err := cellRep.Perform(logger, work)
+					Expect(err).To(MatchError(commonErr))
+					Expect(failedWork).To(Equal(work))
+				})
+			})
+		})
+
 		Describe("performing starts", func() {
 			var (
 				lrpAuctionOne,
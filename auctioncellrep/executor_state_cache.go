@@ -0,0 +1,207 @@
+package auctioncellrep
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/lager"
+)
+
+const executorStateCacheResubscribeInterval = time.Second
+
+// ExecutorState is the view of the executor needed to report the cell's
+// state and container metrics.
+type ExecutorState struct {
+	Containers         []executor.Container
+	TotalResources     executor.ExecutorResources
+	RemainingResources executor.ExecutorResources
+	VolumeDrivers      []string
+}
+
+func fetchExecutorState(logger lager.Logger, client executor.Client) (ExecutorState, error) {
+	containers, err := client.ListContainers(logger)
+	if err != nil {
+		logger.Error("failed-to-fetch-containers", err)
+		return ExecutorState{}, err
+	}
+
+	totalResources, err := client.TotalResources(logger)
+	if err != nil {
+		logger.Error("failed-to-get-total-resources", err)
+		return ExecutorState{}, err
+	}
+
+	remainingResources, err := client.RemainingResources(logger)
+	if err != nil {
+		logger.Error("failed-to-get-remaining-resource", err)
+		return ExecutorState{}, err
+	}
+
+	volumeDrivers, err := client.VolumeDrivers(logger)
+	if err != nil {
+		logger.Error("failed-to-get-volume-drivers", err)
+		return ExecutorState{}, err
+	}
+
+	return ExecutorState{
+		Containers:         containers,
+		TotalResources:     totalResources,
+		RemainingResources: remainingResources,
+		VolumeDrivers:      volumeDrivers,
+	}, nil
+}
+
+type executorStateFetch struct {
+	done  chan struct{}
+	state ExecutorState
+	err   error
+}
+
+// ExecutorStateCache serves the executor state for up to ttl after it was
+// fetched. Concurrent callers share a single fetch, and the cached state is
+// dropped whenever the executor emits a lifecycle event. While the cache is
+// not subscribed to executor events every call goes to the executor.
+type ExecutorStateCache struct {
+	logger lager.Logger
+	client executor.Client
+	clock  clock.Clock
+	ttl    time.Duration
+
+	lock       sync.Mutex
+	subscribed bool
+	state      *ExecutorState
+	fetchedAt  time.Time
+	inflight   *executorStateFetch
+}
+
+func NewExecutorStateCache(logger lager.Logger, client executor.Client, clock clock.Clock, ttl time.Duration) *ExecutorStateCache {
+	return &ExecutorStateCache{
+		logger: logger,
+		client: client,
+		clock:  clock,
+		ttl:    ttl,
+	}
+}
+
+func (c *ExecutorStateCache) Get(logger lager.Logger) (ExecutorState, error) {
+	c.lock.Lock()
+
+	if !c.subscribed {
+		c.lock.Unlock()
+		return fetchExecutorState(logger, c.client)
+	}
+
+	if c.state != nil && c.clock.Since(c.fetchedAt) < c.ttl {
+		state := *c.state
+		c.lock.Unlock()
+		logger.Debug("using-cached-executor-state")
+		return state, nil
+	}
+
+	if fetch := c.inflight; fetch != nil {
+		c.lock.Unlock()
+		<-fetch.done
+		return fetch.state, fetch.err
+	}
+
+	fetch := &executorStateFetch{done: make(chan struct{})}
+	c.inflight = fetch
+	c.lock.Unlock()
+
+	fetch.state, fetch.err = fetchExecutorState(logger, c.client)
+
+	c.lock.Lock()
+	// an invalidation while fetching clears inflight, so the result may
+	// already be stale and must not be cached
+	if c.inflight == fetch {
+		c.inflight = nil
+		if fetch.err == nil {
+			state := fetch.state
+			c.state = &state
+			c.fetchedAt = c.clock.Now()
+		}
+	}
+	c.lock.Unlock()
+
+	close(fetch.done)
+	return fetch.state, fetch.err
+}
+
+func (c *ExecutorStateCache) Invalidate() {
+	c.lock.Lock()
+	c.state = nil
+	c.inflight = nil
+	c.lock.Unlock()
+}
+
+func (c *ExecutorStateCache) setSubscribed(subscribed bool) {
+	c.lock.Lock()
+	c.subscribed = subscribed
+	c.state = nil
+	c.inflight = nil
+	c.lock.Unlock()
+}
+
+// Run keeps the cache subscribed to executor events, resubscribing whenever
+// the event stream closes.
+func (c *ExecutorStateCache) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	logger := c.logger.Session("executor-state-cache", lager.Data{"ttl": c.ttl.String()})
+	logger.Info("starting")
+	defer logger.Info("finished")
+
+	close(ready)
+
+	for {
+		source, err := c.client.SubscribeToEvents(logger)
+		if err != nil {
+			logger.Error("failed-subscribing-to-events", err)
+		} else {
+			logger.Info("subscribed-to-events")
+			c.setSubscribed(true)
+
+			done := c.invalidateOnEvents(source)
+			select {
+			case <-done:
+				c.setSubscribed(false)
+				source.Close()
+				logger.Info("event-stream-closed")
+			case signal := <-signals:
+				logger.Info("received-signal", lager.Data{"signal": signal.String()})
+				source.Close()
+				return nil
+			}
+		}
+
+		timer := c.clock.NewTimer(executorStateCacheResubscribeInterval)
+		select {
+		case <-timer.C():
+		case signal := <-signals:
+			timer.Stop()
+			logger.Info("received-signal", lager.Data{"signal": signal.String()})
+			return nil
+		}
+	}
+}
+
+func (c *ExecutorStateCache) invalidateOnEvents(source executor.EventSource) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for {
+			event, err := source.Next()
+			if err != nil {
+				return
+			}
+
+			if _, ok := event.(executor.LifecycleEvent); ok {
+				c.Invalidate()
+			}
+		}
+	}()
+
+	return done
+}
@@ -0,0 +1,186 @@
+package auctioncellrep_test
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/executor"
+	fake_client "code.cloudfoundry.org/executor/fakes"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/rep/auctioncellrep"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("ExecutorStateCache", func() {
+	const ttl = 5 * time.Second
+
+	var (
+		client    *fake_client.FakeClient
+		logger    *lagertest.TestLogger
+		fakeClock *fakeclock.FakeClock
+		events    chan executor.Event
+
+		cache   *auctioncellrep.ExecutorStateCache
+		process ifrit.Process
+	)
+
+	BeforeEach(func() {
+		client = new(fake_client.FakeClient)
+		logger = lagertest.NewTestLogger("test")
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		events = make(chan executor.Event, 1)
+
+		eventSource := new(fake_client.FakeEventSource)
+		eventSource.NextStub = func() (executor.Event, error) {
+			ev, ok := <-events
+			if !ok {
+				return nil, errors.New("closed")
+			}
+			return ev, nil
+		}
+		client.SubscribeToEventsReturns(eventSource, nil)
+
+		client.ListContainersReturns([]executor.Container{{Guid: "some-guid"}}, nil)
+		client.TotalResourcesReturns(executor.ExecutorResources{MemoryMB: 1024}, nil)
+		client.RemainingResourcesReturns(executor.ExecutorResources{MemoryMB: 512}, nil)
+		client.VolumeDriversReturns([]string{"some-driver"}, nil)
+
+		cache = auctioncellrep.NewExecutorStateCache(logger, client, fakeClock, ttl)
+	})
+
+	AfterEach(func() {
+		if process != nil {
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive())
+			process = nil
+		}
+	})
+
+	Context("when the cache is not subscribed to executor events", func() {
+		It("fetches the state from the executor on every call", func() {
+			_, err := cache.Get(logger)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = cache.Get(logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(client.ListContainersCallCount()).To(Equal(2))
+		})
+	})
+
+	Context("when the cache is subscribed to executor events", func() {
+		BeforeEach(func() {
+			process = ifrit.Invoke(cache)
+			Eventually(client.SubscribeToEventsCallCount).Should(Equal(1))
+			Eventually(logger).Should(gbytes.Say("subscribed-to-events"))
+		})
+
+		It("returns the executor state", func() {
+			state, err := cache.Get(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state).To(Equal(auctioncellrep.ExecutorState{
+				Containers:         []executor.Container{{Guid: "some-guid"}},
+				TotalResources:     executor.ExecutorResources{MemoryMB: 1024},
+				RemainingResources: executor.ExecutorResources{MemoryMB: 512},
+				VolumeDrivers:      []string{"some-driver"},
+			}))
+		})
+
+		It("serves repeated calls within the ttl from the cache", func() {
+			_, err := cache.Get(logger)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = cache.Get(logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(client.ListContainersCallCount()).To(Equal(1))
+			Expect(client.TotalResourcesCallCount()).To(Equal(1))
+			Expect(client.RemainingResourcesCallCount()).To(Equal(1))
+			Expect(client.VolumeDriversCallCount()).To(Equal(1))
+		})
+
+		It("refetches once the ttl has elapsed", func() {
+			_, err := cache.Get(logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			fakeClock.Increment(ttl)
+
+			_, err = cache.Get(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.ListContainersCallCount()).To(Equal(2))
+		})
+
+		It("coalesces concurrent calls into a single fetch", func() {
+			release := make(chan struct{})
+			client.ListContainersStub = func(lager.Logger) ([]executor.Container, error) {
+				<-release
+				return nil, nil
+			}
+
+			results := make(chan error, 3)
+			for i := 0; i < 3; i++ {
+				go func() {
+					_, err := cache.Get(logger)
+					results <- err
+				}()
+			}
+
+			Eventually(client.ListContainersCallCount).Should(Equal(1))
+			Consistently(client.ListContainersCallCount).Should(Equal(1))
+			close(release)
+
+			for i := 0; i < 3; i++ {
+				Eventually(results).Should(Receive(BeNil()))
+			}
+			Expect(client.ListContainersCallCount()).To(Equal(1))
+		})
+
+		It("does not cache errors", func() {
+			client.ListContainersReturns(nil, errors.New("boom"))
+			_, err := cache.Get(logger)
+			Expect(err).To(MatchError("boom"))
+
+			client.ListContainersReturns(nil, nil)
+			_, err = cache.Get(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.ListContainersCallCount()).To(Equal(2))
+		})
+
+		Context("when a lifecycle event is received", func() {
+			It("invalidates the cached state", func() {
+				_, err := cache.Get(logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				events <- executor.NewContainerCompleteEvent(executor.Container{Guid: "some-guid"})
+
+				Eventually(func() int {
+					_, err := cache.Get(logger)
+					Expect(err).NotTo(HaveOccurred())
+					return client.ListContainersCallCount()
+				}).Should(Equal(2))
+			})
+		})
+
+		Context("when the event stream closes", func() {
+			It("bypasses the cache and resubscribes", func() {
+				close(events)
+				Eventually(logger).Should(gbytes.Say("event-stream-closed"))
+
+				_, err := cache.Get(logger)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = cache.Get(logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(client.ListContainersCallCount()).To(Equal(2))
+
+				Eventually(fakeClock.WatcherCount).Should(Equal(1))
+				fakeClock.Increment(time.Second)
+				Eventually(client.SubscribeToEventsCallCount).Should(Equal(2))
+			})
+		})
+	})
+})
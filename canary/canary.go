@@ -0,0 +1,266 @@
+package canary
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock"
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/rep"
+	"code.cloudfoundry.org/rep/evacuation/evacuation_context"
+	uuid "github.com/nu7hatch/gouuid"
+)
+
+const (
+	canaryStartDurationMetric       = "CanaryStartDuration"
+	canaryConsecutiveFailuresMetric = "CanaryConsecutiveFailures"
+
+	ContainerGuidPrefix = "canary-"
+
+	PollInterval = 500 * time.Millisecond
+
+	DefaultInterval         = 5 * time.Minute
+	DefaultTimeout          = time.Minute
+	DefaultFailureThreshold = 3
+)
+
+var (
+	ErrTimedOut = errors.New("canary container did not start in time")
+	ErrSignaled = errors.New("signaled while running canary container")
+)
+
+type Config struct {
+	Interval         time.Duration
+	Timeout          time.Duration
+	FailureThreshold int
+
+	RootFSPath string
+	MemoryMB   int
+	DiskMB     int
+	Path       string
+	Args       []string
+	User       string
+}
+
+// Canary periodically allocates and runs a small container through the
+// executor, the same way LRPs are started, to prove that the cell can start
+// workloads. The cell is reported unhealthy once FailureThreshold runs in a
+// row have failed. No more runs are started once the cell starts evacuating.
+type Canary struct {
+	logger             lager.Logger
+	config             Config
+	executorClient     executor.Client
+	evacuationNotifier evacuation_context.EvacuationNotifier
+	metronClient       loggingclient.IngressClient
+	clock              clock.Clock
+
+	lock   sync.RWMutex
+	result *rep.CanaryResult
+}
+
+func New(
+	logger lager.Logger,
+	config Config,
+	executorClient executor.Client,
+	evacuationNotifier evacuation_context.EvacuationNotifier,
+	metronClient loggingclient.IngressClient,
+	clock clock.Clock,
+) *Canary {
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.FailureThreshold < 1 {
+		config.FailureThreshold = DefaultFailureThreshold
+	}
+
+	return &Canary{
+		logger:             logger.Session("canary"),
+		config:             config,
+		executorClient:     executorClient,
+		evacuationNotifier: evacuationNotifier,
+		metronClient:       metronClient,
+		clock:              clock,
+	}
+}
+
+// LastResult returns the outcome of the most recent run, or nil if the canary
+// has not run yet.
+func (c *Canary) LastResult() *rep.CanaryResult {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.result == nil {
+		return nil
+	}
+	result := *c.result
+	return &result
+}
+
+func (c *Canary) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	logger := c.logger
+	logger.Info("starting", lager.Data{"interval": c.config.Interval.String()})
+	defer logger.Info("finished")
+
+	evacuationNotify := c.evacuationNotifier.EvacuateNotify()
+	close(ready)
+
+	timer := c.clock.NewTimer(c.config.Interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C():
+		case <-evacuationNotify:
+			logger.Info("notified-of-evacuation")
+			timer.Stop()
+			signal := <-signals
+			logger.Info("received-signal", lager.Data{"signal": signal.String()})
+			return nil
+		case signal := <-signals:
+			logger.Info("received-signal", lager.Data{"signal": signal.String()})
+			return nil
+		}
+
+		err := c.runOnce(logger, signals)
+		if err == ErrSignaled {
+			return nil
+		}
+
+		timer.Reset(c.config.Interval)
+	}
+}
+
+func (c *Canary) runOnce(logger lager.Logger, signals <-chan os.Signal) error {
+	guid, err := uuid.NewV4()
+	if err != nil {
+		logger.Error("failed-to-generate-guid", err)
+		return err
+	}
+	containerGuid := ContainerGuidPrefix + guid.String()
+
+	logger = logger.Session("run", lager.Data{"container-guid": containerGuid})
+	logger.Info("starting")
+	defer logger.Info("finished")
+
+	result := rep.CanaryResult{StartedAt: c.clock.Now()}
+
+	startDuration, err := c.startContainer(logger, containerGuid, signals)
+	if err == ErrSignaled {
+		return err
+	}
+
+	result.TotalDuration = c.clock.Since(result.StartedAt)
+	if err != nil {
+		logger.Error("failed", err)
+		result.Error = err.Error()
+	} else {
+		logger.Info("succeeded", lager.Data{"start-duration": startDuration.String()})
+		result.Succeeded = true
+		result.StartDuration = startDuration
+
+		sendErr := c.metronClient.SendDuration(canaryStartDurationMetric, startDuration)
+		if sendErr != nil {
+			logger.Error("failed-to-send-canary-start-duration-metric", sendErr)
+		}
+	}
+
+	c.record(logger, result)
+	return err
+}
+
+func (c *Canary) startContainer(logger lager.Logger, guid string, signals <-chan os.Signal) (time.Duration, error) {
+	startTime := c.clock.Now()
+
+	defer func() {
+		err := c.executorClient.DeleteContainer(logger, guid)
+		if err != nil && err != executor.ErrContainerNotFound {
+			logger.Error("failed-to-delete-container", err)
+		}
+	}()
+
+	resource := executor.NewResource(c.config.MemoryMB, c.config.DiskMB, 0, c.config.RootFSPath)
+	tags := executor.Tags{rep.LifecycleTag: rep.CanaryLifecycle}
+	allocationRequest := executor.NewAllocationRequest(guid, &resource, tags)
+
+	failures := c.executorClient.AllocateContainers(logger, []executor.AllocationRequest{allocationRequest})
+	if len(failures) > 0 {
+		return 0, fmt.Errorf("failed to allocate container: %s", failures[0].ErrorMsg)
+	}
+
+	runInfo := executor.RunInfo{
+		Action: models.WrapAction(&models.RunAction{
+			Path: c.config.Path,
+			Args: c.config.Args,
+			User: c.config.User,
+		}),
+	}
+	runRequest := executor.NewRunRequest(guid, &runInfo, tags)
+
+	err := c.executorClient.RunContainer(logger, &runRequest)
+	if err != nil {
+		return 0, fmt.Errorf("failed to run container: %s", err.Error())
+	}
+
+	timeout := c.clock.NewTimer(c.config.Timeout)
+	defer timeout.Stop()
+
+	ticker := c.clock.NewTicker(PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+		case <-timeout.C():
+			return 0, ErrTimedOut
+		case signal := <-signals:
+			logger.Info("received-signal", lager.Data{"signal": signal.String()})
+			return 0, ErrSignaled
+		}
+
+		container, err := c.executorClient.GetContainer(logger, guid)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get container: %s", err.Error())
+		}
+
+		switch container.State {
+		case executor.StateRunning:
+			return c.clock.Since(startTime), nil
+		case executor.StateCompleted:
+			if container.RunResult.Failed {
+				return 0, fmt.Errorf("container failed: %s", container.RunResult.FailureReason)
+			}
+			return c.clock.Since(startTime), nil
+		}
+	}
+}
+
+func (c *Canary) record(logger lager.Logger, result rep.CanaryResult) {
+	c.lock.Lock()
+	if !result.Succeeded && c.result != nil {
+		result.ConsecutiveFailures = c.result.ConsecutiveFailures
+	}
+	if !result.Succeeded {
+		result.ConsecutiveFailures++
+	}
+	result.Healthy = result.ConsecutiveFailures < c.config.FailureThreshold
+	c.result = &result
+	c.lock.Unlock()
+
+	if !result.Healthy {
+		logger.Error("cell-unhealthy", nil, lager.Data{"consecutive-failures": result.ConsecutiveFailures})
+	}
+
+	err := c.metronClient.SendMetric(canaryConsecutiveFailuresMetric, result.ConsecutiveFailures)
+	if err != nil {
+		logger.Error("failed-to-send-canary-consecutive-failures-metric", err)
+	}
+}
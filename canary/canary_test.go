@@ -0,0 +1,246 @@
+package canary_test
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/executor"
+	fake_client "code.cloudfoundry.org/executor/fakes"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/rep"
+	"code.cloudfoundry.org/rep/canary"
+	"code.cloudfoundry.org/rep/evacuation/evacuation_context"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Canary", func() {
+	const (
+		interval = time.Minute
+		timeout  = 10 * time.Second
+	)
+
+	var (
+		logger           *lagertest.TestLogger
+		fakeClock        *fakeclock.FakeClock
+		executorClient   *fake_client.FakeClient
+		fakeMetronClient *mfakes.FakeIngressClient
+		evacuatable      evacuation_context.Evacuatable
+
+		cellCanary *canary.Canary
+		process    ifrit.Process
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		executorClient = new(fake_client.FakeClient)
+		fakeMetronClient = new(mfakes.FakeIngressClient)
+
+		var evacuationNotifier evacuation_context.EvacuationNotifier
+		evacuatable, _, evacuationNotifier = evacuation_context.New()
+
+		executorClient.GetContainerReturns(executor.Container{State: executor.StateRunning}, nil)
+
+		cellCanary = canary.New(
+			logger,
+			canary.Config{
+				Interval:         interval,
+				Timeout:          timeout,
+				FailureThreshold: 2,
+				RootFSPath:       "/path/to/rootfs",
+				MemoryMB:         16,
+				DiskMB:           32,
+				Path:             "/bin/true",
+				User:             "vcap",
+			},
+			executorClient,
+			evacuationNotifier,
+			fakeMetronClient,
+			fakeClock,
+		)
+	})
+
+	JustBeforeEach(func() {
+		process = ifrit.Invoke(cellCanary)
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive())
+	})
+
+	startRun := func() {
+		Eventually(fakeClock.WatcherCount).Should(Equal(1))
+		fakeClock.Increment(interval)
+	}
+
+	finishedRuns := func() int {
+		return fakeMetronClient.SendMetricCallCount()
+	}
+
+	It("has no result before the first run", func() {
+		Expect(cellCanary.LastResult()).To(BeNil())
+	})
+
+	Context("when the cell evacuates", func() {
+		JustBeforeEach(func() {
+			evacuatable.Evacuate()
+			Eventually(logger).Should(gbytes.Say("notified-of-evacuation"))
+		})
+
+		It("stops running the canary", func() {
+			fakeClock.Increment(interval)
+			Consistently(executorClient.AllocateContainersCallCount).Should(BeZero())
+			Expect(cellCanary.LastResult()).To(BeNil())
+		})
+	})
+
+	Context("when the canary container starts", func() {
+		JustBeforeEach(func() {
+			startRun()
+			Eventually(fakeClock.WatcherCount).Should(Equal(2))
+			fakeClock.Increment(canary.PollInterval)
+			Eventually(finishedRuns).Should(Equal(1))
+		})
+
+		It("allocates and runs a canary container", func() {
+			Expect(executorClient.AllocateContainersCallCount()).To(Equal(1))
+			_, requests := executorClient.AllocateContainersArgsForCall(0)
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].Guid).To(HavePrefix(canary.ContainerGuidPrefix))
+			Expect(requests[0].Resource).To(Equal(executor.NewResource(16, 32, 0, "/path/to/rootfs")))
+			Expect(requests[0].Tags).To(Equal(executor.Tags{rep.LifecycleTag: rep.CanaryLifecycle}))
+
+			Expect(executorClient.RunContainerCallCount()).To(Equal(1))
+			_, runRequest := executorClient.RunContainerArgsForCall(0)
+			Expect(runRequest.Guid).To(Equal(requests[0].Guid))
+			Expect(runRequest.Action).To(Equal(models.WrapAction(&models.RunAction{
+				Path: "/bin/true",
+				User: "vcap",
+			})))
+		})
+
+		It("deletes the canary container", func() {
+			_, requests := executorClient.AllocateContainersArgsForCall(0)
+			_, guid := executorClient.DeleteContainerArgsForCall(0)
+			Expect(guid).To(Equal(requests[0].Guid))
+		})
+
+		It("reports a healthy result with the start latency", func() {
+			result := cellCanary.LastResult()
+			Expect(result).NotTo(BeNil())
+			Expect(result.Succeeded).To(BeTrue())
+			Expect(result.Healthy).To(BeTrue())
+			Expect(result.StartDuration).To(Equal(canary.PollInterval))
+			Expect(result.ConsecutiveFailures).To(Equal(0))
+		})
+
+		It("emits the start latency and failure count", func() {
+			Expect(fakeMetronClient.SendDurationCallCount()).To(Equal(1))
+			name, value, _ := fakeMetronClient.SendDurationArgsForCall(0)
+			Expect(name).To(Equal("CanaryStartDuration"))
+			Expect(value).To(Equal(canary.PollInterval))
+
+			Expect(fakeMetronClient.SendMetricCallCount()).To(Equal(1))
+			name, count, _ := fakeMetronClient.SendMetricArgsForCall(0)
+			Expect(name).To(Equal("CanaryConsecutiveFailures"))
+			Expect(count).To(Equal(0))
+		})
+	})
+
+	Context("when the canary container completes with a failure", func() {
+		BeforeEach(func() {
+			executorClient.GetContainerReturns(executor.Container{
+				State:     executor.StateCompleted,
+				RunResult: executor.ContainerRunResult{Failed: true, FailureReason: "exit status 1"},
+			}, nil)
+		})
+
+		It("reports the failure reason", func() {
+			startRun()
+			Eventually(fakeClock.WatcherCount).Should(Equal(2))
+			fakeClock.Increment(canary.PollInterval)
+			Eventually(finishedRuns).Should(Equal(1))
+
+			result := cellCanary.LastResult()
+			Expect(result.Succeeded).To(BeFalse())
+			Expect(result.Error).To(ContainSubstring("exit status 1"))
+			Expect(result.ConsecutiveFailures).To(Equal(1))
+		})
+	})
+
+	Context("when the canary container does not start in time", func() {
+		BeforeEach(func() {
+			executorClient.GetContainerReturns(executor.Container{State: executor.StateCreated}, nil)
+		})
+
+		It("times out and deletes the container", func() {
+			startRun()
+			Eventually(fakeClock.WatcherCount).Should(Equal(2))
+			fakeClock.Increment(timeout)
+			Eventually(finishedRuns).Should(Equal(1))
+
+			result := cellCanary.LastResult()
+			Expect(result.Succeeded).To(BeFalse())
+			Expect(result.Error).To(Equal(canary.ErrTimedOut.Error()))
+		})
+	})
+
+	Context("when the canary container cannot be allocated", func() {
+		BeforeEach(func() {
+			executorClient.AllocateContainersStub = func(_ lager.Logger, requests []executor.AllocationRequest) []executor.AllocationFailure {
+				return []executor.AllocationFailure{
+					executor.NewAllocationFailure(&requests[0], executor.ErrInsufficientResourcesAvailable.Error()),
+				}
+			}
+		})
+
+		It("marks the cell unhealthy after repeated failures", func() {
+			startRun()
+			Eventually(finishedRuns).Should(Equal(1))
+			result := cellCanary.LastResult()
+			Expect(result.Succeeded).To(BeFalse())
+			Expect(result.Healthy).To(BeTrue())
+			Expect(result.ConsecutiveFailures).To(Equal(1))
+
+			startRun()
+			Eventually(finishedRuns).Should(Equal(2))
+			result = cellCanary.LastResult()
+			Expect(result.Healthy).To(BeFalse())
+			Expect(result.ConsecutiveFailures).To(Equal(2))
+			Expect(logger).To(gbytes.Say("cell-unhealthy"))
+
+			executorClient.AllocateContainersStub = nil
+			executorClient.AllocateContainersReturns(nil)
+
+			startRun()
+			Eventually(fakeClock.WatcherCount).Should(Equal(2))
+			fakeClock.Increment(canary.PollInterval)
+			Eventually(finishedRuns).Should(Equal(3))
+			result = cellCanary.LastResult()
+			Expect(result.Healthy).To(BeTrue())
+			Expect(result.ConsecutiveFailures).To(Equal(0))
+		})
+	})
+
+	Context("when running the canary container fails", func() {
+		BeforeEach(func() {
+			executorClient.RunContainerReturns(errors.New("boom"))
+		})
+
+		It("reports the failure", func() {
+			startRun()
+			Eventually(finishedRuns).Should(Equal(1))
+			Expect(cellCanary.LastResult().Error).To(ContainSubstring("boom"))
+		})
+	})
+})
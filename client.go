@@ -140,6 +140,7 @@ type Client interface {
 	Perform(logger lager.Logger, work Work) (Work, error)
 	StopLRPInstance(logger lager.Logger, key models.ActualLRPKey, instanceKey models.ActualLRPInstanceKey) error
 	CancelTask(logger lager.Logger, taskGuid string) error
+	CancelTaskWithGracePeriod(logger lager.Logger, taskGuid string, gracePeriod time.Duration) error
 	SetStateClient(stateClient *http.Client)
 	StateClientTimeout() time.Duration
 }
@@ -292,8 +293,18 @@ func (c *client) StopLRPInstance(
 }
 
 func (c *client) CancelTask(logger lager.Logger, taskGuid string) error {
+	return c.cancelTask(logger.Session("cancel-task", lager.Data{"task-guid": taskGuid}), taskGuid, nil)
+}
+
+// CancelTaskWithGracePeriod cancels the task, giving its container
+// gracePeriod to complete instead of the cell's default.
+func (c *client) CancelTaskWithGracePeriod(logger lager.Logger, taskGuid string, gracePeriod time.Duration) error {
+	logger = logger.Session("cancel-task", lager.Data{"task-guid": taskGuid, "grace-period": gracePeriod.String()})
+	return c.cancelTask(logger, taskGuid, url.Values{"grace_period": []string{gracePeriod.String()}})
+}
+
+func (c *client) cancelTask(logger lager.Logger, taskGuid string, query url.Values) error {
 	start := time.Now()
-	logger = logger.Session("cancel-task", lager.Data{"task-guid": taskGuid})
 	logger.Info("starting")
 
 	req, err := c.requestGenerator.CreateRequest(CancelTaskRoute, rata.Params{"task_guid": taskGuid}, nil)
@@ -301,6 +312,7 @@ func (c *client) CancelTask(logger lager.Logger, taskGuid string) error {
 		logger.Error("connection-failed", err)
 		return err
 	}
+	req.URL.RawQuery = query.Encode()
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -312,4 +312,30 @@ var _ = Describe("Client", func() {
 			})
 		})
 	})
+
+	Describe("CancelTaskWithGracePeriod", func() {
+		var (
+			logger    = lagertest.NewTestLogger("test")
+			cancelErr error
+		)
+
+		BeforeEach(func() {
+			fakeServer.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/v1/tasks/some-task-guid/cancel", "grace_period=30s"),
+					ghttp.RespondWith(http.StatusAccepted, ""),
+				),
+			)
+		})
+
+		JustBeforeEach(func() {
+			cancelErr = client.CancelTaskWithGracePeriod(logger, "some-task-guid", 30*time.Second)
+		})
+
+		It("sends the grace period with the request", func() {
+			Expect(cancelErr).NotTo(HaveOccurred())
+			Expect(fakeServer.ReceivedRequests()).To(HaveLen(1))
+			Eventually(logger.Buffer()).Should(gbytes.Say("cancel-task.completed"))
+		})
+	})
 })
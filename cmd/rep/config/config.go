@@ -77,29 +77,125 @@ func (m RootFSes) MarshalJSON() (b []byte, err error) {
 	return json.Marshal(arr)
 }
 
+// SimulationConfig describes the capacity of a simulated cell. When present
+// in the rep config the rep serves the auction from an in-memory executor
+// instead of running containers.
+type SimulationConfig struct {
+	MemoryMB          int                   `json:"memory_mb"`
+	DiskMB            int                   `json:"disk_mb"`
+	Containers        int                   `json:"containers"`
+	VolumeDrivers     []string              `json:"volume_drivers,omitempty"`
+	AllocationLatency durationjson.Duration `json:"allocation_latency,omitempty"`
+}
+
+// CanaryConfig enables a canary container that is periodically run to check
+// that the cell can start workloads. RootFS names a preloaded rootfs and
+// defaults to the first one configured.
+type CanaryConfig struct {
+	Interval         durationjson.Duration `json:"interval,omitempty"`
+	Timeout          durationjson.Duration `json:"timeout,omitempty"`
+	FailureThreshold int                   `json:"failure_threshold,omitempty"`
+	RootFS           string                `json:"rootfs,omitempty"`
+	MemoryMB         int                   `json:"memory_mb,omitempty"`
+	DiskMB           int                   `json:"disk_mb,omitempty"`
+	Path             string                `json:"path"`
+	Args             []string              `json:"args,omitempty"`
+	User             string                `json:"user,omitempty"`
+}
+
+// AdaptiveSyncConfig lets the bulk sync polling interval adapt between
+// MinInterval and MaxInterval: it shortens after syncs that generate at least
+// BusyThreshold operations, fail, or follow an interruption of the event
+// stream, and lengthens otherwise. Jitter is the fraction of the interval by
+// which each wait is randomly varied.
+type AdaptiveSyncConfig struct {
+	MinInterval   durationjson.Duration `json:"min_interval,omitempty"`
+	MaxInterval   durationjson.Duration `json:"max_interval,omitempty"`
+	BusyThreshold int                   `json:"busy_threshold,omitempty"`
+	Jitter        float64               `json:"jitter,omitempty"`
+}
+
+// OperationQueueConfig replaces the harmonizer's sliding operation queue with
+// one that executes operations by priority. A concurrency of zero is
+// unlimited.
+type OperationQueueConfig struct {
+	MaxConcurrency       int `json:"max_concurrency,omitempty"`
+	CompletedConcurrency int `json:"completed_concurrency,omitempty"`
+	RunningConcurrency   int `json:"running_concurrency,omitempty"`
+	ReservedConcurrency  int `json:"reserved_concurrency,omitempty"`
+	ResidualConcurrency  int `json:"residual_concurrency,omitempty"`
+}
+
+// RetryConfig bounds the attempts of a retry. Backoffs double per attempt up
+// to MaxBackoff.
+//
+// As bbs_retry it enables processing a container again when a BBS call made
+// for it failed with a transient error. As task_retry it enables rerunning a
+// task on this cell after a retryable failure, before rejecting it back to
+// the BBS.
+type RetryConfig struct {
+	MaxAttempts    int                   `json:"max_attempts"`
+	InitialBackoff durationjson.Duration `json:"initial_backoff,omitempty"`
+	MaxBackoff     durationjson.Duration `json:"max_backoff,omitempty"`
+}
+
+// BBSRateLimitConfig limits the requests the rep makes to the BBS while
+// processing containers and cleaning up evacuated LRPs. Reads of LRPs and
+// tasks and requests that change them have separate budgets; a budget without
+// a rate is unlimited. Bursts default to one second's worth of requests.
+type BBSRateLimitConfig struct {
+	ReadsPerSecond  float64 `json:"reads_per_second,omitempty"`
+	ReadBurst       int     `json:"read_burst,omitempty"`
+	WritesPerSecond float64 `json:"writes_per_second,omitempty"`
+	WriteBurst      int     `json:"write_burst,omitempty"`
+}
+
+// OrphanReaperConfig enables stopping and deleting containers the rep cannot
+// attribute to an LRP or a task once they have been orphaned for GracePeriod.
+// With DryRun set they are only logged.
+type OrphanReaperConfig struct {
+	Interval    durationjson.Duration `json:"interval,omitempty"`
+	GracePeriod durationjson.Duration `json:"grace_period,omitempty"`
+	DryRun      bool                  `json:"dry_run,omitempty"`
+}
+
 type RepConfig struct {
+	AdaptivePollingInterval         *AdaptiveSyncConfig   `json:"adaptive_polling_interval,omitempty"`
 	AdvertiseDomain                 string                `json:"advertise_domain,omitempty"`
 	BBSAddress                      string                `json:"bbs_address"`
 	BBSClientSessionCacheSize       int                   `json:"bbs_client_session_cache_size,omitempty"`
 	BBSMaxIdleConnsPerHost          int                   `json:"bbs_max_idle_conns_per_host,omitempty"`
+	BBSRateLimit                    *BBSRateLimitConfig   `json:"bbs_rate_limit,omitempty"`
+	BBSRetry                        *RetryConfig          `json:"bbs_retry,omitempty"`
 	BBSCACertFile                   string                `json:"bbs_ca_cert_file"`     // DEPRECATED. Kept around for dusts compatability
 	BBSClientCertFile               string                `json:"bbs_client_cert_file"` // DEPRECATED. Kept around for dusts compatability
 	BBSClientKeyFile                string                `json:"bbs_client_key_file"`  // DEPRECATED. Kept around for dusts compatability
+	BulkFullSyncInterval            durationjson.Duration `json:"bulk_full_sync_interval,omitempty"`
 	CaCertFile                      string                `json:"ca_cert_file"`
+	Canary                          *CanaryConfig         `json:"canary,omitempty"`
 	CellID                          string                `json:"cell_id"`
 	CommunicationTimeout            durationjson.Duration `json:"communication_timeout,omitempty"`
 	ConsulCACert                    string                `json:"consul_ca_cert"`
 	ConsulClientCert                string                `json:"consul_client_cert"`
 	ConsulClientKey                 string                `json:"consul_client_key"`
 	ConsulCluster                   string                `json:"consul_cluster"`
+	CrashDiagnosticsMaxSize         int                   `json:"crash_diagnostics_max_size,omitempty"`
+	CrashDiagnosticsPaths           []string              `json:"crash_diagnostics_paths,omitempty"`
+	DesiredLRPCacheTTL              durationjson.Duration `json:"desired_lrp_cache_ttl,omitempty"`
+	DomainQuotas                    rep.DomainQuotas      `json:"domain_quotas,omitempty"`
 	EnableConsulServiceRegistration bool                  `json:"enable_consul_service_registration,omitempty"`
 	EvacuationPollingInterval       durationjson.Duration `json:"evacuation_polling_interval,omitempty"`
 	EvacuationTimeout               durationjson.Duration `json:"evacuation_timeout,omitempty"`
+	ExecutorStateCacheTTL           durationjson.Duration `json:"executor_state_cache_ttl,omitempty"`
 	ListenAddr                      string                `json:"listen_addr,omitempty"`
 	ListenAddrSecurable             string                `json:"listen_addr_securable,omitempty"`
 	LockRetryInterval               durationjson.Duration `json:"lock_retry_interval,omitempty"`
 	LockTTL                         durationjson.Duration `json:"lock_ttl,omitempty"`
+	MaxTaskResultSize               int                   `json:"max_task_result_size,omitempty"`
+	OperationHistorySize            int                   `json:"operation_history_size,omitempty"`
+	OperationQueue                  *OperationQueueConfig `json:"operation_queue,omitempty"`
 	OptionalPlacementTags           []string              `json:"optional_placement_tags"`
+	OrphanReaper                    *OrphanReaperConfig   `json:"orphan_reaper,omitempty"`
 	PlacementTags                   []string              `json:"placement_tags"`
 	PollingInterval                 durationjson.Duration `json:"polling_interval,omitempty"`
 	PreloadedRootFS                 RootFSes              `json:"preloaded_root_fs"`
@@ -108,7 +204,13 @@ type RepConfig struct {
 	CertFile                        string                `json:"cert_file"`
 	KeyFile                         string                `json:"key_file"`
 	SessionName                     string                `json:"session_name,omitempty"`
+	ShadowMode                      bool                  `json:"shadow_mode,omitempty"`
+	Simulation                      *SimulationConfig     `json:"simulation,omitempty"`
 	SupportedProviders              []string              `json:"supported_providers"`
+	SystemDaemons                   *SystemDaemonsConfig  `json:"system_daemons,omitempty"`
+	TaskCancelGracePeriod           durationjson.Duration `json:"task_cancel_grace_period,omitempty"`
+	TaskRetry                       *RetryConfig          `json:"task_retry,omitempty"`
+	TruncateTaskResults             bool                  `json:"truncate_task_results,omitempty"`
 	Zone                            string                `json:"zone"`
 	LoggregatorConfig               loggingclient.Config  `json:"loggregator"`
 	CellRegistrationsLocketEnabled  bool                  `json:"cell_registrations_locket_enabled"`
@@ -119,6 +221,32 @@ type RepConfig struct {
 	vcmodels.VContainerClientConfig
 }
 
+// SystemDaemonsConfig enables system daemons that the rep keeps running in
+// containers on the cell. A daemon that crashes is restarted after a backoff
+// that doubles from InitialBackoff up to MaxBackoff. The resources of every
+// daemon are withheld from the capacity offered to the auction.
+type SystemDaemonsConfig struct {
+	CheckInterval  durationjson.Duration `json:"check_interval,omitempty"`
+	InitialBackoff durationjson.Duration `json:"initial_backoff,omitempty"`
+	MaxBackoff     durationjson.Duration `json:"max_backoff,omitempty"`
+	Daemons        []DaemonConfig        `json:"daemons"`
+}
+
+// DaemonConfig describes a single system daemon. RootFS names a preloaded
+// rootfs and defaults to the first one configured. Its output is forwarded
+// to the log stream of LogGuid when set.
+type DaemonConfig struct {
+	Name     string            `json:"name"`
+	RootFS   string            `json:"rootfs,omitempty"`
+	MemoryMB int               `json:"memory_mb,omitempty"`
+	DiskMB   int               `json:"disk_mb,omitempty"`
+	Path     string            `json:"path"`
+	Args     []string          `json:"args,omitempty"`
+	Env      map[string]string `json:"env,omitempty"`
+	User     string            `json:"user,omitempty"`
+	LogGuid  string            `json:"log_guid,omitempty"`
+}
+
 func defaultConfig() RepConfig {
 	return RepConfig{
 		AdvertiseDomain:           "cell.service.cf.internal",
@@ -133,8 +261,10 @@ func defaultConfig() RepConfig {
 		ListenAddrSecurable:       "0.0.0.0:1801",
 		LockRetryInterval:         durationjson.Duration(locket.RetryInterval),
 		LockTTL:                   durationjson.Duration(locket.DefaultSessionTTL),
+		OperationHistorySize:      1000,
 		PollingInterval:           durationjson.Duration(30 * time.Second),
 		SessionName:               "rep",
+		TaskCancelGracePeriod:     durationjson.Duration(10 * time.Second),
 	}
 }
 
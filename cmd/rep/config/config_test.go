@@ -12,6 +12,7 @@ import (
 	"code.cloudfoundry.org/executor/model"
 	"code.cloudfoundry.org/lager/lagerflags"
 	"code.cloudfoundry.org/locket"
+	"code.cloudfoundry.org/rep"
 	"code.cloudfoundry.org/rep/cmd/rep/config"
 
 	. "github.com/onsi/ginkgo"
@@ -24,12 +25,41 @@ var _ = Describe("RepConfig", func() {
 	BeforeEach(func() {
 		configData = `{
 			"proxy_memory_allocation_mb": 6,
+			"adaptive_polling_interval": {
+				"min_interval": "10s",
+				"max_interval": "2m",
+				"busy_threshold": 20,
+				"jitter": 0.1
+			},
 			"advertise_domain": "test-domain",
 			"bbs_address": "1.1.1.1:9091",
 			"bbs_client_session_cache_size": 100,
 			"bbs_max_idle_conns_per_host": 10,
+			"bbs_rate_limit": {
+				"reads_per_second": 50,
+				"read_burst": 100,
+				"writes_per_second": 20.5,
+				"write_burst": 40
+			},
+			"bbs_retry": {
+				"max_attempts": 5,
+				"initial_backoff": "1s",
+				"max_backoff": "30s"
+			},
+			"bulk_full_sync_interval": "5m",
 			"ca_cert_file": "/tmp/ca_cert",
 			"cache_path": "/tmp/cache",
+			"canary": {
+				"interval": "5m",
+				"timeout": "30s",
+				"failure_threshold": 2,
+				"rootfs": "cflinuxfs2",
+				"memory_mb": 16,
+				"disk_mb": 32,
+				"path": "/bin/true",
+				"args": ["-v"],
+				"user": "vcap"
+			},
 			"cell_id" : "cell_z1/10",
 			"communication_timeout": "11s",
 			"consul_ca_cert": "/tmp/consul_ca_cert",
@@ -42,15 +72,20 @@ var _ = Describe("RepConfig", func() {
 			"container_owner_name": "vcap",
 			"container_reap_interval": "11s",
 			"create_work_pool_size": 15,
+			"crash_diagnostics_max_size": 2048,
+			"crash_diagnostics_paths": ["/home/vcap/logs/crash.log"],
 			"debug_address": "5.5.5.5:9090",
 			"delete_work_pool_size": 10,
+			"desired_lrp_cache_ttl": "3s",
 			"disk_mb": "20000",
+			"domain_quotas": {"cf-apps": {"memory_mb": 1024, "disk_mb": 2048, "containers": 10}},
 			"enable_declarative_healthcheck": true,
 			"declarative_healthcheck_path": "/var/vcap/packages/healthcheck",
 			"enable_consul_service_registration": true,
 			"enable_legacy_api_endpoints": true,
 			"evacuation_polling_interval" : "13s",
 			"evacuation_timeout" : "12s",
+			"executor_state_cache_ttl": "2s",
 			"enable_container_proxy": true,
 			"garden_addr": "100.0.0.1",
 			"garden_healthcheck_command_retry_pause": "15s",
@@ -94,7 +129,21 @@ var _ = Describe("RepConfig", func() {
 			"max_concurrent_downloads": 11,
 			"memory_mb": "1000",
 			"metrics_work_pool_size": 5,
+			"max_task_result_size": 65536,
+			"operation_history_size": 500,
+			"operation_queue": {
+				"max_concurrency": 50,
+				"completed_concurrency": 20,
+				"running_concurrency": 20,
+				"reserved_concurrency": 10,
+				"residual_concurrency": 5
+			},
 			"optional_placement_tags": ["otag1", "otag2"],
+			"orphan_reaper": {
+				"interval": "1m",
+				"grace_period": "10m",
+				"dry_run": true
+			},
 			"path_to_ca_certs_for_downloads": "/tmp/ca-certs",
 			"placement_tags": ["tag1", "tag2"],
 			"polling_interval": "10s",
@@ -106,8 +155,37 @@ var _ = Describe("RepConfig", func() {
 			"cert_file": "/tmp/server_cert",
 			"key_file": "/tmp/server_key",
 			"session_name": "test",
+			"shadow_mode": true,
+			"simulation": {
+				"memory_mb": 4096,
+				"disk_mb": 8192,
+				"containers": 100,
+				"volume_drivers": ["simdriver"],
+				"allocation_latency": "50ms"
+			},
 			"skip_cert_verify": true,
 			"supported_providers": ["provider1", "provider2"],
+			"system_daemons": {
+				"check_interval": "10s",
+				"max_backoff": "2m",
+				"daemons": [{
+					"name": "log-forwarder",
+					"rootfs": "test2",
+					"memory_mb": 64,
+					"disk_mb": 128,
+					"path": "/bin/forwarder",
+					"args": ["-v"],
+					"env": {"LEVEL": "info"},
+					"user": "vcap",
+					"log_guid": "forwarder-logs"
+				}]
+			},
+			"task_cancel_grace_period": "30s",
+			"task_retry": {
+				"max_attempts": 2,
+				"initial_backoff": "5s"
+			},
+			"truncate_task_results": true,
 			"temp_dir": "/tmp/test",
 			"trusted_system_certificates_path": "/tmp/trusted",
 			"unhealthy_monitoring_interval": "10s",
@@ -140,11 +218,40 @@ var _ = Describe("RepConfig", func() {
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(repConfig).To(Equal(config.RepConfig{
+			AdaptivePollingInterval: &config.AdaptiveSyncConfig{
+				MinInterval:   durationjson.Duration(10 * time.Second),
+				MaxInterval:   durationjson.Duration(2 * time.Minute),
+				BusyThreshold: 20,
+				Jitter:        0.1,
+			},
 			AdvertiseDomain:           "test-domain",
 			BBSAddress:                "1.1.1.1:9091",
 			BBSClientSessionCacheSize: 100,
 			BBSMaxIdleConnsPerHost:    10,
-			CaCertFile:                "/tmp/ca_cert",
+			BBSRateLimit: &config.BBSRateLimitConfig{
+				ReadsPerSecond:  50,
+				ReadBurst:       100,
+				WritesPerSecond: 20.5,
+				WriteBurst:      40,
+			},
+			BBSRetry: &config.RetryConfig{
+				MaxAttempts:    5,
+				InitialBackoff: durationjson.Duration(time.Second),
+				MaxBackoff:     durationjson.Duration(30 * time.Second),
+			},
+			BulkFullSyncInterval: durationjson.Duration(5 * time.Minute),
+			CaCertFile:           "/tmp/ca_cert",
+			Canary: &config.CanaryConfig{
+				Interval:         durationjson.Duration(5 * time.Minute),
+				Timeout:          durationjson.Duration(30 * time.Second),
+				FailureThreshold: 2,
+				RootFS:           "cflinuxfs2",
+				MemoryMB:         16,
+				DiskMB:           32,
+				Path:             "/bin/true",
+				Args:             []string{"-v"},
+				User:             "vcap",
+			},
 			CellID:                    "cell_z1/10",
 			CellRegistrationsLocketEnabled: true,
 			ClientLocketConfig: locket.ClientLocketConfig{
@@ -153,17 +260,24 @@ var _ = Describe("RepConfig", func() {
 				LocketClientCertFile: "locket-client-cert",
 				LocketClientKeyFile:  "locket-client-key",
 			},
-			CommunicationTimeout: durationjson.Duration(11 * time.Second),
-			ConsulCACert:         "/tmp/consul_ca_cert",
-			ConsulClientCert:     "/tmp/consul_client_cert",
-			ConsulClientKey:      "/tmp/consul_client_key",
-			ConsulCluster:        "test cluster",
+			CommunicationTimeout:    durationjson.Duration(11 * time.Second),
+			ConsulCACert:            "/tmp/consul_ca_cert",
+			ConsulClientCert:        "/tmp/consul_client_cert",
+			ConsulClientKey:         "/tmp/consul_client_key",
+			ConsulCluster:           "test cluster",
+			CrashDiagnosticsMaxSize: 2048,
+			CrashDiagnosticsPaths:   []string{"/home/vcap/logs/crash.log"},
 			DebugServerConfig: debugserver.DebugServerConfig{
 				DebugAddress: "5.5.5.5:9090",
 			},
+			DesiredLRPCacheTTL: durationjson.Duration(3 * time.Second),
+			DomainQuotas: rep.DomainQuotas{
+				"cf-apps": rep.DomainQuota{MemoryMB: 1024, DiskMB: 2048, Containers: 10},
+			},
 			EnableConsulServiceRegistration: true,
 			EvacuationPollingInterval:       durationjson.Duration(13 * time.Second),
 			EvacuationTimeout:               durationjson.Duration(12 * time.Second),
+			ExecutorStateCacheTTL:           durationjson.Duration(2 * time.Second),
 			ExecutorConfig: model.ExecutorConfig{
 				ProxyMemoryAllocationMB:        6,
 				CachePath:                      "/tmp/cache",
@@ -220,14 +334,57 @@ var _ = Describe("RepConfig", func() {
 			ListenAddrSecurable:   "0.0.0.0:8081",
 			LockRetryInterval:     durationjson.Duration(5 * time.Second),
 			LockTTL:               durationjson.Duration(5 * time.Second),
+			MaxTaskResultSize:     65536,
+			OperationHistorySize:  500,
+			OperationQueue: &config.OperationQueueConfig{
+				MaxConcurrency:       50,
+				CompletedConcurrency: 20,
+				RunningConcurrency:   20,
+				ReservedConcurrency:  10,
+				ResidualConcurrency:  5,
+			},
 			OptionalPlacementTags: []string{"otag1", "otag2"},
+			OrphanReaper: &config.OrphanReaperConfig{
+				Interval:    durationjson.Duration(time.Minute),
+				GracePeriod: durationjson.Duration(10 * time.Minute),
+				DryRun:      true,
+			},
 			PlacementTags:         []string{"tag1", "tag2"},
 			PollingInterval:       durationjson.Duration(10 * time.Second),
 			PreloadedRootFS:       []config.RootFS{{"test", "value"}, {"test2", "value2"}},
 			CertFile:              "/tmp/server_cert",
 			KeyFile:               "/tmp/server_key",
 			SessionName:           "test",
-			SupportedProviders:    []string{"provider1", "provider2"},
+			ShadowMode:            true,
+			Simulation: &config.SimulationConfig{
+				MemoryMB:          4096,
+				DiskMB:            8192,
+				Containers:        100,
+				VolumeDrivers:     []string{"simdriver"},
+				AllocationLatency: durationjson.Duration(50 * time.Millisecond),
+			},
+			SupportedProviders: []string{"provider1", "provider2"},
+			SystemDaemons: &config.SystemDaemonsConfig{
+				CheckInterval: durationjson.Duration(10 * time.Second),
+				MaxBackoff:    durationjson.Duration(2 * time.Minute),
+				Daemons: []config.DaemonConfig{{
+					Name:     "log-forwarder",
+					RootFS:   "test2",
+					MemoryMB: 64,
+					DiskMB:   128,
+					Path:     "/bin/forwarder",
+					Args:     []string{"-v"},
+					Env:      map[string]string{"LEVEL": "info"},
+					User:     "vcap",
+					LogGuid:  "forwarder-logs",
+				}},
+			},
+			TaskCancelGracePeriod: durationjson.Duration(30 * time.Second),
+			TaskRetry: &config.RetryConfig{
+				MaxAttempts:    2,
+				InitialBackoff: durationjson.Duration(5 * time.Second),
+			},
+			TruncateTaskResults:   true,
 			Zone:                  "test-zone",
 			LoggregatorConfig: loggingclient.Config{
 				UseV2API:      true,
@@ -286,6 +443,8 @@ var _ = Describe("RepConfig", func() {
 				SessionName:               "rep",
 				LockTTL:                   durationjson.Duration(locket.DefaultSessionTTL),
 				LockRetryInterval:         durationjson.Duration(locket.RetryInterval),
+				OperationHistorySize:      1000,
+				TaskCancelGracePeriod:     durationjson.Duration(10 * time.Second),
 				ListenAddr:                "0.0.0.0:1800",
 				ListenAddrSecurable:       "0.0.0.0:1801",
 				PollingInterval:           durationjson.Duration(30 * time.Second),
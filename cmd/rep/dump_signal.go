@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// operationHistoryDumpSignal makes the rep log its operation history.
+var operationHistoryDumpSignal os.Signal = syscall.SIGUSR2
@@ -0,0 +1,7 @@
+package main
+
+import "os"
+
+// operationHistoryDumpSignal is not available on Windows, where the operation
+// history can only be queried through the localhost server.
+var operationHistoryDumpSignal os.Signal
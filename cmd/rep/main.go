@@ -34,13 +34,18 @@ import (
 	"code.cloudfoundry.org/operationq"
 	"code.cloudfoundry.org/rep"
 	"code.cloudfoundry.org/rep/auctioncellrep"
+	"code.cloudfoundry.org/rep/canary"
 	"code.cloudfoundry.org/rep/cmd/rep/config"
+	"code.cloudfoundry.org/rep/daemon"
 	"code.cloudfoundry.org/rep/evacuation"
 	"code.cloudfoundry.org/rep/evacuation/evacuation_context"
 	"code.cloudfoundry.org/rep/generator"
 	"code.cloudfoundry.org/rep/handlers"
 	"code.cloudfoundry.org/rep/harmonizer"
+	"code.cloudfoundry.org/rep/history"
 	"code.cloudfoundry.org/rep/maintain"
+	"code.cloudfoundry.org/rep/ratelimit"
+	"code.cloudfoundry.org/rep/reaper"
 	"github.com/hashicorp/consul/api"
 	"github.com/nu7hatch/gouuid"
 	"github.com/tedsuo/ifrit"
@@ -51,6 +56,9 @@ import (
 
 const (
 	bbsPingTimeout = 5 * time.Minute
+
+	// the bulk sync is reported unhealthy after missing this many polls
+	bulkSyncHealthPollingIntervals = 3
 )
 
 var configFilePath = flag.String(
@@ -99,11 +107,25 @@ func main() {
 		os.Exit(1)
 	}
 
+	if repConfig.Simulation != nil {
+		runSimulation(logger, reconfigurableSink, repConfig, clock)
+		return
+	}
+
 	metronClient, err := initializeMetron(logger, repConfig)
 	if err != nil {
 		logger.Error("failed-to-initialize-metron-client", err)
 		os.Exit(1)
 	}
+	bbsClient := initializeBBSClient(logger, repConfig)
+	if repConfig.ShadowMode {
+		err = verifyNoLiveRep(logger, bbsClient, repConfig.CellID)
+		if err != nil {
+			logger.Error("refusing-to-start-in-shadow-mode", err)
+			os.Exit(1)
+		}
+	}
+
 	executorClient, containerMetricsProvider, executorMembers, err := executorinit.Initialize(logger, repConfig.ExecutorConfig, repConfig.VContainerClientConfig, repConfig.CellID, gardenHealthcheckRootFS, metronClient, clock)
 	if err != nil {
 		logger.Error("failed-to-initialize-executor", err)
@@ -117,23 +139,55 @@ func main() {
 
 	evacuatable, evacuationReporter, evacuationNotifier := evacuation_context.New()
 
-	// only one outstanding operation per container is necessary
-	queue := operationq.NewSlidingQueue(1)
+	queue := harmonizer.NewInstrumentedQueue(logger, initializeOperationQueue(logger, repConfig), metronClient)
+
+	operationHistory := history.New(repConfig.OperationHistorySize, clock)
+	var operationHistoryReporter handlers.OperationHistory
+	if operationHistory != nil {
+		operationHistoryReporter = operationHistory
+	}
+
+	lifecycles := rep.NewLifecycleRegistry()
 
 	evacuator := evacuation.NewEvacuator(
 		logger,
 		clock,
 		executorClient,
+		lifecycles,
 		evacuationNotifier,
 		repConfig.CellID,
 		time.Duration(repConfig.EvacuationTimeout),
 		time.Duration(repConfig.EvacuationPollingInterval),
 	)
 
-	bbsClient := initializeBBSClient(logger, repConfig)
+	bbsRateLimitCancel := make(chan struct{})
+	limitedBBSClient := initializeRateLimitedBBSClient(bbsClient, repConfig.BBSRateLimit, clock, metronClient, bbsRateLimitCancel)
 	url := repURL(repConfig)
 	address := repAddress(logger, repConfig)
 	cellPresence := initializeCellPresence(address, serviceClient, executorClient, logger, repConfig, rootFSNames, url)
+
+	var executorStateCache *auctioncellrep.ExecutorStateCache
+	if repConfig.ExecutorStateCacheTTL > 0 {
+		executorStateCache = auctioncellrep.NewExecutorStateCache(logger, executorClient, clock, time.Duration(repConfig.ExecutorStateCacheTTL))
+	}
+
+	var cellCanary *canary.Canary
+	var canaryReporter auctioncellrep.CanaryReporter
+	if repConfig.Canary != nil && !repConfig.ShadowMode {
+		cellCanary = initializeCanary(logger, repConfig, executorClient, evacuationNotifier, metronClient, clock)
+		canaryReporter = cellCanary
+	}
+
+	var daemonManager *daemon.Manager
+	var reservedResources rep.Resources
+	if repConfig.SystemDaemons != nil && !repConfig.ShadowMode {
+		daemonManager = initializeDaemonManager(logger, repConfig, executorClient, evacuationNotifier, metronClient, clock)
+		reservedResources = daemonManager.ReservedResources()
+		lifecycles.RegisterProcessor(rep.DaemonLifecycle, daemonManager)
+		lifecycles.RegisterReporter(rep.DaemonLifecycle, daemonManager)
+		lifecycles.RegisterCellManaged(rep.DaemonLifecycle)
+	}
+
 	auctionCellRep := auctioncellrep.New(
 		repConfig.CellID,
 		url,
@@ -148,24 +202,45 @@ func main() {
 		repConfig.OptionalPlacementTags,
 		repConfig.ProxyMemoryAllocationMB,
 		repConfig.EnableContainerProxy,
+		repConfig.DomainQuotas,
+		executorStateCache,
+		canaryReporter,
+		lifecycles,
+		reservedResources,
 	)
-	httpServer := initializeServer(auctionCellRep, executorClient, evacuatable, logger, repConfig, false)
-	httpsServer := initializeServer(auctionCellRep, executorClient, evacuatable, logger, repConfig, true)
-
 	opGenerator := generator.New(
 		repConfig.CellID,
-		bbsClient,
+		limitedBBSClient,
 		executorClient,
 		metronClient,
 		evacuationReporter,
 		uint64(time.Duration(repConfig.EvacuationTimeout).Seconds()),
+		clock,
+		time.Duration(repConfig.BulkFullSyncInterval),
+		queue,
+		initializeRetryPolicy(repConfig.BBSRetry),
+		initializeRetryPolicy(repConfig.TaskRetry),
+		generator.ResultFilePolicy{
+			MaxSize:  repConfig.MaxTaskResultSize,
+			Truncate: repConfig.TruncateTaskResults,
+		},
+		generator.CrashDiagnosticsPolicy{
+			Paths:   repConfig.CrashDiagnosticsPaths,
+			MaxSize: repConfig.CrashDiagnosticsMaxSize,
+		},
+		operationHistory,
+		lifecycles,
+		repConfig.ShadowMode,
+		time.Duration(repConfig.DesiredLRPCacheTTL),
 	)
 
 	cleanup := evacuation.NewEvacuationCleanup(
 		logger,
 		repConfig.CellID,
 		time.Duration(repConfig.GracefulShutdownInterval),
-		bbsClient,
+		// The cleanup runs once the rep is signaled, after requests waiting
+		// for the rate limit were canceled.
+		ratelimit.Uncancelable(limitedBBSClient),
 		executorClient,
 		clock,
 		metronClient,
@@ -184,28 +259,89 @@ func main() {
 		logger,
 		time.Duration(repConfig.PollingInterval),
 		time.Duration(repConfig.EvacuationPollingInterval),
+		initializeAdaptiveInterval(repConfig.AdaptivePollingInterval),
 		evacuationNotifier,
 		clock,
 		opGenerator,
 		queue,
 		metronClient,
 	)
+	eventConsumer := harmonizer.NewEventConsumer(logger, opGenerator, queue, clock, metronClient, bulker)
+
+	healthChecks := handlers.HealthChecks{
+		"executor":     handlers.ExecutorPingCheck(executorClient),
+		"garden":       handlers.GardenHealthCheck(executorClient),
+		"bbs":          handlers.BBSPingCheck(bbsClient),
+		"bulk_sync":    handlers.BulkSyncCheck(bulker, clock, bulkSyncHealthPollingIntervals*maxPollingInterval(repConfig)),
+		"event_stream": handlers.EventStreamCheck(eventConsumer),
+	}
+	if !repConfig.ShadowMode {
+		healthChecks["presence"] = handlers.PresenceCheck(cellPresence)
+	}
+	if cellCanary != nil {
+		healthChecks["canary"] = handlers.CanaryCheck(cellCanary)
+	}
+
+	// A rep in shadow mode neither takes auction work nor changes the
+	// containers on the cell, and does not announce itself or write to the
+	// BBS when it shuts down.
+	var cellClient auctioncellrep.AuctionCellClient = auctionCellRep
+	var handlerExecutorClient executor.Client = executorClient
+	if repConfig.ShadowMode {
+		logger.Info("running-in-shadow-mode")
+		cellClient = shadowCellClient{AuctionCellClient: auctionCellRep}
+		handlerExecutorClient = shadowExecutorClient{Client: executorClient}
+	}
 
-	members := grouper.Members{
-		{"presence", cellPresence},
+	httpServer := initializeServer(cellClient, auctionCellRep, handlerExecutorClient, evacuatable, healthChecks, opGenerator, operationHistoryReporter, bulker, clock, logger, repConfig, false)
+	httpsServer := initializeServer(cellClient, auctionCellRep, handlerExecutorClient, evacuatable, healthChecks, opGenerator, operationHistoryReporter, bulker, clock, logger, repConfig, true)
+
+	members := grouper.Members{}
+	if !repConfig.ShadowMode {
+		members = append(members, grouper.Member{"presence", cellPresence})
+	}
+	members = append(members, grouper.Members{
 		{"http_server", httpServer},
 		{"https_server", httpsServer},
-		{"evacuation-cleanup", cleanup},
+	}...)
+	if !repConfig.ShadowMode {
+		members = append(members, grouper.Member{"evacuation-cleanup", cleanup})
+	}
+	members = append(members, grouper.Members{
 		{"bulker", bulker},
-		{"event-consumer", harmonizer.NewEventConsumer(logger, opGenerator, queue)},
+		{"event-consumer", eventConsumer},
 		{"evacuator", evacuator},
+	}...)
+
+	if executorStateCache != nil {
+		members = append(members, grouper.Member{"executor-state-cache", executorStateCache})
 	}
 
-	if repConfig.EnableConsulServiceRegistration {
+	if cellCanary != nil {
+		members = append(members, grouper.Member{"canary", cellCanary})
+	}
+
+	if daemonManager != nil {
+		members = append(members, grouper.Member{"system-daemons", daemonManager})
+	}
+
+	if repConfig.OrphanReaper != nil {
+		members = append(members, grouper.Member{"orphan-reaper", initializeOrphanReaper(logger, repConfig, lifecycles, executorClient, metronClient, clock)})
+	}
+
+	if operationHistory != nil && operationHistoryDumpSignal != nil {
+		members = append(members, grouper.Member{"operation-history-dumper", history.NewDumper(logger, operationHistory, operationHistoryDumpSignal)})
+	}
+
+	if repConfig.EnableConsulServiceRegistration && !repConfig.ShadowMode {
 		registrationRunner := initializeRegistrationRunner(logger, consulClient, repConfig, portNum, clock)
 		members = append(members, grouper.Member{"registration-runner", registrationRunner})
 	}
 
+	// Stopped first, so that operations waiting for the BBS rate limit do not
+	// hold up the members that run them.
+	members = append(members, grouper.Member{"bbs-rate-limit", initializeRateLimitCanceller(bbsRateLimitCancel)})
+
 	members = append(executorMembers, members...)
 
 	if repConfig.DebugAddress != "" {
@@ -237,7 +373,7 @@ func initializeCellPresence(
 	repConfig config.RepConfig,
 	preloadedRootFSes []string,
 	repUrl string,
-) ifrit.Runner {
+) maintain.PresenceRunner {
 	if repConfig.CellRegistrationsLocketEnabled {
 		locketClient, err := locket.NewClient(logger, repConfig.ClientLocketConfig)
 		if err != nil {
@@ -272,14 +408,14 @@ func initializeCellPresence(
 		}
 
 		logger.Debug("presence-payload", lager.Data{"payload": lockPayload})
-		return lock.NewPresenceRunner(
+		return maintain.NewPresenceTracker(lock.NewPresenceRunner(
 			logger,
 			locketClient,
 			lockPayload,
 			int64(time.Duration(repConfig.LockTTL)/time.Second),
 			clock.NewClock(),
 			locket.RetryInterval,
-		)
+		))
 	} else {
 		config := maintain.Config{
 			CellID:                repConfig.CellID,
@@ -304,15 +440,199 @@ func initializeCellPresence(
 	}
 }
 
+func initializeCanary(
+	logger lager.Logger,
+	repConfig config.RepConfig,
+	executorClient executor.Client,
+	evacuationNotifier evacuation_context.EvacuationNotifier,
+	metronClient loggingclient.IngressClient,
+	clock clock.Clock,
+) *canary.Canary {
+	canaryConfig := repConfig.Canary
+
+	rootFS := canaryConfig.RootFS
+	if rootFS == "" && len(repConfig.PreloadedRootFS) > 0 {
+		rootFS = repConfig.PreloadedRootFS[0].Name
+	}
+	rootFSPath, ok := repConfig.PreloadedRootFS.StackPathMap()[rootFS]
+	if !ok {
+		logger.Fatal("failed-to-find-canary-rootfs", auctioncellrep.ErrPreloadedRootFSNotFound, lager.Data{"rootfs": rootFS})
+	}
+
+	return canary.New(
+		logger,
+		canary.Config{
+			Interval:         time.Duration(canaryConfig.Interval),
+			Timeout:          time.Duration(canaryConfig.Timeout),
+			FailureThreshold: canaryConfig.FailureThreshold,
+			RootFSPath:       rootFSPath,
+			MemoryMB:         canaryConfig.MemoryMB,
+			DiskMB:           canaryConfig.DiskMB,
+			Path:             canaryConfig.Path,
+			Args:             canaryConfig.Args,
+			User:             canaryConfig.User,
+		},
+		executorClient,
+		evacuationNotifier,
+		metronClient,
+		clock,
+	)
+}
+
+func initializeAdaptiveInterval(adaptiveConfig *config.AdaptiveSyncConfig) harmonizer.AdaptiveInterval {
+	if adaptiveConfig == nil {
+		return harmonizer.AdaptiveInterval{}
+	}
+
+	return harmonizer.AdaptiveInterval{
+		MinInterval:   time.Duration(adaptiveConfig.MinInterval),
+		MaxInterval:   time.Duration(adaptiveConfig.MaxInterval),
+		BusyThreshold: adaptiveConfig.BusyThreshold,
+		Jitter:        adaptiveConfig.Jitter,
+	}
+}
+
+// maxPollingInterval is the longest the bulker may wait between syncs,
+// ignoring jitter.
+func maxPollingInterval(repConfig config.RepConfig) time.Duration {
+	interval := time.Duration(repConfig.PollingInterval)
+	if repConfig.AdaptivePollingInterval != nil && time.Duration(repConfig.AdaptivePollingInterval.MaxInterval) > interval {
+		interval = time.Duration(repConfig.AdaptivePollingInterval.MaxInterval)
+	}
+	return interval
+}
+
+func initializeDaemonManager(
+	logger lager.Logger,
+	repConfig config.RepConfig,
+	executorClient executor.Client,
+	evacuationNotifier evacuation_context.EvacuationNotifier,
+	metronClient loggingclient.IngressClient,
+	clock clock.Clock,
+) *daemon.Manager {
+	daemonsConfig := repConfig.SystemDaemons
+	stackPathMap := repConfig.PreloadedRootFS.StackPathMap()
+
+	names := map[string]bool{}
+	definitions := make([]daemon.Definition, 0, len(daemonsConfig.Daemons))
+	for _, daemonConfig := range daemonsConfig.Daemons {
+		if daemonConfig.Name == "" || names[daemonConfig.Name] {
+			logger.Fatal("invalid-daemon-name", errors.New("daemon names must be unique and not empty"), lager.Data{"daemon": daemonConfig.Name})
+		}
+		names[daemonConfig.Name] = true
+
+		rootFS := daemonConfig.RootFS
+		if rootFS == "" && len(repConfig.PreloadedRootFS) > 0 {
+			rootFS = repConfig.PreloadedRootFS[0].Name
+		}
+		rootFSPath, ok := stackPathMap[rootFS]
+		if !ok {
+			logger.Fatal("failed-to-find-daemon-rootfs", auctioncellrep.ErrPreloadedRootFSNotFound, lager.Data{"daemon": daemonConfig.Name, "rootfs": rootFS})
+		}
+
+		definitions = append(definitions, daemon.Definition{
+			Name:       daemonConfig.Name,
+			RootFSPath: rootFSPath,
+			MemoryMB:   daemonConfig.MemoryMB,
+			DiskMB:     daemonConfig.DiskMB,
+			Path:       daemonConfig.Path,
+			Args:       daemonConfig.Args,
+			Env:        daemonConfig.Env,
+			User:       daemonConfig.User,
+			LogGuid:    daemonConfig.LogGuid,
+		})
+	}
+
+	return daemon.New(
+		logger,
+		daemon.Config{
+			CheckInterval:  time.Duration(daemonsConfig.CheckInterval),
+			InitialBackoff: time.Duration(daemonsConfig.InitialBackoff),
+			MaxBackoff:     time.Duration(daemonsConfig.MaxBackoff),
+			Daemons:        definitions,
+		},
+		executorClient,
+		evacuationNotifier,
+		metronClient,
+		clock,
+	)
+}
+
+func initializeOrphanReaper(
+	logger lager.Logger,
+	repConfig config.RepConfig,
+	lifecycles *rep.LifecycleRegistry,
+	executorClient executor.Client,
+	metronClient loggingclient.IngressClient,
+	clock clock.Clock,
+) *reaper.Reaper {
+	return reaper.New(
+		logger,
+		reaper.Config{
+			Interval:    time.Duration(repConfig.OrphanReaper.Interval),
+			GracePeriod: time.Duration(repConfig.OrphanReaper.GracePeriod),
+			DryRun:      repConfig.OrphanReaper.DryRun || repConfig.ShadowMode,
+		},
+		repConfig.CellID,
+		lifecycles,
+		executorClient,
+		metronClient,
+		clock,
+	)
+}
+
+func initializeRetryPolicy(retryConfig *config.RetryConfig) generator.RetryPolicy {
+	if retryConfig == nil {
+		return generator.RetryPolicy{}
+	}
+
+	policy := generator.RetryPolicy{
+		MaxAttempts:    retryConfig.MaxAttempts,
+		InitialBackoff: time.Duration(retryConfig.InitialBackoff),
+		MaxBackoff:     time.Duration(retryConfig.MaxBackoff),
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = time.Second
+	}
+	if policy.MaxBackoff < policy.InitialBackoff {
+		policy.MaxBackoff = policy.InitialBackoff
+	}
+	return policy
+}
+
+func initializeOperationQueue(logger lager.Logger, repConfig config.RepConfig) operationq.Queue {
+	queueConfig := repConfig.OperationQueue
+	if queueConfig == nil {
+		// only one outstanding operation per container is necessary
+		return operationq.NewSlidingQueue(1)
+	}
+
+	return harmonizer.NewPriorityQueue(logger, harmonizer.PriorityQueueConfig{
+		MaxConcurrency: queueConfig.MaxConcurrency,
+		ClassConcurrency: map[generator.Priority]int{
+			generator.PriorityCompleted: queueConfig.CompletedConcurrency,
+			generator.PriorityRunning:   queueConfig.RunningConcurrency,
+			generator.PriorityReserved:  queueConfig.ReservedConcurrency,
+			generator.PriorityResidual:  queueConfig.ResidualConcurrency,
+		},
+	})
+}
+
 func initializeServer(
-	auctionCellRep *auctioncellrep.AuctionCellRep,
+	auctionCellClient auctioncellrep.AuctionCellClient,
+	metricCollector handlers.MetricCollector,
 	executorClient executor.Client,
 	evacuatable evacuation_context.Evacuatable,
+	healthChecks handlers.HealthChecks,
+	reconciliationReporter handlers.ReconciliationReporter,
+	operationHistory handlers.OperationHistory,
+	bulkSyncer handlers.BulkSyncer,
+	clock clock.Clock,
 	logger lager.Logger,
 	repConfig config.RepConfig,
 	networkAccessible bool,
 ) ifrit.Runner {
-	handlers := handlers.New(auctionCellRep, auctionCellRep, executorClient, evacuatable, logger, networkAccessible)
+	handlers := handlers.New(auctionCellClient, metricCollector, executorClient, evacuatable, healthChecks, reconciliationReporter, operationHistory, bulkSyncer, time.Duration(repConfig.TaskCancelGracePeriod), clock, logger, networkAccessible)
 	routes := rep.NewRoutes(networkAccessible)
 	router, err := rata.NewRouter(routes, handlers)
 
@@ -384,6 +704,38 @@ func initializeBBSClient(
 	return bbsClient
 }
 
+func initializeRateLimitedBBSClient(
+	bbsClient bbs.InternalClient,
+	rateLimitConfig *config.BBSRateLimitConfig,
+	clock clock.Clock,
+	metronClient loggingclient.IngressClient,
+	cancel <-chan struct{},
+) bbs.InternalClient {
+	if rateLimitConfig == nil {
+		return bbsClient
+	}
+
+	reads := ratelimit.Budget{
+		RequestsPerSecond: rateLimitConfig.ReadsPerSecond,
+		Burst:             rateLimitConfig.ReadBurst,
+	}
+	writes := ratelimit.Budget{
+		RequestsPerSecond: rateLimitConfig.WritesPerSecond,
+		Burst:             rateLimitConfig.WriteBurst,
+	}
+	return ratelimit.NewBBSClient(bbsClient, reads, writes, clock, metronClient, cancel)
+}
+
+// initializeRateLimitCanceller closes cancel when the rep is signaled.
+func initializeRateLimitCanceller(cancel chan<- struct{}) ifrit.Runner {
+	return ifrit.RunFunc(func(signals <-chan os.Signal, ready chan<- struct{}) error {
+		close(ready)
+		<-signals
+		close(cancel)
+		return nil
+	})
+}
+
 func initializeConsulClient(
 	logger lager.Logger,
 	repConfig config.RepConfig,
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/rep"
+	"code.cloudfoundry.org/rep/auctioncellrep"
+)
+
+// shadowCellClient hands all auction work back as failed, so that a rep in
+// shadow mode never starts workloads on the cell.
+type shadowCellClient struct {
+	auctioncellrep.AuctionCellClient
+}
+
+func (c shadowCellClient) Perform(logger lager.Logger, work rep.Work) (rep.Work, error) {
+	logger.Info("shadow-mode-refused-work", lager.Data{
+		"lrp-starts": len(work.LRPs),
+		"tasks":      len(work.Tasks),
+	})
+	return work, nil
+}
+
+// shadowExecutorClient only logs the container stops and deletions requested
+// through the handlers of a rep in shadow mode.
+type shadowExecutorClient struct {
+	executor.Client
+}
+
+func (c shadowExecutorClient) StopContainer(logger lager.Logger, guid string) error {
+	logger.Info("shadow-mode-skipped-stop-container", lager.Data{"container-guid": guid})
+	return nil
+}
+
+func (c shadowExecutorClient) DeleteContainer(logger lager.Logger, guid string) error {
+	logger.Info("shadow-mode-skipped-delete-container", lager.Data{"container-guid": guid})
+	return nil
+}
+
+// verifyNoLiveRep returns an error when a rep has registered its presence for
+// the cell. A rep in shadow mode runs its own executor, which destroys the
+// containers it finds on start and cannot see the containers of another rep,
+// so it must not run next to a live rep.
+func verifyNoLiveRep(logger lager.Logger, bbsClient bbs.InternalClient, cellID string) error {
+	cells, err := bbsClient.Cells(logger)
+	if err != nil {
+		return fmt.Errorf("failed to fetch cell presences: %s", err)
+	}
+
+	for _, cell := range cells {
+		if cell.CellId == cellID {
+			return fmt.Errorf("cell %s is owned by a live rep at %s", cellID, cell.RepAddress)
+		}
+	}
+	return nil
+}
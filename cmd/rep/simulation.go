@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/debugserver"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/rep"
+	"code.cloudfoundry.org/rep/auctioncellrep"
+	"code.cloudfoundry.org/rep/cmd/rep/config"
+	"code.cloudfoundry.org/rep/evacuation/evacuation_context"
+	"code.cloudfoundry.org/rep/maintain"
+	"code.cloudfoundry.org/rep/simulation"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/grouper"
+	"github.com/tedsuo/ifrit/sigmon"
+)
+
+// runSimulation serves the auction from an in-memory executor so that many
+// simulated cells can be registered against a real auctioneer. No containers
+// are run and nothing is reported to the BBS.
+func runSimulation(
+	logger lager.Logger,
+	reconfigurableSink *lager.ReconfigurableSink,
+	repConfig config.RepConfig,
+	clock clock.Clock,
+) {
+	logger = logger.Session("simulation")
+
+	simConfig := repConfig.Simulation
+	simExecutor := simulation.NewExecutor(
+		clock,
+		executor.ExecutorResources{
+			MemoryMB:   simConfig.MemoryMB,
+			DiskMB:     simConfig.DiskMB,
+			Containers: simConfig.Containers,
+		},
+		simConfig.VolumeDrivers,
+		time.Duration(simConfig.AllocationLatency),
+	)
+
+	evacuatable, evacuationReporter, _ := evacuation_context.New()
+
+	rootFSes := repConfig.PreloadedRootFS
+	url := repURL(repConfig)
+	address := repAddress(logger, repConfig)
+	serviceClient := maintain.NewCellPresenceClient(initializeConsulClient(logger, repConfig), clock)
+	cellPresence := initializeCellPresence(address, serviceClient, simExecutor, logger, repConfig, rootFSes.Names(), url)
+
+	cellRep := simulation.NewCellRep(
+		auctioncellrep.New(
+			repConfig.CellID,
+			url,
+			rootFSes.StackPathMap(),
+			simExecutor,
+			repConfig.SupportedProviders,
+			repConfig.Zone,
+			auctioncellrep.GenerateGuid,
+			simExecutor,
+			evacuationReporter,
+			repConfig.PlacementTags,
+			repConfig.OptionalPlacementTags,
+			0,
+			false,
+			repConfig.DomainQuotas,
+			nil,
+			nil,
+			nil,
+			rep.Resources{},
+		),
+		simExecutor,
+	)
+
+	httpServer := initializeServer(cellRep, cellRep, simExecutor, evacuatable, nil, nil, nil, nil, clock, logger, repConfig, false)
+	httpsServer := initializeServer(cellRep, cellRep, simExecutor, evacuatable, nil, nil, nil, nil, clock, logger, repConfig, true)
+
+	members := grouper.Members{
+		{"presence", cellPresence},
+		{"http_server", httpServer},
+		{"https_server", httpsServer},
+	}
+
+	if repConfig.DebugAddress != "" {
+		members = append(grouper.Members{
+			{"debug-server", debugserver.Runner(repConfig.DebugAddress, reconfigurableSink)},
+		}, members...)
+	}
+
+	group := grouper.NewOrdered(os.Interrupt, members)
+
+	monitor := ifrit.Invoke(sigmon.New(group))
+
+	logger.Info("started", lager.Data{"cell-id": repConfig.CellID, "capacity": simConfig})
+
+	err := <-monitor.Wait()
+	if err != nil {
+		logger.Error("exited-with-failure", err)
+		os.Exit(1)
+	}
+
+	logger.Info("exited")
+}
@@ -15,8 +15,12 @@ const (
 	ResultFileTag = "result-file"
 	DomainTag     = "domain"
 
-	TaskLifecycle = "task"
-	LRPLifecycle  = "lrp"
+	TaskLifecycle   = "task"
+	LRPLifecycle    = "lrp"
+	CanaryLifecycle = "canary"
+	DaemonLifecycle = "daemon"
+
+	DaemonNameTag = "daemon-name"
 
 	ProcessGuidTag  = "process-guid"
 	InstanceGuidTag = "instance-guid"
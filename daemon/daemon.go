@@ -0,0 +1,326 @@
+package daemon
+
+import (
+	"os"
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock"
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/rep"
+	"code.cloudfoundry.org/rep/evacuation/evacuation_context"
+)
+
+const (
+	daemonCrashesMetric = "RepDaemonCrashes"
+
+	ContainerGuidPrefix = "daemon-"
+	LogSource           = "DAEMON"
+
+	DefaultCheckInterval  = 5 * time.Second
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = 5 * time.Minute
+)
+
+// Definition describes a system daemon to keep running in a container on the
+// cell. Logs are only forwarded when LogGuid is set.
+type Definition struct {
+	Name       string
+	RootFSPath string
+	MemoryMB   int
+	DiskMB     int
+	Path       string
+	Args       []string
+	Env        map[string]string
+	User       string
+	LogGuid    string
+}
+
+type Config struct {
+	CheckInterval  time.Duration
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Daemons        []Definition
+}
+
+// ContainerGuid returns the guid of the container running the named daemon.
+func ContainerGuid(name string) string {
+	return ContainerGuidPrefix + name
+}
+
+type daemonState struct {
+	crashes      int
+	restartAt    time.Time
+	runningSince time.Time
+}
+
+// Manager keeps the configured daemons running in executor containers. A
+// daemon whose container crashes or cannot be started is restarted after a
+// backoff that doubles per consecutive failure up to MaxBackoff, and is reset
+// once the daemon has stayed up for MaxBackoff. Daemons are no longer checked
+// or restarted once the cell starts evacuating, and their containers are
+// destroyed when the manager is signaled.
+//
+// Manager is also the processor and cell state reporter of the daemon
+// lifecycle, so that daemon containers completing are noticed as soon as the
+// generator sees them and daemons are reported apart from LRPs and tasks.
+type Manager struct {
+	logger             lager.Logger
+	config             Config
+	executorClient     executor.Client
+	evacuationNotifier evacuation_context.EvacuationNotifier
+	metronClient       loggingclient.IngressClient
+	clock              clock.Clock
+
+	checkNow chan struct{}
+	states   map[string]*daemonState
+}
+
+func New(
+	logger lager.Logger,
+	config Config,
+	executorClient executor.Client,
+	evacuationNotifier evacuation_context.EvacuationNotifier,
+	metronClient loggingclient.IngressClient,
+	clock clock.Clock,
+) *Manager {
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = DefaultCheckInterval
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = DefaultInitialBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultMaxBackoff
+	}
+
+	states := make(map[string]*daemonState, len(config.Daemons))
+	for _, definition := range config.Daemons {
+		states[definition.Name] = &daemonState{}
+	}
+
+	return &Manager{
+		logger:             logger.Session("daemons"),
+		config:             config,
+		executorClient:     executorClient,
+		evacuationNotifier: evacuationNotifier,
+		metronClient:       metronClient,
+		clock:              clock,
+		checkNow:           make(chan struct{}, 1),
+		states:             states,
+	}
+}
+
+// ReservedResources returns the resources of every configured daemon. They
+// are withheld from the capacity the cell offers to the auction.
+func (m *Manager) ReservedResources() rep.Resources {
+	reserved := rep.Resources{}
+	for _, definition := range m.config.Daemons {
+		reserved.MemoryMB += int32(definition.MemoryMB)
+		reserved.DiskMB += int32(definition.DiskMB)
+		reserved.Containers++
+	}
+	return reserved
+}
+
+// Process schedules an immediate check of the daemons when a daemon
+// container has completed.
+func (m *Manager) Process(logger lager.Logger, container executor.Container) {
+	if container.State != executor.StateCompleted {
+		return
+	}
+
+	logger.Info("daemon-container-completed", lager.Data{"daemon": container.Tags[rep.DaemonNameTag]})
+	select {
+	case m.checkNow <- struct{}{}:
+	default:
+	}
+}
+
+// ReportContainer adds a daemon container to the cell state.
+func (m *Manager) ReportContainer(logger lager.Logger, container executor.Container, state *rep.CellState) {
+	state.Daemons = append(state.Daemons, rep.Daemon{
+		Name:          container.Tags[rep.DaemonNameTag],
+		ContainerGuid: container.Guid,
+		State:         string(container.State),
+		MemoryMB:      int32(container.MemoryMB),
+		DiskMB:        int32(container.DiskMB),
+	})
+}
+
+func (m *Manager) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	logger := m.logger
+	logger.Info("starting", lager.Data{"daemons": len(m.config.Daemons)})
+	defer logger.Info("finished")
+
+	evacuationNotify := m.evacuationNotifier.EvacuateNotify()
+	close(ready)
+
+	ticker := m.clock.NewTicker(m.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		m.check(logger)
+
+		select {
+		case <-ticker.C():
+		case <-m.checkNow:
+		case <-evacuationNotify:
+			logger.Info("notified-of-evacuation")
+			ticker.Stop()
+			signal := <-signals
+			logger.Info("received-signal", lager.Data{"signal": signal.String()})
+			m.destroy(logger)
+			return nil
+		case signal := <-signals:
+			logger.Info("received-signal", lager.Data{"signal": signal.String()})
+			m.destroy(logger)
+			return nil
+		}
+	}
+}
+
+func (m *Manager) check(logger lager.Logger) {
+	for _, definition := range m.config.Daemons {
+		m.checkDaemon(logger.Session("check", lager.Data{"daemon": definition.Name}), definition)
+	}
+}
+
+func (m *Manager) checkDaemon(logger lager.Logger, definition Definition) {
+	guid := ContainerGuid(definition.Name)
+	state := m.states[definition.Name]
+
+	container, err := m.executorClient.GetContainer(logger, guid)
+	if err == executor.ErrContainerNotFound {
+		state.runningSince = time.Time{}
+		if m.clock.Now().Before(state.restartAt) {
+			return
+		}
+		m.start(logger, definition, state)
+		return
+	}
+	if err != nil {
+		logger.Error("failed-to-get-container", err)
+		return
+	}
+
+	switch container.State {
+	case executor.StateRunning:
+		if state.runningSince.IsZero() {
+			state.runningSince = m.clock.Now()
+		}
+		if state.crashes > 0 && m.clock.Since(state.runningSince) >= m.config.MaxBackoff {
+			logger.Info("daemon-stable", lager.Data{"crashes": state.crashes})
+			state.crashes = 0
+		}
+
+	case executor.StateCompleted:
+		err := m.executorClient.DeleteContainer(logger, guid)
+		if err != nil && err != executor.ErrContainerNotFound {
+			logger.Error("failed-to-delete-container", err)
+			return
+		}
+
+		m.backOff(logger, state, container.RunResult.FailureReason)
+		err = m.metronClient.IncrementCounter(daemonCrashesMetric)
+		if err != nil {
+			logger.Error("failed-to-increment-daemon-crashes-counter", err)
+		}
+	}
+}
+
+func (m *Manager) start(logger lager.Logger, definition Definition, state *daemonState) {
+	guid := ContainerGuid(definition.Name)
+	logger.Info("starting-daemon", lager.Data{"container-guid": guid})
+
+	resource := executor.NewResource(definition.MemoryMB, definition.DiskMB, 0, definition.RootFSPath)
+	tags := executor.Tags{
+		rep.LifecycleTag:  rep.DaemonLifecycle,
+		rep.DaemonNameTag: definition.Name,
+	}
+	allocationRequest := executor.NewAllocationRequest(guid, &resource, tags)
+
+	failures := m.executorClient.AllocateContainers(logger, []executor.AllocationRequest{allocationRequest})
+	if len(failures) > 0 {
+		m.backOff(logger, state, "failed to allocate container: "+failures[0].ErrorMsg)
+		return
+	}
+
+	runInfo := executor.RunInfo{
+		Action: models.WrapAction(&models.RunAction{
+			Path: definition.Path,
+			Args: definition.Args,
+			User: definition.User,
+		}),
+		Env: environment(definition.Env),
+	}
+	if definition.LogGuid != "" {
+		runInfo.LogConfig = executor.LogConfig{
+			Guid:       definition.LogGuid,
+			SourceName: LogSource,
+		}
+	}
+	runRequest := executor.NewRunRequest(guid, &runInfo, tags)
+
+	err := m.executorClient.RunContainer(logger, &runRequest)
+	if err != nil {
+		deleteErr := m.executorClient.DeleteContainer(logger, guid)
+		if deleteErr != nil && deleteErr != executor.ErrContainerNotFound {
+			logger.Error("failed-to-delete-container", deleteErr)
+		}
+		m.backOff(logger, state, "failed to run container: "+err.Error())
+		return
+	}
+
+	logger.Info("started-daemon", lager.Data{"container-guid": guid})
+}
+
+// backOff delays the next start of the daemon by a backoff that doubles with
+// every consecutive failure.
+func (m *Manager) backOff(logger lager.Logger, state *daemonState, reason string) {
+	state.crashes++
+	state.runningSince = time.Time{}
+
+	backoff := m.config.InitialBackoff
+	for i := 1; i < state.crashes && backoff < m.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > m.config.MaxBackoff {
+		backoff = m.config.MaxBackoff
+	}
+	state.restartAt = m.clock.Now().Add(backoff)
+
+	logger.Error("daemon-failed", nil, lager.Data{
+		"reason":     reason,
+		"crashes":    state.crashes,
+		"restart-in": backoff.String(),
+	})
+}
+
+func (m *Manager) destroy(logger lager.Logger) {
+	logger = logger.Session("destroy")
+	for _, definition := range m.config.Daemons {
+		guid := ContainerGuid(definition.Name)
+		err := m.executorClient.DeleteContainer(logger, guid)
+		if err != nil && err != executor.ErrContainerNotFound {
+			logger.Error("failed-to-delete-container", err, lager.Data{"container-guid": guid})
+		}
+	}
+}
+
+func environment(env map[string]string) []executor.EnvironmentVariable {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	variables := make([]executor.EnvironmentVariable, 0, len(names))
+	for _, name := range names {
+		variables = append(variables, executor.EnvironmentVariable{Name: name, Value: env[name]})
+	}
+	return variables
+}
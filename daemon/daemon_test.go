@@ -0,0 +1,249 @@
+package daemon_test
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/executor"
+	fake_client "code.cloudfoundry.org/executor/fakes"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/rep"
+	"code.cloudfoundry.org/rep/daemon"
+	"code.cloudfoundry.org/rep/evacuation/evacuation_context"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Manager", func() {
+	const (
+		checkInterval  = 5 * time.Second
+		initialBackoff = 10 * time.Second
+		maxBackoff     = time.Minute
+	)
+
+	var (
+		logger           *lagertest.TestLogger
+		fakeClock        *fakeclock.FakeClock
+		executorClient   *fake_client.FakeClient
+		fakeMetronClient *mfakes.FakeIngressClient
+		evacuatable      evacuation_context.Evacuatable
+
+		manager *daemon.Manager
+		process ifrit.Process
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		executorClient = new(fake_client.FakeClient)
+		fakeMetronClient = new(mfakes.FakeIngressClient)
+
+		var evacuationNotifier evacuation_context.EvacuationNotifier
+		evacuatable, _, evacuationNotifier = evacuation_context.New()
+
+		executorClient.GetContainerReturns(executor.Container{}, executor.ErrContainerNotFound)
+
+		manager = daemon.New(
+			logger,
+			daemon.Config{
+				CheckInterval:  checkInterval,
+				InitialBackoff: initialBackoff,
+				MaxBackoff:     maxBackoff,
+				Daemons: []daemon.Definition{{
+					Name:       "forwarder",
+					RootFSPath: "/path/to/rootfs",
+					MemoryMB:   64,
+					DiskMB:     128,
+					Path:       "/bin/forwarder",
+					Args:       []string{"-v"},
+					Env:        map[string]string{"LEVEL": "info", "DEST": "syslog"},
+					User:       "vcap",
+					LogGuid:    "forwarder-logs",
+				}},
+			},
+			executorClient,
+			evacuationNotifier,
+			fakeMetronClient,
+			fakeClock,
+		)
+	})
+
+	JustBeforeEach(func() {
+		process = ifrit.Invoke(manager)
+		Eventually(executorClient.GetContainerCallCount).Should(Equal(1))
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive())
+	})
+
+	tick := func(checks int) {
+		Eventually(fakeClock.WatcherCount).Should(Equal(1))
+		fakeClock.Increment(checkInterval)
+		Eventually(executorClient.GetContainerCallCount).Should(Equal(checks))
+	}
+
+	It("starts a container for each daemon", func() {
+		Eventually(executorClient.RunContainerCallCount).Should(Equal(1))
+
+		_, requests := executorClient.AllocateContainersArgsForCall(0)
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Guid).To(Equal(daemon.ContainerGuid("forwarder")))
+		Expect(requests[0].Resource).To(Equal(executor.NewResource(64, 128, 0, "/path/to/rootfs")))
+		Expect(requests[0].Tags).To(Equal(executor.Tags{
+			rep.LifecycleTag:  rep.DaemonLifecycle,
+			rep.DaemonNameTag: "forwarder",
+		}))
+
+		_, runRequest := executorClient.RunContainerArgsForCall(0)
+		Expect(runRequest.Guid).To(Equal(daemon.ContainerGuid("forwarder")))
+		Expect(runRequest.Action).To(Equal(models.WrapAction(&models.RunAction{
+			Path: "/bin/forwarder",
+			Args: []string{"-v"},
+			User: "vcap",
+		})))
+		Expect(runRequest.Env).To(Equal([]executor.EnvironmentVariable{
+			{Name: "DEST", Value: "syslog"},
+			{Name: "LEVEL", Value: "info"},
+		}))
+		Expect(runRequest.LogConfig).To(Equal(executor.LogConfig{Guid: "forwarder-logs", SourceName: daemon.LogSource}))
+	})
+
+	It("reserves the resources of every daemon", func() {
+		Expect(manager.ReservedResources()).To(Equal(rep.NewResources(64, 128, 1)))
+	})
+
+	It("leaves a running daemon alone", func() {
+		executorClient.GetContainerReturns(executor.Container{State: executor.StateRunning}, nil)
+		tick(2)
+		Expect(executorClient.AllocateContainersCallCount()).To(Equal(1))
+		Expect(executorClient.DeleteContainerCallCount()).To(BeZero())
+	})
+
+	Context("when the daemon crashes", func() {
+		BeforeEach(func() {
+			executorClient.GetContainerReturns(executor.Container{
+				State:     executor.StateCompleted,
+				RunResult: executor.ContainerRunResult{Failed: true, FailureReason: "exit status 1"},
+			}, nil)
+		})
+
+		JustBeforeEach(func() {
+			Eventually(fakeMetronClient.IncrementCounterCallCount).Should(Equal(1))
+			executorClient.GetContainerReturns(executor.Container{}, executor.ErrContainerNotFound)
+		})
+
+		It("deletes the container and counts the crash", func() {
+			Expect(executorClient.DeleteContainerCallCount()).To(Equal(1))
+			_, guid := executorClient.DeleteContainerArgsForCall(0)
+			Expect(guid).To(Equal(daemon.ContainerGuid("forwarder")))
+			Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("RepDaemonCrashes"))
+		})
+
+		It("restarts it after the backoff", func() {
+			tick(2)
+			Expect(executorClient.AllocateContainersCallCount()).To(BeZero())
+
+			tick(3)
+			Eventually(executorClient.RunContainerCallCount).Should(Equal(1))
+			Expect(executorClient.AllocateContainersCallCount()).To(Equal(1))
+		})
+
+		Context("and the restart fails", func() {
+			BeforeEach(func() {
+				executorClient.AllocateContainersReturns([]executor.AllocationFailure{
+					executor.NewAllocationFailure(&executor.AllocationRequest{}, "insufficient resources"),
+				})
+			})
+
+			It("doubles the backoff", func() {
+				tick(2)
+				tick(3)
+				Eventually(logger).Should(gbytes.Say("insufficient resources"))
+				Expect(executorClient.AllocateContainersCallCount()).To(Equal(1))
+
+				for checks := 4; checks <= 6; checks++ {
+					tick(checks)
+				}
+				Expect(executorClient.AllocateContainersCallCount()).To(Equal(1))
+
+				tick(7)
+				Eventually(executorClient.AllocateContainersCallCount).Should(Equal(2))
+			})
+		})
+	})
+
+	Context("when a daemon container completes", func() {
+		BeforeEach(func() {
+			executorClient.GetContainerReturns(executor.Container{State: executor.StateRunning}, nil)
+		})
+
+		It("checks the daemons without waiting for the next interval", func() {
+			completed := executor.Container{
+				Guid:  daemon.ContainerGuid("forwarder"),
+				State: executor.StateCompleted,
+				Tags:  executor.Tags{rep.LifecycleTag: rep.DaemonLifecycle, rep.DaemonNameTag: "forwarder"},
+			}
+			executorClient.GetContainerReturns(completed, nil)
+
+			manager.Process(logger, completed)
+			Eventually(executorClient.DeleteContainerCallCount).Should(Equal(1))
+		})
+	})
+
+	Context("when the cell evacuates", func() {
+		JustBeforeEach(func() {
+			Eventually(executorClient.RunContainerCallCount).Should(Equal(1))
+			evacuatable.Evacuate()
+			Eventually(logger).Should(gbytes.Say("notified-of-evacuation"))
+		})
+
+		It("stops checking and restarting the daemons", func() {
+			fakeClock.Increment(checkInterval)
+			Consistently(executorClient.GetContainerCallCount).Should(Equal(1))
+			Expect(executorClient.AllocateContainersCallCount()).To(Equal(1))
+		})
+
+		It("still deletes the daemon containers when signaled", func() {
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive())
+			Expect(executorClient.DeleteContainerCallCount()).To(Equal(1))
+		})
+	})
+
+	It("reports daemon containers in the cell state", func() {
+		state := rep.CellState{}
+		manager.ReportContainer(logger, executor.Container{
+			Guid:     daemon.ContainerGuid("forwarder"),
+			State:    executor.StateRunning,
+			Resource: executor.NewResource(64, 128, 0, "/path/to/rootfs"),
+			Tags:     executor.Tags{rep.LifecycleTag: rep.DaemonLifecycle, rep.DaemonNameTag: "forwarder"},
+		}, &state)
+
+		Expect(state.Daemons).To(Equal([]rep.Daemon{{
+			Name:          "forwarder",
+			ContainerGuid: daemon.ContainerGuid("forwarder"),
+			State:         string(executor.StateRunning),
+			MemoryMB:      64,
+			DiskMB:        128,
+		}}))
+		Expect(state.LRPs).To(BeEmpty())
+		Expect(state.Tasks).To(BeEmpty())
+	})
+
+	It("deletes the daemon containers when signaled", func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive())
+
+		Expect(executorClient.DeleteContainerCallCount()).To(Equal(1))
+		_, guid := executorClient.DeleteContainerArgsForCall(0)
+		Expect(guid).To(Equal(daemon.ContainerGuid("forwarder")))
+	})
+})
@@ -0,0 +1 @@
+package daemon // import "code.cloudfoundry.org/rep/daemon"
@@ -7,6 +7,7 @@ import (
 	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/executor"
 	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/rep"
 	"code.cloudfoundry.org/rep/evacuation/evacuation_context"
 )
 
@@ -14,6 +15,7 @@ type Evacuator struct {
 	logger             lager.Logger
 	clock              clock.Clock
 	executorClient     executor.Client
+	lifecycles         *rep.LifecycleRegistry
 	evacuationNotifier evacuation_context.EvacuationNotifier
 	cellID             string
 	evacuationTimeout  time.Duration
@@ -24,6 +26,7 @@ func NewEvacuator(
 	logger lager.Logger,
 	clock clock.Clock,
 	executorClient executor.Client,
+	lifecycles *rep.LifecycleRegistry,
 	evacuationNotifier evacuation_context.EvacuationNotifier,
 	cellID string,
 	evacuationTimeout time.Duration,
@@ -33,6 +36,7 @@ func NewEvacuator(
 		logger:             logger,
 		clock:              clock,
 		executorClient:     executorClient,
+		lifecycles:         lifecycles,
 		evacuationNotifier: evacuationNotifier,
 		cellID:             cellID,
 		evacuationTimeout:  evacuationTimeout,
@@ -102,6 +106,8 @@ func (e *Evacuator) evacuate(logger lager.Logger, doneCh chan<- struct{}) {
 	}
 }
 
+// allContainersEvacuated ignores containers of cell managed lifecycles, which
+// are not evacuated and are only destroyed when the rep shuts down.
 func (e *Evacuator) allContainersEvacuated(logger lager.Logger) bool {
 	containers, err := e.executorClient.ListContainers(logger)
 	if err != nil {
@@ -109,5 +115,10 @@ func (e *Evacuator) allContainersEvacuated(logger lager.Logger) bool {
 		return false
 	}
 
-	return len(containers) == 0
+	for _, container := range containers {
+		if !e.lifecycles.CellManaged(container.Tags[rep.LifecycleTag]) {
+			return false
+		}
+	}
+	return true
 }
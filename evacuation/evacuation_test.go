@@ -50,10 +50,14 @@ var _ = Describe("Evacuation", func() {
 
 		evacuatable, _, evacuationNotifier = evacuation_context.New()
 
+		lifecycles := rep.NewLifecycleRegistry()
+		lifecycles.RegisterCellManaged(rep.DaemonLifecycle)
+
 		evacuator = evacuation.NewEvacuator(
 			logger,
 			fakeClock,
 			executorClient,
+			lifecycles,
 			evacuationNotifier,
 			cellID,
 			evacuationTimeout,
@@ -144,6 +148,19 @@ var _ = Describe("Evacuation", func() {
 				})
 			})
 
+			Context("and only containers of cell managed lifecycles remain", func() {
+				BeforeEach(func() {
+					executorClient.ListContainersReturns([]executor.Container{
+						{Guid: "daemon-guid", State: executor.StateRunning, Tags: executor.Tags{rep.LifecycleTag: rep.DaemonLifecycle}},
+					}, nil)
+				})
+
+				It("exits without waiting for them", func() {
+					Eventually(errChan).Should(Receive(BeNil()))
+					Expect(executorClient.ListContainersCallCount()).To(Equal(1))
+				})
+			})
+
 			Context("and are not all destroyed before the timeout elapses", func() {
 				BeforeEach(func() {
 					executorClient.ListContainersReturns(containers, nil)
@@ -36,8 +36,24 @@ type FakeGenerator struct {
 		result1 <-chan operationq.Operation
 		result2 error
 	}
-	invocations      map[string][][]interface{}
-	invocationsMutex sync.RWMutex
+	ReconciliationReportStub        func(lager.Logger) (generator.ReconciliationReport, error)
+	reconciliationReportMutex       sync.RWMutex
+	reconciliationReportArgsForCall []struct {
+		arg1 lager.Logger
+	}
+	reconciliationReportReturns struct {
+		result1 generator.ReconciliationReport
+		result2 error
+	}
+	reconciliationReportReturnsOnCall map[int]struct {
+		result1 generator.ReconciliationReport
+		result2 error
+	}
+	ForceFullSyncStub        func()
+	forceFullSyncMutex       sync.RWMutex
+	forceFullSyncArgsForCall []struct{}
+	invocations              map[string][][]interface{}
+	invocationsMutex         sync.RWMutex
 }
 
 func (fake *FakeGenerator) BatchOperations(arg1 lager.Logger) (map[string]operationq.Operation, error) {
@@ -142,6 +158,73 @@ func (fake *FakeGenerator) OperationStreamReturnsOnCall(i int, result1 <-chan op
 	}{result1, result2}
 }
 
+func (fake *FakeGenerator) ReconciliationReport(arg1 lager.Logger) (generator.ReconciliationReport, error) {
+	fake.reconciliationReportMutex.Lock()
+	ret, specificReturn := fake.reconciliationReportReturnsOnCall[len(fake.reconciliationReportArgsForCall)]
+	fake.reconciliationReportArgsForCall = append(fake.reconciliationReportArgsForCall, struct {
+		arg1 lager.Logger
+	}{arg1})
+	fake.recordInvocation("ReconciliationReport", []interface{}{arg1})
+	fake.reconciliationReportMutex.Unlock()
+	if fake.ReconciliationReportStub != nil {
+		return fake.ReconciliationReportStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.reconciliationReportReturns.result1, fake.reconciliationReportReturns.result2
+}
+
+func (fake *FakeGenerator) ReconciliationReportCallCount() int {
+	fake.reconciliationReportMutex.RLock()
+	defer fake.reconciliationReportMutex.RUnlock()
+	return len(fake.reconciliationReportArgsForCall)
+}
+
+func (fake *FakeGenerator) ReconciliationReportArgsForCall(i int) lager.Logger {
+	fake.reconciliationReportMutex.RLock()
+	defer fake.reconciliationReportMutex.RUnlock()
+	return fake.reconciliationReportArgsForCall[i].arg1
+}
+
+func (fake *FakeGenerator) ReconciliationReportReturns(result1 generator.ReconciliationReport, result2 error) {
+	fake.ReconciliationReportStub = nil
+	fake.reconciliationReportReturns = struct {
+		result1 generator.ReconciliationReport
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGenerator) ReconciliationReportReturnsOnCall(i int, result1 generator.ReconciliationReport, result2 error) {
+	fake.ReconciliationReportStub = nil
+	if fake.reconciliationReportReturnsOnCall == nil {
+		fake.reconciliationReportReturnsOnCall = make(map[int]struct {
+			result1 generator.ReconciliationReport
+			result2 error
+		})
+	}
+	fake.reconciliationReportReturnsOnCall[i] = struct {
+		result1 generator.ReconciliationReport
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGenerator) ForceFullSync() {
+	fake.forceFullSyncMutex.Lock()
+	fake.forceFullSyncArgsForCall = append(fake.forceFullSyncArgsForCall, struct{}{})
+	fake.recordInvocation("ForceFullSync", []interface{}{})
+	fake.forceFullSyncMutex.Unlock()
+	if fake.ForceFullSyncStub != nil {
+		fake.ForceFullSyncStub()
+	}
+}
+
+func (fake *FakeGenerator) ForceFullSyncCallCount() int {
+	fake.forceFullSyncMutex.RLock()
+	defer fake.forceFullSyncMutex.RUnlock()
+	return len(fake.forceFullSyncArgsForCall)
+}
+
 func (fake *FakeGenerator) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -149,6 +232,10 @@ func (fake *FakeGenerator) Invocations() map[string][][]interface{} {
 	defer fake.batchOperationsMutex.RUnlock()
 	fake.operationStreamMutex.RLock()
 	defer fake.operationStreamMutex.RUnlock()
+	fake.reconciliationReportMutex.RLock()
+	defer fake.reconciliationReportMutex.RUnlock()
+	fake.forceFullSyncMutex.RLock()
+	defer fake.forceFullSyncMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
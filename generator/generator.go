@@ -3,9 +3,12 @@ package generator
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
 	"code.cloudfoundry.org/bbs"
 	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock"
 	loggingclient "code.cloudfoundry.org/diego-logging-client"
 	"code.cloudfoundry.org/executor"
 	"code.cloudfoundry.org/lager"
@@ -13,6 +16,7 @@ import (
 	"code.cloudfoundry.org/rep"
 	"code.cloudfoundry.org/rep/evacuation/evacuation_context"
 	"code.cloudfoundry.org/rep/generator/internal"
+	"code.cloudfoundry.org/rep/history"
 	multierror "github.com/hashicorp/go-multierror"
 )
 
@@ -21,19 +25,72 @@ import (
 // Generator encapsulates operation creation in the Rep.
 type Generator interface {
 	// BatchOperations creates a set of operations across all containers the Rep is managing.
+	// When a full sync interval is configured, only guids whose container or BBS state changed
+	// since the previous batch get an operation, except on a periodic full sync.
 	BatchOperations(lager.Logger) (map[string]operationq.Operation, error)
 
 	// OperationStream creates an operation every time a container lifecycle event is observed.
 	OperationStream(lager.Logger) (<-chan operationq.Operation, error)
+
+	// ReconciliationReport describes what BatchOperations would act on, without creating any operations.
+	ReconciliationReport(lager.Logger) (ReconciliationReport, error)
+
+	// ForceFullSync makes the next BatchOperations generate an operation for every guid,
+	// for when the state observed by the previous batch can no longer be trusted.
+	ForceFullSync()
 }
 
+// RetryPolicy bounds how often a container is processed again after a
+// transient BBS failure. A zero MaxAttempts disables retries.
+type RetryPolicy = internal.RetryPolicy
+
+// ResultFilePolicy bounds the size of task result files and whether larger
+// results are truncated instead of failing the task.
+type ResultFilePolicy = internal.ResultFilePolicy
+
+// CrashDiagnosticsPolicy lists files whose tails are attached to the crash
+// reason of LRP containers that fail.
+type CrashDiagnosticsPolicy = internal.CrashDiagnosticsPolicy
+
 type generator struct {
-	cellID            string
-	bbs               bbs.InternalClient
-	executorClient    executor.Client
-	lrpProcessor      internal.LRPProcessor
-	taskProcessor     internal.TaskProcessor
-	containerDelegate internal.ContainerDelegate
+	cellID             string
+	bbs                bbs.InternalClient
+	executorClient     executor.Client
+	lifecycles         *rep.LifecycleRegistry
+	containerDelegate  internal.ContainerDelegate
+	retrier            internal.Retrier
+	taskRetrier        internal.TaskRetrier
+	crashDiagnostics   internal.CrashDiagnostics
+	evacuationReporter evacuation_context.EvacuationReporter
+	instrumentation    instrumentation
+
+	clock            clock.Clock
+	fullSyncInterval time.Duration
+
+	observedLock       sync.Mutex
+	observed           map[string]observedState
+	observedEvacuating bool
+	lastFullSync       time.Time
+}
+
+// observedState is what BatchOperations saw for a single guid. In delta mode
+// an operation is only generated when it differs from the previous batch.
+type observedState struct {
+	hasContainer    bool
+	containerState  executor.State
+	containerFailed bool
+
+	hasInstanceLRP bool
+	instanceState  string
+	instanceTag    models.ModificationTag
+
+	hasEvacuatingLRP bool
+	evacuatingState  string
+	evacuatingTag    models.ModificationTag
+
+	hasTask    bool
+	taskState  models.Task_State
+	taskFailed bool
 }
 
 func New(
@@ -43,25 +100,179 @@ func New(
 	metronClient loggingclient.IngressClient,
 	evacuationReporter evacuation_context.EvacuationReporter,
 	evacuationTTLInSeconds uint64,
+	clock clock.Clock,
+	fullSyncInterval time.Duration,
+	retryQueue operationq.Queue,
+	retryPolicy RetryPolicy,
+	taskRetryPolicy RetryPolicy,
+	resultFilePolicy ResultFilePolicy,
+	crashDiagnosticsPolicy CrashDiagnosticsPolicy,
+	operationHistory *history.History,
+	lifecycles *rep.LifecycleRegistry,
+	shadowMode bool,
+	desiredLRPCacheTTL time.Duration,
 ) Generator {
-	containerDelegate := internal.NewContainerDelegate(executorClient)
-	lrpProcessor := internal.NewLRPProcessor(bbs, containerDelegate, metronClient, cellID, evacuationReporter, evacuationTTLInSeconds)
-	taskProcessor := internal.NewTaskProcessor(bbs, containerDelegate, cellID)
-
-	return &generator{
-		cellID:            cellID,
-		bbs:               bbs,
-		executorClient:    executorClient,
-		lrpProcessor:      lrpProcessor,
-		taskProcessor:     taskProcessor,
-		containerDelegate: containerDelegate,
+	var observers []internal.BBSCallObserver
+	if operationHistory != nil {
+		observers = append(observers, history.RecordCall)
+	}
+	if metronClient != nil {
+		observers = append(observers, countBBSErrors(metronClient))
+	}
+	if len(observers) > 0 {
+		bbs = internal.NewObservedBBSClient(bbs, clock, observers...)
+	}
+
+	// In shadow mode BBS writes and container actions are logged and
+	// recorded in the operation history instead of being made.
+	var shadowObservers []internal.ShadowObserver
+	if operationHistory != nil {
+		shadowObservers = append(shadowObservers, history.RecordShadowedCall)
+	}
+	if shadowMode {
+		bbs = internal.NewShadowBBSClient(bbs, shadowObservers...)
+		if metronClient != nil {
+			metronClient = internal.NewShadowIngressClient(metronClient)
+		}
+	}
+
+	g := &generator{
+		cellID:             cellID,
+		bbs:                bbs,
+		executorClient:     executorClient,
+		evacuationReporter: evacuationReporter,
+		instrumentation: instrumentation{
+			history:      operationHistory,
+			metronClient: metronClient,
+			clock:        clock,
+		},
+		clock:            clock,
+		fullSyncInterval: fullSyncInterval,
+	}
+
+	if retryQueue == nil {
+		retryPolicy = RetryPolicy{}
+		taskRetryPolicy = RetryPolicy{}
+	}
+	retry := func(logger lager.Logger, container executor.Container) {
+		retryQueue.Push(g.operationFromContainer(logger, container))
+	}
+	retrier := internal.NewRetrier(clock, retryPolicy, metronClient, retry)
+	g.retrier = retrier
+	g.taskRetrier = internal.NewTaskRetrier(clock, taskRetryPolicy, metronClient, retry)
+
+	g.containerDelegate = internal.NewContainerDelegate(executorClient, resultFilePolicy, metronClient)
+	if shadowMode {
+		g.containerDelegate = internal.NewShadowContainerDelegate(g.containerDelegate, shadowObservers...)
+	}
+	// Starting many instances of an LRP at once fetches the same DesiredLRP
+	// for each of them, so the LRP processor may share recent fetches.
+	lrpBBS := bbs
+	if desiredLRPCacheTTL > 0 {
+		lrpBBS = internal.NewDesiredLRPCache(bbs, clock, desiredLRPCacheTTL)
+	}
+	g.crashDiagnostics = internal.NewCrashDiagnostics(executorClient, crashDiagnosticsPolicy, metronClient)
+	lrpProcessor := internal.NewLRPProcessor(lrpBBS, g.containerDelegate, retrier, g.crashDiagnostics, metronClient, cellID, evacuationReporter, evacuationTTLInSeconds)
+	taskProcessor := internal.NewTaskProcessor(bbs, g.containerDelegate, retrier, g.taskRetrier, metronClient, cellID)
+
+	if lifecycles == nil {
+		lifecycles = rep.NewLifecycleRegistry()
 	}
+	RegisterLifecycles(lifecycles, lrpProcessor, taskProcessor)
+	g.lifecycles = lifecycles
+
+	return g
 }
 
 func (g *generator) BatchOperations(logger lager.Logger) (map[string]operationq.Operation, error) {
 	logger = logger.Session("batch-operations")
 	logger.Info("started")
 
+	observation, err := g.observe(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	containers := observation.containers
+	instanceLRPs := observation.instanceLRPs
+	evacuatingLRPs := observation.evacuatingLRPs
+	tasks := observation.tasks
+
+	// Containers deleted outside of the rep never succeed or exhaust their
+	// retries, so their backoff and crash diagnostics are dropped here.
+	g.retrier.Prune(containers)
+	g.taskRetrier.Prune(containers)
+	g.crashDiagnostics.Prune(containers)
+
+	batch := make(map[string]operationq.Operation)
+
+	// create operations for processes with containers
+	unknownLifecycles := map[string]int{}
+	for guid, container := range containers {
+		lifecycle := container.Tags[rep.LifecycleTag]
+		if _, ok := g.lifecycles.Processor(lifecycle); !ok {
+			unknownLifecycles[lifecycle]++
+		}
+		batch[guid] = g.operationFromContainer(logger, container)
+	}
+	if len(unknownLifecycles) > 0 {
+		logger.Info("found-containers-with-unknown-lifecycles", lager.Data{"lifecycles": unknownLifecycles})
+	}
+	// create operations for instance lrps with no containers
+	for guid, lrp := range instanceLRPs {
+		if _, foundContainer := batch[guid]; foundContainer {
+			continue
+		}
+		if _, foundEvacuatingLRP := evacuatingLRPs[guid]; foundEvacuatingLRP {
+			op := NewResidualJointLRPOperation(logger, g.bbs, g.containerDelegate, lrp.ActualLRPKey, lrp.ActualLRPInstanceKey)
+			op.instrumentation = g.instrumentation
+			batch[guid] = op
+		} else {
+			op := NewResidualInstanceLRPOperation(logger, g.bbs, g.containerDelegate, lrp.ActualLRPKey, lrp.ActualLRPInstanceKey)
+			op.instrumentation = g.instrumentation
+			batch[guid] = op
+		}
+	}
+
+	// create operations for evacuating lrps with no containers
+	for guid, lrp := range evacuatingLRPs {
+		_, found := batch[guid]
+		if !found {
+			op := NewResidualEvacuatingLRPOperation(logger, g.bbs, g.containerDelegate, lrp.ActualLRPKey, lrp.ActualLRPInstanceKey)
+			op.instrumentation = g.instrumentation
+			batch[guid] = op
+		}
+	}
+
+	// create operations for tasks with no containers
+	for guid, _ := range tasks {
+		_, found := batch[guid]
+		if !found {
+			op := NewResidualTaskOperation(logger, guid, g.cellID, g.bbs, g.containerDelegate)
+			op.instrumentation = g.instrumentation
+			batch[guid] = op
+		}
+	}
+
+	if g.fullSyncInterval > 0 {
+		observed := observeState(containers, instanceLRPs, evacuatingLRPs, tasks)
+		batch = g.changedOperations(logger, batch, observed, g.evacuationReporter.Evacuating())
+	}
+
+	logger.Info("succeeded", lager.Data{"batch-size": len(batch)})
+	return batch, nil
+}
+
+// observation is the state of the cell's containers and of its ActualLRPs
+// and tasks in the BBS, keyed by container guid.
+type observation struct {
+	containers     map[string]executor.Container
+	instanceLRPs   map[string]models.ActualLRP
+	evacuatingLRPs map[string]models.ActualLRP
+	tasks          map[string]*models.Task
+}
+
+func (g *generator) observe(logger lager.Logger) (*observation, error) {
 	containers := make(map[string]executor.Container)
 	instanceLRPs := make(map[string]models.ActualLRP)
 	evacuatingLRPs := make(map[string]models.ActualLRP)
@@ -130,42 +341,96 @@ func (g *generator) BatchOperations(logger lager.Logger) (map[string]operationq.
 	}
 	logger.Info("succeeded-getting-containers-lrps-and-tasks")
 
-	batch := make(map[string]operationq.Operation)
+	return &observation{
+		containers:     containers,
+		instanceLRPs:   instanceLRPs,
+		evacuatingLRPs: evacuatingLRPs,
+		tasks:          tasks,
+	}, nil
+}
 
-	// create operations for processes with containers
-	for guid, _ := range containers {
-		batch[guid] = g.operationFromContainer(logger, guid)
+func (g *generator) ForceFullSync() {
+	g.observedLock.Lock()
+	defer g.observedLock.Unlock()
+	g.observed = nil
+}
+
+// changedOperations drops operations for guids whose state is unchanged since
+// the previous batch, unless a full sync is due. Once the cell starts
+// evacuating every container has to be processed again, so a full sync is
+// due as well.
+func (g *generator) changedOperations(
+	logger lager.Logger,
+	batch map[string]operationq.Operation,
+	observed map[string]observedState,
+	evacuating bool,
+) map[string]operationq.Operation {
+	g.observedLock.Lock()
+	defer g.observedLock.Unlock()
+
+	previous, previousEvacuating := g.observed, g.observedEvacuating
+	g.observed, g.observedEvacuating = observed, evacuating
+
+	now := g.clock.Now()
+	if previous == nil || evacuating != previousEvacuating || now.Sub(g.lastFullSync) >= g.fullSyncInterval {
+		g.lastFullSync = now
+		logger.Info("full-sync", lager.Data{"batch-size": len(batch)})
+		return batch
 	}
-	// create operations for instance lrps with no containers
-	for guid, lrp := range instanceLRPs {
-		if _, foundContainer := batch[guid]; foundContainer {
-			continue
-		}
-		if _, foundEvacuatingLRP := evacuatingLRPs[guid]; foundEvacuatingLRP {
-			batch[guid] = NewResidualJointLRPOperation(logger, g.bbs, g.containerDelegate, lrp.ActualLRPKey, lrp.ActualLRPInstanceKey)
-		} else {
-			batch[guid] = NewResidualInstanceLRPOperation(logger, g.bbs, g.containerDelegate, lrp.ActualLRPKey, lrp.ActualLRPInstanceKey)
+
+	changed := make(map[string]operationq.Operation)
+	for guid, operation := range batch {
+		previousState, found := previous[guid]
+		if !found || previousState != observed[guid] {
+			changed[guid] = operation
 		}
 	}
 
-	// create operations for evacuating lrps with no containers
+	logger.Info("delta-sync", lager.Data{"full-batch-size": len(batch), "changed": len(changed)})
+	return changed
+}
+
+func observeState(
+	containers map[string]executor.Container,
+	instanceLRPs map[string]models.ActualLRP,
+	evacuatingLRPs map[string]models.ActualLRP,
+	tasks map[string]*models.Task,
+) map[string]observedState {
+	observed := make(map[string]observedState)
+
+	for guid, container := range containers {
+		state := observed[guid]
+		state.hasContainer = true
+		state.containerState = container.State
+		state.containerFailed = container.RunResult.Failed
+		observed[guid] = state
+	}
+
+	for guid, lrp := range instanceLRPs {
+		state := observed[guid]
+		state.hasInstanceLRP = true
+		state.instanceState = lrp.State
+		state.instanceTag = lrp.ModificationTag
+		observed[guid] = state
+	}
+
 	for guid, lrp := range evacuatingLRPs {
-		_, found := batch[guid]
-		if !found {
-			batch[guid] = NewResidualEvacuatingLRPOperation(logger, g.bbs, g.containerDelegate, lrp.ActualLRPKey, lrp.ActualLRPInstanceKey)
-		}
+		state := observed[guid]
+		state.hasEvacuatingLRP = true
+		state.evacuatingState = lrp.State
+		state.evacuatingTag = lrp.ModificationTag
+		observed[guid] = state
 	}
 
-	// create operations for tasks with no containers
-	for guid, _ := range tasks {
-		_, found := batch[guid]
-		if !found {
-			batch[guid] = NewResidualTaskOperation(logger, guid, g.cellID, g.bbs, g.containerDelegate)
-		}
+	for guid, task := range tasks {
+		state := observed[guid]
+		state.hasTask = true
+		state.taskState = task.State
+		state.taskFailed = task.Failed
+		observed[guid] = state
 	}
 
-	logger.Info("succeeded", lager.Data{"batch-size": len(batch)})
-	return batch, nil
+	return observed
 }
 
 func (g *generator) OperationStream(logger lager.Logger) (<-chan operationq.Operation, error) {
@@ -200,13 +465,17 @@ func (g *generator) OperationStream(logger lager.Logger) (<-chan operationq.Oper
 			}
 
 			container := lifecycle.Container()
-			opChan <- g.operationFromContainer(logger, container.Guid)
+			opChan <- g.operationFromContainer(logger, container)
 		}
 	}()
 
 	return opChan, nil
 }
 
-func (g *generator) operationFromContainer(logger lager.Logger, guid string) operationq.Operation {
-	return NewContainerOperation(logger, g.lrpProcessor, g.taskProcessor, g.containerDelegate, guid)
+func (g *generator) operationFromContainer(logger lager.Logger, container executor.Container) operationq.Operation {
+	op := NewContainerOperation(logger, g.lifecycles, g.containerDelegate, container.Guid)
+	op.priority = containerPriority(container.State)
+	op.evacuation = g.lifecycles.Evacuated(container.Tags[rep.LifecycleTag]) && g.evacuationReporter.Evacuating()
+	op.instrumentation = g.instrumentation
+	return op
 }
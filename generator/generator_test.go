@@ -2,14 +2,19 @@ package generator_test
 
 import (
 	"errors"
+	"time"
 
 	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
 	"code.cloudfoundry.org/executor"
 	efakes "code.cloudfoundry.org/executor/fakes"
+	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/operationq"
 	"code.cloudfoundry.org/rep"
 	"code.cloudfoundry.org/rep/evacuation/evacuation_context/fake_evacuation_context"
 	"code.cloudfoundry.org/rep/generator"
+	"code.cloudfoundry.org/rep/history"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -24,8 +29,11 @@ func (BogusEvent) EventType() executor.EventType {
 
 var _ = Describe("Generator", func() {
 	var (
-		cellID             string
-		fakeExecutorClient *efakes.FakeClient
+		cellID                 string
+		fakeExecutorClient     *efakes.FakeClient
+		fakeEvacuationReporter *fake_evacuation_context.FakeEvacuationReporter
+		fakeClock              *fakeclock.FakeClock
+		fullSyncInterval       time.Duration
 
 		opGenerator generator.Generator
 	)
@@ -33,8 +41,13 @@ var _ = Describe("Generator", func() {
 	BeforeEach(func() {
 		cellID = "some-cell-id"
 		fakeExecutorClient = new(efakes.FakeClient)
-		fakeEvacuationReporter := &fake_evacuation_context.FakeEvacuationReporter{}
-		opGenerator = generator.New(cellID, fakeBBS, fakeExecutorClient, nil, fakeEvacuationReporter, 0)
+		fakeEvacuationReporter = &fake_evacuation_context.FakeEvacuationReporter{}
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fullSyncInterval = 0
+	})
+
+	JustBeforeEach(func() {
+		opGenerator = generator.New(cellID, fakeBBS, fakeExecutorClient, nil, fakeEvacuationReporter, 0, fakeClock, fullSyncInterval, nil, generator.RetryPolicy{}, generator.RetryPolicy{}, generator.ResultFilePolicy{}, generator.CrashDiagnosticsPolicy{}, nil, nil, false, 0)
 	})
 
 	Describe("BatchOperations", func() {
@@ -86,9 +99,17 @@ var _ = Describe("Generator", func() {
 			BeforeEach(func() {
 				containers := []executor.Container{
 					{Guid: rep.LRPContainerGuid(processGuid, instanceGuidContainerOnly)},
-					{Guid: rep.LRPContainerGuid(processGuid, instanceGuidContainerForInstanceLRP)},
+					{
+						Guid:  rep.LRPContainerGuid(processGuid, instanceGuidContainerForInstanceLRP),
+						State: executor.StateRunning,
+						Tags:  executor.Tags{rep.LifecycleTag: rep.LRPLifecycle},
+					},
 					{Guid: rep.LRPContainerGuid(processGuid, instanceGuidContainerForEvacuatingLRP)},
-					{Guid: guidContainerForTask},
+					{
+						Guid:  guidContainerForTask,
+						State: executor.StateCompleted,
+						Tags:  executor.Tags{rep.LifecycleTag: rep.TaskLifecycle},
+					},
 				}
 
 				actualLRPKey := models.ActualLRPKey{ProcessGuid: processGuid}
@@ -177,6 +198,47 @@ var _ = Describe("Generator", func() {
 				Expect(batch[guid]).To(BeAssignableToTypeOf(new(generator.ResidualTaskOperation)))
 			})
 
+			priorityOf := func(guid string) (generator.Priority, bool) {
+				op, ok := batch[guid].(generator.PrioritizedOperation)
+				Expect(ok).To(BeTrue())
+				return op.Priority(), op.Evacuation()
+			}
+
+			It("prioritizes container operations by the state of the container", func() {
+				priority, _ := priorityOf(guidContainerForTask)
+				Expect(priority).To(Equal(generator.PriorityCompleted))
+
+				priority, _ = priorityOf(rep.LRPContainerGuid(processGuid, instanceGuidContainerForInstanceLRP))
+				Expect(priority).To(Equal(generator.PriorityRunning))
+
+				priority, _ = priorityOf(rep.LRPContainerGuid(processGuid, instanceGuidContainerOnly))
+				Expect(priority).To(Equal(generator.PriorityReserved))
+			})
+
+			It("prioritizes operations without containers as residual and boosts evacuating ones", func() {
+				priority, evacuation := priorityOf(guidTaskOnly)
+				Expect(priority).To(Equal(generator.PriorityResidual))
+				Expect(evacuation).To(BeFalse())
+
+				priority, evacuation = priorityOf(rep.LRPContainerGuid(processGuid, instanceGuidEvacuatingLRPOnly))
+				Expect(priority).To(Equal(generator.PriorityResidual))
+				Expect(evacuation).To(BeTrue())
+			})
+
+			Context("when the cell is evacuating", func() {
+				BeforeEach(func() {
+					fakeEvacuationReporter.EvacuatingReturns(true)
+				})
+
+				It("boosts operations for lrp containers", func() {
+					_, evacuation := priorityOf(rep.LRPContainerGuid(processGuid, instanceGuidContainerForInstanceLRP))
+					Expect(evacuation).To(BeTrue())
+
+					_, evacuation = priorityOf(guidContainerForTask)
+					Expect(evacuation).To(BeFalse())
+				})
+			})
+
 		})
 
 		Context("when retrieving data fails", func() {
@@ -227,6 +289,83 @@ var _ = Describe("Generator", func() {
 		})
 	})
 
+	Describe("BatchOperations in delta mode", func() {
+		var containers []executor.Container
+
+		BeforeEach(func() {
+			fullSyncInterval = time.Minute
+			containers = []executor.Container{
+				{Guid: "container-a", State: executor.StateRunning},
+				{Guid: "container-b", State: executor.StateReserved},
+			}
+			fakeExecutorClient.ListContainersStub = func(lager.Logger) ([]executor.Container, error) {
+				return containers, nil
+			}
+			fakeBBS.TasksByCellIDReturns([]*models.Task{{TaskGuid: "task-only", State: models.Task_Running}}, nil)
+		})
+
+		It("generates operations for every guid on the first batch", func() {
+			batch, err := opGenerator.BatchOperations(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(batch).To(HaveLen(3))
+			Expect(logger).To(Say("full-sync"))
+		})
+
+		Context("after the first batch", func() {
+			JustBeforeEach(func() {
+				_, err := opGenerator.BatchOperations(logger)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("generates no operations when nothing changed", func() {
+				batch, err := opGenerator.BatchOperations(logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(batch).To(BeEmpty())
+				Expect(logger).To(Say("delta-sync"))
+			})
+
+			It("generates operations only for guids whose state changed", func() {
+				containers = []executor.Container{
+					{Guid: "container-a", State: executor.StateRunning},
+					{Guid: "container-b", State: executor.StateRunning},
+					{Guid: "container-c", State: executor.StateReserved},
+				}
+				fakeBBS.TasksByCellIDReturns([]*models.Task{{TaskGuid: "task-only", State: models.Task_Completed}}, nil)
+
+				batch, err := opGenerator.BatchOperations(logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(batch).To(HaveLen(3))
+				Expect(batch).To(HaveKey("container-b"))
+				Expect(batch).To(HaveKey("container-c"))
+				Expect(batch).To(HaveKey("task-only"))
+			})
+
+			It("generates operations for every guid once the full sync interval has elapsed", func() {
+				fakeClock.Increment(fullSyncInterval)
+
+				batch, err := opGenerator.BatchOperations(logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(batch).To(HaveLen(3))
+			})
+
+			It("generates operations for every guid once the cell starts evacuating", func() {
+				fakeEvacuationReporter.EvacuatingReturns(true)
+
+				batch, err := opGenerator.BatchOperations(logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(batch).To(HaveLen(3))
+			})
+
+			It("generates operations for every guid when a full sync is forced", func() {
+				opGenerator.ForceFullSync()
+
+				batch, err := opGenerator.BatchOperations(logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(batch).To(HaveLen(3))
+			})
+		})
+	})
+
 	Describe("OperationStream", func() {
 		const sessionPrefix = "test.operation-stream."
 
@@ -370,4 +509,118 @@ var _ = Describe("Generator", func() {
 			})
 		})
 	})
+
+	Describe("operation metrics", func() {
+		var (
+			fakeMetronClient *mfakes.FakeIngressClient
+			batch            map[string]operationq.Operation
+		)
+
+		BeforeEach(func() {
+			fakeMetronClient = new(mfakes.FakeIngressClient)
+		})
+
+		JustBeforeEach(func() {
+			opGenerator = generator.New(cellID, fakeBBS, fakeExecutorClient, fakeMetronClient, fakeEvacuationReporter, 0, fakeClock, 0, nil, generator.RetryPolicy{}, generator.RetryPolicy{}, generator.ResultFilePolicy{}, generator.CrashDiagnosticsPolicy{}, nil, nil, false, 0)
+
+			var err error
+			batch, err = opGenerator.BatchOperations(logger)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		counters := func() []string {
+			var names []string
+			for i := 0; i < fakeMetronClient.IncrementCounterCallCount(); i++ {
+				names = append(names, fakeMetronClient.IncrementCounterArgsForCall(i))
+			}
+			return names
+		}
+
+		Context("for a residual operation", func() {
+			BeforeEach(func() {
+				fakeBBS.TasksByCellIDReturns([]*models.Task{{TaskGuid: "task-guid"}}, nil)
+				fakeBBS.CompleteTaskReturns(models.ErrResourceNotFound)
+				fakeExecutorClient.GetContainerReturns(executor.Container{}, executor.ErrContainerNotFound)
+			})
+
+			It("counts the operation and reports its duration", func() {
+				batch["task-guid"].Execute()
+
+				Expect(counters()).To(ContainElement("RepResidualTaskOperationCount"))
+				Expect(fakeMetronClient.SendDurationCallCount()).To(Equal(1))
+				name, _, _ := fakeMetronClient.SendDurationArgsForCall(0)
+				Expect(name).To(Equal("RepResidualTaskOperationDuration"))
+			})
+
+			It("counts BBS errors by type", func() {
+				batch["task-guid"].Execute()
+
+				Expect(counters()).To(ContainElement("RepBBSResourceNotFoundErrors"))
+			})
+		})
+
+		Context("for a container operation", func() {
+			var container executor.Container
+
+			BeforeEach(func() {
+				container = executor.Container{
+					Guid:  "task-guid",
+					State: executor.StateCompleted,
+					Tags:  executor.Tags{rep.LifecycleTag: rep.TaskLifecycle},
+				}
+				fakeExecutorClient.ListContainersReturns([]executor.Container{container}, nil)
+				fakeExecutorClient.GetContainerReturns(container, nil)
+			})
+
+			It("names the operation after the lifecycle and state of the container", func() {
+				batch["task-guid"].Execute()
+
+				Expect(counters()).To(ContainElement("RepTaskCompletedContainerOperationCount"))
+				name, _, _ := fakeMetronClient.SendDurationArgsForCall(0)
+				Expect(name).To(Equal("RepTaskCompletedContainerOperationDuration"))
+			})
+		})
+	})
+
+	Describe("shadow mode", func() {
+		var operationHistory *history.History
+
+		BeforeEach(func() {
+			operationHistory = history.New(10, fakeClock)
+
+			container := executor.Container{
+				Guid:  "task-guid",
+				State: executor.StateCompleted,
+				Tags:  executor.Tags{rep.LifecycleTag: rep.TaskLifecycle},
+			}
+			fakeExecutorClient.ListContainersReturns([]executor.Container{container}, nil)
+			fakeExecutorClient.GetContainerReturns(container, nil)
+			fakeBBS.TasksByCellIDReturns([]*models.Task{{TaskGuid: "task-guid", State: models.Task_Running}}, nil)
+		})
+
+		JustBeforeEach(func() {
+			opGenerator = generator.New(cellID, fakeBBS, fakeExecutorClient, nil, fakeEvacuationReporter, 0, fakeClock, 0, nil, generator.RetryPolicy{}, generator.RetryPolicy{}, generator.ResultFilePolicy{}, generator.CrashDiagnosticsPolicy{}, operationHistory, nil, true, 0)
+
+			batch, err := opGenerator.BatchOperations(logger)
+			Expect(err).NotTo(HaveOccurred())
+			batch["task-guid"].Execute()
+		})
+
+		It("only logs the BBS writes and container actions it would make", func() {
+			Expect(fakeBBS.CompleteTaskCallCount()).To(BeZero())
+			Expect(fakeExecutorClient.DeleteContainerCallCount()).To(BeZero())
+
+			Expect(logger).To(Say("shadowed-bbs-call.*CompleteTask"))
+			Expect(logger).To(Say("shadowed-container-action.*DeleteContainer"))
+		})
+
+		It("records them in the operation history", func() {
+			entries := operationHistory.ByGuid("task-guid")
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Calls).To(Equal([]history.Call{
+				{Name: "CompleteTask", Shadowed: true},
+				{Name: "DeleteContainer", Shadowed: true},
+			}))
+		})
+	})
 })
@@ -3,35 +3,67 @@ package internal
 import (
 	"archive/tar"
 	"errors"
-	"fmt"
+	"io"
+	"io/ioutil"
+	"unicode/utf8"
 
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
 	"code.cloudfoundry.org/executor"
 	"code.cloudfoundry.org/lager"
 )
 
-const MAX_RESULT_SIZE = 1024 * 10
+const (
+	MAX_RESULT_SIZE = 1024 * 10
 
-var ErrResultFileTooLarge = errors.New(
-	fmt.Sprintf("result file is too large (over %d bytes)", MAX_RESULT_SIZE),
+	// TruncatedResultMarker ends a result that was cut down to the maximum
+	// result size.
+	TruncatedResultMarker = "...[truncated]"
+
+	taskResultFileSizeMetric       = "RepTaskResultFileSize"
+	taskResultFilesTruncatedMetric = "RepTaskResultFilesTruncated"
+	taskResultFilesTooLargeMetric  = "RepTaskResultFilesTooLarge"
 )
 
+var ErrResultFileTooLarge = errors.New("result file is too large")
+
+// ResultFilePolicy bounds the size of a task result file. A zero MaxSize
+// uses MAX_RESULT_SIZE. Larger results fail the task, unless Truncate is set,
+// in which case they are cut down to MaxSize and end with
+// TruncatedResultMarker.
+type ResultFilePolicy struct {
+	MaxSize  int
+	Truncate bool
+}
+
+func (p ResultFilePolicy) maxSize() int {
+	if p.MaxSize <= 0 {
+		return MAX_RESULT_SIZE
+	}
+	return p.MaxSize
+}
+
 //go:generate counterfeiter -o fake_internal/fake_container_delegate.go container_delegate.go ContainerDelegate
 
 type ContainerDelegate interface {
 	GetContainer(logger lager.Logger, guid string) (executor.Container, bool)
 	RunContainer(logger lager.Logger, req *executor.RunRequest) bool
+	AllocateContainer(logger lager.Logger, req *executor.AllocationRequest) bool
 	StopContainer(logger lager.Logger, guid string) bool
 	DeleteContainer(logger lager.Logger, guid string) bool
 	FetchContainerResultFile(logger lager.Logger, guid string, filename string) (string, error)
 }
 
 type containerDelegate struct {
-	client executor.Client
+	client           executor.Client
+	resultFilePolicy ResultFilePolicy
+	metronClient     loggingclient.IngressClient
 }
 
-func NewContainerDelegate(client executor.Client) ContainerDelegate {
+func NewContainerDelegate(client executor.Client, resultFilePolicy ResultFilePolicy, metronClient loggingclient.IngressClient) ContainerDelegate {
 	return &containerDelegate{
-		client: client,
+		client:           client,
+		resultFilePolicy: resultFilePolicy,
+		metronClient:     metronClient,
 	}
 }
 
@@ -58,6 +90,17 @@ func (d *containerDelegate) RunContainer(logger lager.Logger, req *executor.RunR
 	return true
 }
 
+func (d *containerDelegate) AllocateContainer(logger lager.Logger, req *executor.AllocationRequest) bool {
+	logger.Info("allocating-container")
+	failures := d.client.AllocateContainers(logger, []executor.AllocationRequest{*req})
+	if len(failures) > 0 {
+		logger.Error("failed-allocating-container", errors.New(failures[0].ErrorMsg))
+		return false
+	}
+	logger.Info("succeeded-allocating-container")
+	return true
+}
+
 func (d *containerDelegate) StopContainer(logger lager.Logger, guid string) bool {
 	logger.Info("stopping-container")
 	err := d.client.StopContainer(logger, guid)
@@ -92,20 +135,70 @@ func (d *containerDelegate) FetchContainerResultFile(logger lager.Logger, guid s
 
 	tarReader := tar.NewReader(stream)
 
-	_, err = tarReader.Next()
+	header, err := tarReader.Next()
+	if err != nil {
+		return "", err
+	}
+
+	maxSize := d.resultFilePolicy.maxSize()
+	buf, err := ioutil.ReadAll(io.LimitReader(tarReader, int64(maxSize)+1))
 	if err != nil {
+		logger.Error("failed-reading-container-result", err)
 		return "", err
 	}
 
-	buf := make([]byte, MAX_RESULT_SIZE+1)
-	n, err := tarReader.Read(buf)
-	if n > MAX_RESULT_SIZE {
-		logInfoOrError(logger, "failed-fetching-container-result-too-large", err)
-		return "", ErrResultFileTooLarge
+	d.sendMetric(logger, taskResultFileSizeMetric, int(header.Size))
+
+	if len(buf) > maxSize {
+		data := lager.Data{"result-size": header.Size, "max-result-size": maxSize}
+		if !d.resultFilePolicy.Truncate {
+			logger.Error("failed-fetching-container-result-too-large", ErrResultFileTooLarge, data)
+			d.incrementCounter(logger, taskResultFilesTooLargeMetric)
+			return "", ErrResultFileTooLarge
+		}
+
+		logger.Info("truncating-container-result", data)
+		d.incrementCounter(logger, taskResultFilesTruncatedMetric)
+		return truncateResult(buf, maxSize), nil
+	}
+
+	logger.Info("succeeded-fetching-container-result", lager.Data{"result-size": len(buf)})
+	return string(buf), nil
+}
+
+func (d *containerDelegate) sendMetric(logger lager.Logger, name string, value int) {
+	if d.metronClient == nil {
+		return
+	}
+	err := d.metronClient.SendMetric(name, value)
+	if err != nil {
+		logger.Error("failed-to-send-metric", err, lager.Data{"metric-name": name})
+	}
+}
+
+func (d *containerDelegate) incrementCounter(logger lager.Logger, name string) {
+	if d.metronClient == nil {
+		return
+	}
+	err := d.metronClient.IncrementCounter(name)
+	if err != nil {
+		logger.Error("failed-to-increment-counter", err, lager.Data{"counter-name": name})
+	}
+}
+
+// truncateResult cuts the result down to maxSize bytes including the marker,
+// without splitting a multi-byte character.
+func truncateResult(result []byte, maxSize int) string {
+	if maxSize <= len(TruncatedResultMarker) {
+		return TruncatedResultMarker[:maxSize]
+	}
+
+	end := maxSize - len(TruncatedResultMarker)
+	for end > 0 && !utf8.RuneStart(result[end]) {
+		end--
 	}
 
-	logger.Info("succeeded-fetching-container-result")
-	return string(buf[:n]), nil
+	return string(result[:end]) + TruncatedResultMarker
 }
 
 func logInfoOrError(logger lager.Logger, msg string, err error) {
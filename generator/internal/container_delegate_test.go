@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"code.cloudfoundry.org/archiver/extractor/test_helper"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
 	"code.cloudfoundry.org/executor"
 	"code.cloudfoundry.org/executor/fakes"
 	"code.cloudfoundry.org/lager/lagertest"
@@ -18,16 +19,23 @@ import (
 var _ = Describe("ContainerDelegate", func() {
 	var containerDelegate internal.ContainerDelegate
 	var executorClient *fakes.FakeClient
+	var fakeMetronClient *mfakes.FakeIngressClient
+	var resultFilePolicy internal.ResultFilePolicy
 	var logger *lagertest.TestLogger
 	var expectedGuid = "some-instance-guid"
 	const sessionPrefix = "test"
 
 	BeforeEach(func() {
 		executorClient = new(fakes.FakeClient)
-		containerDelegate = internal.NewContainerDelegate(executorClient)
+		fakeMetronClient = new(mfakes.FakeIngressClient)
+		resultFilePolicy = internal.ResultFilePolicy{}
 		logger = lagertest.NewTestLogger(sessionPrefix)
 	})
 
+	JustBeforeEach(func() {
+		containerDelegate = internal.NewContainerDelegate(executorClient, resultFilePolicy, fakeMetronClient)
+	})
+
 	Describe("RunContainer", func() {
 		var result bool
 		var runRequest executor.RunRequest
@@ -223,6 +231,32 @@ var _ = Describe("ContainerDelegate", func() {
 					Expect(logger).To(gbytes.Say(sessionPrefix + ".fetching-container-result"))
 					Expect(logger).To(gbytes.Say(sessionPrefix + ".succeeded-fetching-container-result"))
 				})
+
+				It("emits the result size", func() {
+					Expect(fakeMetronClient.SendMetricCallCount()).To(Equal(1))
+					name, value, _ := fakeMetronClient.SendMetricArgsForCall(0)
+					Expect(name).To(Equal("RepTaskResultFileSize"))
+					Expect(value).To(Equal(len("some result")))
+				})
+			})
+
+			Context("and the payload is larger than the default but within the configured size", func() {
+				BeforeEach(func() {
+					resultFilePolicy.MaxSize = 2 * internal.MAX_RESULT_SIZE
+					test_helper.WriteTar(
+						fileStream,
+						[]test_helper.ArchiveFile{{
+							Name: "some-file",
+							Body: strings.Repeat("x", internal.MAX_RESULT_SIZE+1),
+							Mode: 0600,
+						}},
+					)
+				})
+
+				It("returns the whole result", func() {
+					Expect(fetchErr).NotTo(HaveOccurred())
+					Expect(result).To(HaveLen(internal.MAX_RESULT_SIZE + 1))
+				})
 			})
 
 			Context("but the payload is too large", func() {
@@ -248,6 +282,51 @@ var _ = Describe("ContainerDelegate", func() {
 				It("logs the failure", func() {
 					Expect(logger).To(gbytes.Say(sessionPrefix + ".failed-fetching-container-result-too-large"))
 				})
+
+				It("counts the oversized result", func() {
+					Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(1))
+					Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("RepTaskResultFilesTooLarge"))
+				})
+
+				Context("when truncation is enabled", func() {
+					BeforeEach(func() {
+						resultFilePolicy.Truncate = true
+					})
+
+					It("returns the result truncated to the maximum size with a marker", func() {
+						Expect(fetchErr).NotTo(HaveOccurred())
+						Expect(result).To(HaveLen(internal.MAX_RESULT_SIZE))
+						Expect(result).To(HaveSuffix(internal.TruncatedResultMarker))
+						Expect(result).To(HavePrefix("xxx"))
+					})
+
+					It("logs and counts the truncation", func() {
+						Expect(logger).To(gbytes.Say(sessionPrefix + ".truncating-container-result"))
+						Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("RepTaskResultFilesTruncated"))
+					})
+				})
+			})
+
+			Context("but a truncated payload would split a multi-byte character", func() {
+				BeforeEach(func() {
+					resultFilePolicy = internal.ResultFilePolicy{
+						MaxSize:  len(internal.TruncatedResultMarker) + 2,
+						Truncate: true,
+					}
+					test_helper.WriteTar(
+						fileStream,
+						[]test_helper.ArchiveFile{{
+							Name: "some-file",
+							Body: "aééééééééééé",
+							Mode: 0600,
+						}},
+					)
+				})
+
+				It("drops the partial character", func() {
+					Expect(fetchErr).NotTo(HaveOccurred())
+					Expect(result).To(Equal("a" + internal.TruncatedResultMarker))
+				})
 			})
 
 			Context("when the stream is empty for whatever reason", func() {
@@ -0,0 +1,220 @@
+package internal
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/executor/depot/log_streamer"
+	"code.cloudfoundry.org/lager"
+)
+
+const (
+	DefaultCrashDiagnosticsMaxSize = 1024
+
+	// crashReasonSummaryMaxSize bounds the summary attached to the crash
+	// reason recorded in the BBS.
+	crashReasonSummaryMaxSize = 256
+)
+
+//go:generate counterfeiter -o fake_internal/fake_crash_diagnostics.go crash_diagnostics.go CrashDiagnostics
+
+// CrashDiagnostics summarizes diagnostic files from a crashed LRP container
+// before it is deleted. The files are fetched once per container, so that
+// retries of the crash reuse them, and are kept until Stream or Forget is
+// called for the container.
+type CrashDiagnostics interface {
+	// Collect returns a short summary for the crash reason, or an empty
+	// string when there is nothing to report.
+	Collect(logger lager.Logger, container executor.Container) string
+	// Stream writes the full diagnostics collected for the container to the
+	// app's log stream and forgets them.
+	Stream(logger lager.Logger, container executor.Container)
+	// Forget drops the diagnostics collected for the container.
+	Forget(guid string)
+	// Prune drops the diagnostics collected for containers missing from
+	// containers, such as containers deleted before their crash was
+	// reported.
+	Prune(containers map[string]executor.Container)
+}
+
+// CrashDiagnosticsPolicy lists the files fetched from a crashed container.
+// Only the tail of each file is kept so that the whole summary fits in
+// MaxSize bytes, which defaults to DefaultCrashDiagnosticsMaxSize. No paths
+// disables collection.
+type CrashDiagnosticsPolicy struct {
+	Paths   []string
+	MaxSize int
+}
+
+type diagnostics struct {
+	summary string
+	details string
+}
+
+type crashDiagnostics struct {
+	executorClient executor.Client
+	policy         CrashDiagnosticsPolicy
+	metronClient   loggingclient.IngressClient
+
+	lock      sync.Mutex
+	collected map[string]diagnostics
+}
+
+func NewCrashDiagnostics(executorClient executor.Client, policy CrashDiagnosticsPolicy, metronClient loggingclient.IngressClient) CrashDiagnostics {
+	if policy.MaxSize <= 0 {
+		policy.MaxSize = DefaultCrashDiagnosticsMaxSize
+	}
+
+	return &crashDiagnostics{
+		executorClient: executorClient,
+		policy:         policy,
+		metronClient:   metronClient,
+		collected:      map[string]diagnostics{},
+	}
+}
+
+func (d *crashDiagnostics) Collect(logger lager.Logger, container executor.Container) string {
+	if len(d.policy.Paths) == 0 {
+		return ""
+	}
+
+	d.lock.Lock()
+	collected, ok := d.collected[container.Guid]
+	d.lock.Unlock()
+	if ok {
+		return collected.summary
+	}
+
+	collected = d.collect(logger, container)
+
+	d.lock.Lock()
+	d.collected[container.Guid] = collected
+	d.lock.Unlock()
+
+	return collected.summary
+}
+
+func (d *crashDiagnostics) Stream(logger lager.Logger, container executor.Container) {
+	d.lock.Lock()
+	collected := d.collected[container.Guid]
+	delete(d.collected, container.Guid)
+	d.lock.Unlock()
+
+	if collected.details == "" || d.metronClient == nil {
+		return
+	}
+
+	streamer := log_streamer.New(
+		container.RunInfo.LogConfig.Guid,
+		container.RunInfo.LogConfig.SourceName,
+		container.RunInfo.LogConfig.Index,
+		d.metronClient,
+	)
+	fmt.Fprintf(streamer.Stderr(), "Crash diagnostics for instance %s:\n%s\n", container.Guid, collected.details)
+	streamer.Flush()
+}
+
+func (d *crashDiagnostics) Forget(guid string) {
+	d.lock.Lock()
+	delete(d.collected, guid)
+	d.lock.Unlock()
+}
+
+func (d *crashDiagnostics) Prune(containers map[string]executor.Container) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for guid := range d.collected {
+		if _, found := containers[guid]; !found {
+			delete(d.collected, guid)
+		}
+	}
+}
+
+// collect fetches the tail of every configured file. The details hold the
+// tails, and the summary only the last line of each.
+func (d *crashDiagnostics) collect(logger lager.Logger, container executor.Container) diagnostics {
+	logger = logger.Session("collect-crash-diagnostics")
+	logger.Info("starting")
+	defer logger.Info("finished")
+
+	tailSize := d.policy.MaxSize / len(d.policy.Paths)
+
+	sections := []string{}
+	lastLines := []string{}
+	for _, path := range d.policy.Paths {
+		tail, err := d.fetchTail(logger, container.Guid, path, tailSize)
+		if err != nil {
+			logger.Info("failed-fetching-diagnostic-file", lager.Data{"path": path, "error": err.Error()})
+			continue
+		}
+		if tail == "" {
+			continue
+		}
+		sections = append(sections, fmt.Sprintf("%s:\n%s", path, tail))
+		lastLines = append(lastLines, fmt.Sprintf("%s: %s", path, tail[strings.LastIndex(tail, "\n")+1:]))
+	}
+
+	if len(sections) == 0 {
+		return diagnostics{}
+	}
+
+	return diagnostics{
+		summary: truncateSummary(strings.Join(lastLines, "\n"), crashReasonSummaryMaxSize),
+		details: truncateSummary(strings.Join(sections, "\n"), d.policy.MaxSize),
+	}
+}
+
+// fetchTail returns up to the last size bytes of the first file in the
+// archive streamed for path.
+func (d *crashDiagnostics) fetchTail(logger lager.Logger, guid, path string, size int) (string, error) {
+	stream, err := d.executorClient.GetFiles(logger, guid, path)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	tarReader := tar.NewReader(stream)
+	header, err := tarReader.Next()
+	if err != nil {
+		return "", err
+	}
+
+	if skip := header.Size - int64(size); skip > 0 {
+		_, err = io.CopyN(ioutil.Discard, tarReader, skip)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	tail, err := ioutil.ReadAll(tarReader)
+	if err != nil {
+		return "", err
+	}
+
+	// drop a multi-byte character split by the cut
+	for len(tail) > 0 && !utf8.RuneStart(tail[0]) {
+		tail = tail[1:]
+	}
+
+	return strings.TrimSpace(string(tail)), nil
+}
+
+func truncateSummary(summary string, maxSize int) string {
+	if len(summary) <= maxSize {
+		return summary
+	}
+
+	end := maxSize
+	for end > 0 && !utf8.RuneStart(summary[end]) {
+		end--
+	}
+	return summary[:end]
+}
@@ -0,0 +1,172 @@
+package internal_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"code.cloudfoundry.org/archiver/extractor/test_helper"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/executor/fakes"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/rep/generator/internal"
+	"github.com/onsi/gomega/gbytes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CrashDiagnostics", func() {
+	var (
+		executorClient   *fakes.FakeClient
+		fakeMetronClient *mfakes.FakeIngressClient
+		logger           *lagertest.TestLogger
+		policy           internal.CrashDiagnosticsPolicy
+		files            map[string]string
+		container        executor.Container
+
+		diagnostics internal.CrashDiagnostics
+		summary     string
+	)
+
+	BeforeEach(func() {
+		executorClient = new(fakes.FakeClient)
+		fakeMetronClient = new(mfakes.FakeIngressClient)
+		logger = lagertest.NewTestLogger("test")
+		policy = internal.CrashDiagnosticsPolicy{
+			Paths:   []string{"/logs/crash.log", "/logs/missing.log"},
+			MaxSize: 64,
+		}
+		files = map[string]string{
+			"/logs/crash.log": "starting\nout of cheese\n",
+		}
+		container = executor.Container{
+			Guid: "some-guid",
+			RunInfo: executor.RunInfo{
+				LogConfig: executor.LogConfig{Guid: "app-guid", SourceName: "CELL", Index: 2},
+			},
+		}
+
+		executorClient.GetFilesStub = func(_ lager.Logger, _, path string) (io.ReadCloser, error) {
+			body, ok := files[path]
+			if !ok {
+				return nil, errors.New("no such file")
+			}
+			buffer := gbytes.NewBuffer()
+			test_helper.WriteTar(buffer, []test_helper.ArchiveFile{{Name: "file", Body: body, Mode: 0600}})
+			return ioutil.NopCloser(buffer), nil
+		}
+	})
+
+	JustBeforeEach(func() {
+		diagnostics = internal.NewCrashDiagnostics(executorClient, policy, fakeMetronClient)
+		summary = diagnostics.Collect(logger, container)
+	})
+
+	It("fetches every configured path from the container", func() {
+		Expect(executorClient.GetFilesCallCount()).To(Equal(2))
+		_, guid, path := executorClient.GetFilesArgsForCall(0)
+		Expect(guid).To(Equal("some-guid"))
+		Expect(path).To(Equal("/logs/crash.log"))
+	})
+
+	It("summarizes the last line of the files that could be fetched", func() {
+		Expect(summary).To(Equal("/logs/crash.log: out of cheese"))
+		Expect(logger).To(gbytes.Say("failed-fetching-diagnostic-file"))
+	})
+
+	It("does not write to the app's log stream while collecting", func() {
+		Expect(fakeMetronClient.SendAppErrorLogCallCount()).To(Equal(0))
+	})
+
+	It("reuses the collected diagnostics for the same container", func() {
+		Expect(diagnostics.Collect(logger, container)).To(Equal(summary))
+		Expect(executorClient.GetFilesCallCount()).To(Equal(2))
+	})
+
+	Context("when the diagnostics are streamed", func() {
+		JustBeforeEach(func() {
+			diagnostics.Stream(logger, container)
+		})
+
+		It("writes the full diagnostics to the app's log stream", func() {
+			Expect(fakeMetronClient.SendAppErrorLogCallCount()).To(BeNumerically(">", 1))
+			appGuid, msg, sourceName, index := fakeMetronClient.SendAppErrorLogArgsForCall(0)
+			Expect(appGuid).To(Equal("app-guid"))
+			Expect(msg).To(Equal("Crash diagnostics for instance some-guid:"))
+			Expect(sourceName).To(Equal("CELL"))
+			Expect(index).To(Equal("2"))
+
+			_, msg, _, _ = fakeMetronClient.SendAppErrorLogArgsForCall(2)
+			Expect(msg).To(Equal("starting"))
+		})
+
+		It("forgets them", func() {
+			calls := fakeMetronClient.SendAppErrorLogCallCount()
+			diagnostics.Stream(logger, container)
+			Expect(fakeMetronClient.SendAppErrorLogCallCount()).To(Equal(calls))
+
+			diagnostics.Collect(logger, container)
+			Expect(executorClient.GetFilesCallCount()).To(Equal(4))
+		})
+	})
+
+	Context("when the diagnostics are forgotten", func() {
+		JustBeforeEach(func() {
+			diagnostics.Forget(container.Guid)
+		})
+
+		It("fetches the files again on the next collection", func() {
+			diagnostics.Collect(logger, container)
+			Expect(executorClient.GetFilesCallCount()).To(Equal(4))
+		})
+
+		It("has nothing to stream", func() {
+			diagnostics.Stream(logger, container)
+			Expect(fakeMetronClient.SendAppErrorLogCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the container is pruned", func() {
+		It("drops the diagnostics once the container is gone", func() {
+			diagnostics.Prune(map[string]executor.Container{})
+
+			diagnostics.Collect(logger, container)
+			Expect(executorClient.GetFilesCallCount()).To(Equal(4))
+		})
+
+		It("keeps the diagnostics while the container exists", func() {
+			diagnostics.Prune(map[string]executor.Container{container.Guid: container})
+
+			diagnostics.Collect(logger, container)
+			Expect(executorClient.GetFilesCallCount()).To(Equal(2))
+		})
+	})
+
+	Context("when a file is larger than its share of the summary", func() {
+		BeforeEach(func() {
+			files["/logs/crash.log"] = strings.Repeat("x", 100) + "\n" + strings.Repeat("y", 300) + "the end"
+			policy.MaxSize = 1024
+		})
+
+		It("keeps the crash reason summary short", func() {
+			Expect(summary).To(HavePrefix("/logs/crash.log: yyy"))
+			Expect(len(summary)).To(BeNumerically("<=", 256))
+		})
+	})
+
+	Context("when no paths are configured", func() {
+		BeforeEach(func() {
+			policy.Paths = nil
+		})
+
+		It("does nothing", func() {
+			Expect(summary).To(BeEmpty())
+			Expect(executorClient.GetFilesCallCount()).To(Equal(0))
+			Expect(fakeMetronClient.SendAppErrorLogCallCount()).To(Equal(0))
+		})
+	})
+})
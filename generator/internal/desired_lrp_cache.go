@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+)
+
+type desiredLRPFetch struct {
+	done    chan struct{}
+	desired *models.DesiredLRP
+	err     error
+}
+
+type cachedDesiredLRP struct {
+	desired   *models.DesiredLRP
+	fetchedAt time.Time
+}
+
+type desiredLRPCache struct {
+	bbs.InternalClient
+	clock clock.Clock
+	ttl   time.Duration
+
+	lock     sync.Mutex
+	entries  map[string]cachedDesiredLRP
+	inflight map[string]*desiredLRPFetch
+}
+
+// NewDesiredLRPCache wraps the client so that a DesiredLRP is served for up
+// to ttl after it was fetched, which spares the BBS a fetch per instance when
+// many instances of an LRP start on the cell at once. Concurrent callers for
+// the same process guid share a single fetch.
+//
+// Before a cached DesiredLRP is served its ModificationTag is compared with
+// the one in the BBS, which only needs the DesiredLRP's scheduling info and
+// not its much larger run info. An updated or deleted DesiredLRP drops the
+// entry and is fetched again. A failed fetch drops the entry as well, and so
+// does a failed claim or start of an instance of the LRP, or the removal of
+// an instance whose container could not be run, since the cached DesiredLRP
+// may be the reason.
+func NewDesiredLRPCache(client bbs.InternalClient, clock clock.Clock, ttl time.Duration) bbs.InternalClient {
+	return &desiredLRPCache{
+		InternalClient: client,
+		clock:          clock,
+		ttl:            ttl,
+		entries:        make(map[string]cachedDesiredLRP),
+		inflight:       make(map[string]*desiredLRPFetch),
+	}
+}
+
+func (c *desiredLRPCache) DesiredLRPByProcessGuid(logger lager.Logger, processGuid string) (*models.DesiredLRP, error) {
+	c.lock.Lock()
+
+	if entry, ok := c.entries[processGuid]; ok && c.clock.Since(entry.fetchedAt) < c.ttl {
+		c.lock.Unlock()
+		if c.unmodified(logger, processGuid, entry.desired) {
+			logger.Debug("using-cached-desired-lrp", lager.Data{"process-guid": processGuid})
+			return entry.desired, nil
+		}
+
+		c.lock.Lock()
+		if current, ok := c.entries[processGuid]; ok && current.desired == entry.desired {
+			delete(c.entries, processGuid)
+		}
+	}
+
+	if fetch, ok := c.inflight[processGuid]; ok {
+		c.lock.Unlock()
+		<-fetch.done
+		return fetch.desired, fetch.err
+	}
+
+	fetch := &desiredLRPFetch{done: make(chan struct{})}
+	c.inflight[processGuid] = fetch
+	c.lock.Unlock()
+
+	fetch.desired, fetch.err = c.InternalClient.DesiredLRPByProcessGuid(logger, processGuid)
+
+	c.lock.Lock()
+	delete(c.inflight, processGuid)
+	c.store(processGuid, fetch)
+	c.lock.Unlock()
+
+	close(fetch.done)
+	return fetch.desired, fetch.err
+}
+
+// store caches the result of a fetch and drops expired entries. It must be
+// called with the lock held.
+func (c *desiredLRPCache) store(processGuid string, fetch *desiredLRPFetch) {
+	now := c.clock.Now()
+	for guid, entry := range c.entries {
+		if now.Sub(entry.fetchedAt) >= c.ttl {
+			delete(c.entries, guid)
+		}
+	}
+
+	if fetch.err != nil || fetch.desired == nil {
+		delete(c.entries, processGuid)
+		return
+	}
+
+	c.entries[processGuid] = cachedDesiredLRP{desired: fetch.desired, fetchedAt: now}
+}
+
+// unmodified returns whether the DesiredLRP in the BBS still has the
+// ModificationTag of the cached one.
+func (c *desiredLRPCache) unmodified(logger lager.Logger, processGuid string, desired *models.DesiredLRP) bool {
+	infos, err := c.InternalClient.DesiredLRPSchedulingInfos(logger, models.DesiredLRPFilter{ProcessGuids: []string{processGuid}})
+	if err != nil {
+		logger.Error("failed-to-check-desired-lrp-modification-tag", err, lager.Data{"process-guid": processGuid})
+		return false
+	}
+
+	if len(infos) != 1 {
+		logger.Info("desired-lrp-deleted", lager.Data{"process-guid": processGuid})
+		return false
+	}
+
+	if !desired.ModificationTag.Equal(&infos[0].ModificationTag) {
+		logger.Info("desired-lrp-modified", lager.Data{
+			"process-guid":     processGuid,
+			"previous-tag":     desired.ModificationTag,
+			"modification-tag": infos[0].ModificationTag,
+		})
+		return false
+	}
+
+	return true
+}
+
+func (c *desiredLRPCache) ClaimActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey) error {
+	err := c.InternalClient.ClaimActualLRP(logger, key, instanceKey)
+	if err != nil {
+		c.invalidate(logger, key.ProcessGuid)
+	}
+	return err
+}
+
+func (c *desiredLRPCache) StartActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey, netInfo *models.ActualLRPNetInfo) error {
+	err := c.InternalClient.StartActualLRP(logger, key, instanceKey, netInfo)
+	if err != nil {
+		c.invalidate(logger, key.ProcessGuid)
+	}
+	return err
+}
+
+func (c *desiredLRPCache) RemoveActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey) error {
+	c.invalidate(logger, key.ProcessGuid)
+	return c.InternalClient.RemoveActualLRP(logger, key, instanceKey)
+}
+
+// invalidate drops the DesiredLRP cached for the process guid, so that the
+// next instance refetches it.
+func (c *desiredLRPCache) invalidate(logger lager.Logger, processGuid string) {
+	c.lock.Lock()
+	_, cached := c.entries[processGuid]
+	delete(c.entries, processGuid)
+	c.lock.Unlock()
+
+	if cached {
+		logger.Debug("invalidated-cached-desired-lrp", lager.Data{"process-guid": processGuid})
+	}
+}
@@ -0,0 +1,226 @@
+package internal_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/fake_bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/rep/generator/internal"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("DesiredLRPCache", func() {
+	const ttl = 5 * time.Second
+
+	var (
+		logger    *lagertest.TestLogger
+		fakeClock *fakeclock.FakeClock
+		fakeBBS   *fake_bbs.FakeInternalClient
+		client    bbs.InternalClient
+
+		desired    *models.DesiredLRP
+		currentTag models.ModificationTag
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeBBS = new(fake_bbs.FakeInternalClient)
+		client = internal.NewDesiredLRPCache(fakeBBS, fakeClock, ttl)
+
+		desired = &models.DesiredLRP{
+			ProcessGuid:     "process-guid",
+			ModificationTag: &models.ModificationTag{Epoch: "epoch", Index: 1},
+		}
+		fakeBBS.DesiredLRPByProcessGuidReturns(desired, nil)
+
+		currentTag = *desired.ModificationTag
+		fakeBBS.DesiredLRPSchedulingInfosStub = func(_ lager.Logger, filter models.DesiredLRPFilter) ([]*models.DesiredLRPSchedulingInfo, error) {
+			return []*models.DesiredLRPSchedulingInfo{{ModificationTag: currentTag}}, nil
+		}
+	})
+
+	It("serves a fetched DesiredLRP until the ttl elapses", func() {
+		for i := 0; i < 3; i++ {
+			fetched, err := client.DesiredLRPByProcessGuid(logger, "process-guid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fetched).To(Equal(desired))
+		}
+		Expect(fakeBBS.DesiredLRPByProcessGuidCallCount()).To(Equal(1))
+
+		fakeClock.Increment(ttl)
+		_, err := client.DesiredLRPByProcessGuid(logger, "process-guid")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeBBS.DesiredLRPByProcessGuidCallCount()).To(Equal(2))
+	})
+
+	It("caches each process guid separately", func() {
+		client.DesiredLRPByProcessGuid(logger, "process-guid")
+		client.DesiredLRPByProcessGuid(logger, "other-process-guid")
+
+		Expect(fakeBBS.DesiredLRPByProcessGuidCallCount()).To(Equal(2))
+		_, guid := fakeBBS.DesiredLRPByProcessGuidArgsForCall(1)
+		Expect(guid).To(Equal("other-process-guid"))
+	})
+
+	It("shares a fetch between concurrent callers", func() {
+		release := make(chan struct{})
+		fakeBBS.DesiredLRPByProcessGuidStub = func(lager.Logger, string) (*models.DesiredLRP, error) {
+			<-release
+			return desired, nil
+		}
+
+		results := make(chan *models.DesiredLRP, 3)
+		for i := 0; i < 3; i++ {
+			go func() {
+				defer GinkgoRecover()
+				fetched, err := client.DesiredLRPByProcessGuid(logger, "process-guid")
+				Expect(err).NotTo(HaveOccurred())
+				results <- fetched
+			}()
+		}
+
+		Eventually(fakeBBS.DesiredLRPByProcessGuidCallCount).Should(Equal(1))
+		Consistently(results).ShouldNot(Receive())
+
+		close(release)
+		for i := 0; i < 3; i++ {
+			Eventually(results).Should(Receive(Equal(desired)))
+		}
+		Expect(fakeBBS.DesiredLRPByProcessGuidCallCount()).To(Equal(1))
+	})
+
+	It("checks the ModificationTag of the DesiredLRP before serving it from the cache", func() {
+		client.DesiredLRPByProcessGuid(logger, "process-guid")
+		client.DesiredLRPByProcessGuid(logger, "process-guid")
+
+		Expect(fakeBBS.DesiredLRPSchedulingInfosCallCount()).To(Equal(1))
+		_, filter := fakeBBS.DesiredLRPSchedulingInfosArgsForCall(0)
+		Expect(filter).To(Equal(models.DesiredLRPFilter{ProcessGuids: []string{"process-guid"}}))
+	})
+
+	Context("when the DesiredLRP is modified within the ttl", func() {
+		var modified *models.DesiredLRP
+
+		BeforeEach(func() {
+			modified = &models.DesiredLRP{
+				ProcessGuid:     "process-guid",
+				ModificationTag: &models.ModificationTag{Epoch: "epoch", Index: 2},
+			}
+		})
+
+		It("fetches and serves the modified DesiredLRP", func() {
+			client.DesiredLRPByProcessGuid(logger, "process-guid")
+
+			fakeBBS.DesiredLRPByProcessGuidReturns(modified, nil)
+			currentTag = *modified.ModificationTag
+
+			fetched, err := client.DesiredLRPByProcessGuid(logger, "process-guid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fetched).To(Equal(modified))
+			Expect(logger).To(gbytes.Say("desired-lrp-modified"))
+			Expect(fakeBBS.DesiredLRPByProcessGuidCallCount()).To(Equal(2))
+
+			fetched, err = client.DesiredLRPByProcessGuid(logger, "process-guid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fetched).To(Equal(modified))
+			Expect(fakeBBS.DesiredLRPByProcessGuidCallCount()).To(Equal(2))
+		})
+	})
+
+	Context("when the DesiredLRP is deleted within the ttl", func() {
+		It("fetches it again", func() {
+			client.DesiredLRPByProcessGuid(logger, "process-guid")
+
+			fakeBBS.DesiredLRPSchedulingInfosStub = nil
+			fakeBBS.DesiredLRPByProcessGuidReturns(nil, models.ErrResourceNotFound)
+
+			_, err := client.DesiredLRPByProcessGuid(logger, "process-guid")
+			Expect(err).To(Equal(models.ErrResourceNotFound))
+			Expect(logger).To(gbytes.Say("desired-lrp-deleted"))
+		})
+	})
+
+	Context("when fetching fails", func() {
+		BeforeEach(func() {
+			fakeBBS.DesiredLRPByProcessGuidReturns(nil, errors.New("boom"))
+		})
+
+		It("does not cache the failure", func() {
+			_, err := client.DesiredLRPByProcessGuid(logger, "process-guid")
+			Expect(err).To(MatchError("boom"))
+
+			fakeBBS.DesiredLRPByProcessGuidReturns(desired, nil)
+			fetched, err := client.DesiredLRPByProcessGuid(logger, "process-guid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fetched).To(Equal(desired))
+			Expect(fakeBBS.DesiredLRPByProcessGuidCallCount()).To(Equal(2))
+		})
+	})
+
+	Describe("invalidation", func() {
+		lrpKey := &models.ActualLRPKey{ProcessGuid: "process-guid", Index: 0, Domain: "domain"}
+		instanceKey := &models.ActualLRPInstanceKey{InstanceGuid: "instance-guid", CellId: "cell-id"}
+
+		BeforeEach(func() {
+			client.DesiredLRPByProcessGuid(logger, "process-guid")
+		})
+
+		expectRefetch := func() {
+			_, err := client.DesiredLRPByProcessGuid(logger, "process-guid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeBBS.DesiredLRPByProcessGuidCallCount()).To(Equal(2))
+		}
+
+		It("keeps the DesiredLRP when an instance is claimed and started", func() {
+			Expect(client.ClaimActualLRP(logger, lrpKey, instanceKey)).To(Succeed())
+			Expect(client.StartActualLRP(logger, lrpKey, instanceKey, &models.ActualLRPNetInfo{})).To(Succeed())
+
+			client.DesiredLRPByProcessGuid(logger, "process-guid")
+			Expect(fakeBBS.DesiredLRPByProcessGuidCallCount()).To(Equal(1))
+			Expect(fakeBBS.ClaimActualLRPCallCount()).To(Equal(1))
+			Expect(fakeBBS.StartActualLRPCallCount()).To(Equal(1))
+		})
+
+		It("drops the DesiredLRP when claiming an instance fails", func() {
+			fakeBBS.ClaimActualLRPReturns(errors.New("boom"))
+			Expect(client.ClaimActualLRP(logger, lrpKey, instanceKey)).To(MatchError("boom"))
+			expectRefetch()
+		})
+
+		It("drops the DesiredLRP when starting an instance fails", func() {
+			fakeBBS.StartActualLRPReturns(errors.New("boom"))
+			Expect(client.StartActualLRP(logger, lrpKey, instanceKey, &models.ActualLRPNetInfo{})).To(MatchError("boom"))
+			expectRefetch()
+		})
+
+		It("drops the DesiredLRP when an instance is removed", func() {
+			Expect(client.RemoveActualLRP(logger, lrpKey, instanceKey)).To(Succeed())
+			Expect(fakeBBS.RemoveActualLRPCallCount()).To(Equal(1))
+			expectRefetch()
+		})
+
+		It("only drops the DesiredLRP of the instance's process guid", func() {
+			client.DesiredLRPByProcessGuid(logger, "other-process-guid")
+			fakeBBS.ClaimActualLRPReturns(errors.New("boom"))
+			client.ClaimActualLRP(logger, lrpKey, instanceKey)
+
+			client.DesiredLRPByProcessGuid(logger, "other-process-guid")
+			Expect(fakeBBS.DesiredLRPByProcessGuidCallCount()).To(Equal(2))
+		})
+	})
+
+	It("passes other requests through", func() {
+		_, err := client.TaskByGuid(logger, "task-guid")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeBBS.TaskByGuidCallCount()).To(Equal(1))
+	})
+})
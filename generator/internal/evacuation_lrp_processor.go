@@ -15,16 +15,20 @@ import (
 type evacuationLRPProcessor struct {
 	bbsClient              bbs.InternalClient
 	containerDelegate      ContainerDelegate
+	retrier                Retrier
+	crashDiagnostics       CrashDiagnostics
 	metronClient           loggingclient.IngressClient
 	cellID                 string
 	evacuationTTLInSeconds uint64
 	evacuatedContainers    sync.Map
 }
 
-func newEvacuationLRPProcessor(bbsClient bbs.InternalClient, containerDelegate ContainerDelegate, metronClient loggingclient.IngressClient, cellID string, evacuationTTLInSeconds uint64) LRPProcessor {
+func newEvacuationLRPProcessor(bbsClient bbs.InternalClient, containerDelegate ContainerDelegate, retrier Retrier, crashDiagnostics CrashDiagnostics, metronClient loggingclient.IngressClient, cellID string, evacuationTTLInSeconds uint64) LRPProcessor {
 	return &evacuationLRPProcessor{
 		bbsClient:              bbsClient,
 		containerDelegate:      containerDelegate,
+		retrier:                retrier,
+		crashDiagnostics:       crashDiagnostics,
 		metronClient:           metronClient,
 		cellID:                 cellID,
 		evacuationTTLInSeconds: evacuationTTLInSeconds,
@@ -108,27 +112,45 @@ func (p *evacuationLRPProcessor) processRunningContainer(logger lager.Logger, lr
 	logger.Info("bbs-evacuate-running-actual-lrp", lager.Data{"net_info": netInfo})
 	keepContainer, err := p.bbsClient.EvacuateRunningActualLRP(logger, lrpContainer.ActualLRPKey, lrpContainer.ActualLRPInstanceKey, netInfo, p.evacuationTTLInSeconds)
 	if keepContainer == false {
+		p.retrier.Succeeded(lrpContainer.Guid)
 		p.containerDelegate.DeleteContainer(logger, lrpContainer.Container.Guid)
 	} else if err != nil {
 		logger.Error("failed-to-evacuate-running-actual-lrp", err, lager.Data{"lrp-key": lrpContainer.ActualLRPKey})
+		p.retrier.Failed(logger, lrpContainer.Container, err)
+	} else {
+		p.retrier.Succeeded(lrpContainer.Guid)
 	}
 }
 
 func (p *evacuationLRPProcessor) processCompletedContainer(logger lager.Logger, lrpContainer *lrpContainer) {
 	logger = logger.Session("process-completed-container")
 
+	var err error
 	if lrpContainer.RunResult.Stopped {
-		_, err := p.bbsClient.EvacuateStoppedActualLRP(logger, lrpContainer.ActualLRPKey, lrpContainer.ActualLRPInstanceKey)
+		_, err = p.bbsClient.EvacuateStoppedActualLRP(logger, lrpContainer.ActualLRPKey, lrpContainer.ActualLRPInstanceKey)
 		if err != nil {
 			logger.Error("failed-to-evacuate-stopped-actual-lrp", err, lager.Data{"lrp-key": lrpContainer.ActualLRPKey})
 		}
 	} else {
-		_, err := p.bbsClient.EvacuateCrashedActualLRP(logger, lrpContainer.ActualLRPKey, lrpContainer.ActualLRPInstanceKey, lrpContainer.RunResult.FailureReason)
+		reason := lrpContainer.RunResult.FailureReason
+		if summary := p.crashDiagnostics.Collect(logger, lrpContainer.Container); summary != "" {
+			reason = fmt.Sprintf("%s\n%s", reason, summary)
+		}
+
+		_, err = p.bbsClient.EvacuateCrashedActualLRP(logger, lrpContainer.ActualLRPKey, lrpContainer.ActualLRPInstanceKey, reason)
 		if err != nil {
 			logger.Error("failed-to-evacuate-crashed-actual-lrp", err, lager.Data{"lrp-key": lrpContainer.ActualLRPKey})
+		} else {
+			p.crashDiagnostics.Stream(logger, lrpContainer.Container)
 		}
 	}
 
+	if err != nil && p.retrier.Failed(logger, lrpContainer.Container, err) {
+		return
+	}
+
+	p.crashDiagnostics.Forget(lrpContainer.Guid)
+	p.retrier.Succeeded(lrpContainer.Guid)
 	p.containerDelegate.DeleteContainer(logger, lrpContainer.Guid)
 }
 
@@ -141,8 +163,12 @@ func (p *evacuationLRPProcessor) evacuateClaimedLRPContainer(logger lager.Logger
 	_, err := p.bbsClient.EvacuateClaimedActualLRP(logger, lrpContainer.ActualLRPKey, lrpContainer.ActualLRPInstanceKey)
 	if err != nil {
 		logger.Error("failed-to-unclaim-actual-lrp", err, lager.Data{"lrp-key": lrpContainer.ActualLRPKey})
+		if p.retrier.Failed(logger, lrpContainer.Container, err) {
+			return
+		}
 	}
 
+	p.retrier.Succeeded(lrpContainer.Guid)
 	p.containerDelegate.DeleteContainer(logger, lrpContainer.Container.Guid)
 }
 
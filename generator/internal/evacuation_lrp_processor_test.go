@@ -33,6 +33,8 @@ var _ = Describe("EvacuationLrpProcessor", func() {
 			fakeContainerDelegate  *fake_internal.FakeContainerDelegate
 			fakeEvacuationReporter *fake_evacuation_context.FakeEvacuationReporter
 			fakeMetronClient       *mfakes.FakeIngressClient
+			fakeRetrier            *fake_internal.FakeRetrier
+			fakeCrashDiagnostics   *fake_internal.FakeCrashDiagnostics
 
 			lrpProcessor internal.LRPProcessor
 
@@ -57,8 +59,10 @@ var _ = Describe("EvacuationLrpProcessor", func() {
 			fakeEvacuationReporter.EvacuatingReturns(true)
 
 			fakeMetronClient = new(mfakes.FakeIngressClient)
+			fakeRetrier = &fake_internal.FakeRetrier{}
+			fakeCrashDiagnostics = &fake_internal.FakeCrashDiagnostics{}
 
-			lrpProcessor = internal.NewLRPProcessor(fakeBBS, fakeContainerDelegate, fakeMetronClient, localCellID, fakeEvacuationReporter, evacuationTTL)
+			lrpProcessor = internal.NewLRPProcessor(fakeBBS, fakeContainerDelegate, fakeRetrier, fakeCrashDiagnostics, fakeMetronClient, localCellID, fakeEvacuationReporter, evacuationTTL)
 
 			processGuid = "process-guid"
 			desiredLRP = models.DesiredLRP{
@@ -142,6 +146,23 @@ var _ = Describe("EvacuationLrpProcessor", func() {
 					_, actualContainerGuid := fakeContainerDelegate.DeleteContainerArgsForCall(0)
 					Expect(actualContainerGuid).To(Equal(container.Guid))
 				})
+
+				It("reports the failure to the retrier", func() {
+					Expect(fakeRetrier.FailedCallCount()).To(Equal(1))
+					_, retriedContainer, err := fakeRetrier.FailedArgsForCall(0)
+					Expect(retriedContainer.Guid).To(Equal(container.Guid))
+					Expect(err).To(MatchError("whoops"))
+				})
+
+				Context("when a retry is scheduled", func() {
+					BeforeEach(func() {
+						fakeRetrier.FailedReturns(true)
+					})
+
+					It("keeps the container", func() {
+						Expect(fakeContainerDelegate.DeleteContainerCallCount()).To(Equal(0))
+					})
+				})
 			})
 		})
 
@@ -317,6 +338,10 @@ var _ = Describe("EvacuationLrpProcessor", func() {
 				It("does not delete the container", func() {
 					Expect(fakeContainerDelegate.DeleteContainerCallCount()).To(Equal(0))
 				})
+
+				It("reports the failure to the retrier", func() {
+					Expect(fakeRetrier.FailedCallCount()).To(Equal(1))
+				})
 			})
 		})
 
@@ -367,6 +392,16 @@ var _ = Describe("EvacuationLrpProcessor", func() {
 					_, actualContainerGuid := fakeContainerDelegate.DeleteContainerArgsForCall(0)
 					Expect(actualContainerGuid).To(Equal(container.Guid))
 				})
+
+				Context("when a retry is scheduled", func() {
+					BeforeEach(func() {
+						fakeRetrier.FailedReturns(true)
+					})
+
+					It("keeps the container", func() {
+						Expect(fakeContainerDelegate.DeleteContainerCallCount()).To(Equal(0))
+					})
+				})
 			})
 		})
 
@@ -385,6 +420,17 @@ var _ = Describe("EvacuationLrpProcessor", func() {
 				Expect(reason).To(Equal("crashed"))
 			})
 
+			Context("when crash diagnostics were collected", func() {
+				BeforeEach(func() {
+					fakeCrashDiagnostics.CollectReturns("/logs/crash.log: out of cheese")
+				})
+
+				It("attaches their summary to the crash reason", func() {
+					_, _, _, reason := fakeBBS.EvacuateCrashedActualLRPArgsForCall(0)
+					Expect(reason).To(Equal("crashed\n/logs/crash.log: out of cheese"))
+				})
+			})
+
 			Context("when the evacuation returns successfully", func() {
 				BeforeEach(func() {
 					fakeBBS.EvacuateCrashedActualLRPReturns(false, nil)
@@ -395,6 +441,12 @@ var _ = Describe("EvacuationLrpProcessor", func() {
 					_, actualContainerGuid := fakeContainerDelegate.DeleteContainerArgsForCall(0)
 					Expect(actualContainerGuid).To(Equal(container.Guid))
 				})
+
+				It("streams and forgets the crash diagnostics", func() {
+					Expect(fakeCrashDiagnostics.StreamCallCount()).To(Equal(1))
+					Expect(fakeCrashDiagnostics.ForgetCallCount()).To(Equal(1))
+					Expect(fakeCrashDiagnostics.ForgetArgsForCall(0)).To(Equal(container.Guid))
+				})
 			})
 
 			Context("when the evacuation returns that it failed to remove the LRP", func() {
@@ -414,6 +466,21 @@ var _ = Describe("EvacuationLrpProcessor", func() {
 					fakeBBS.EvacuateCrashedActualLRPReturns(false, errors.New("whoops"))
 				})
 
+				It("does not stream the crash diagnostics", func() {
+					Expect(fakeCrashDiagnostics.StreamCallCount()).To(Equal(0))
+				})
+
+				Context("and a retry is scheduled", func() {
+					BeforeEach(func() {
+						fakeRetrier.FailedReturns(true)
+					})
+
+					It("keeps the crash diagnostics for the retry", func() {
+						Expect(fakeCrashDiagnostics.ForgetCallCount()).To(Equal(0))
+						Expect(fakeContainerDelegate.DeleteContainerCallCount()).To(Equal(0))
+					})
+				})
+
 				It("deletes the container", func() {
 					Expect(fakeContainerDelegate.DeleteContainerCallCount()).To(Equal(1))
 					_, actualContainerGuid := fakeContainerDelegate.DeleteContainerArgsForCall(0)
@@ -36,6 +36,18 @@ type FakeContainerDelegate struct {
 	runContainerReturnsOnCall map[int]struct {
 		result1 bool
 	}
+	AllocateContainerStub        func(logger lager.Logger, req *executor.AllocationRequest) bool
+	allocateContainerMutex       sync.RWMutex
+	allocateContainerArgsForCall []struct {
+		logger lager.Logger
+		req    *executor.AllocationRequest
+	}
+	allocateContainerReturns struct {
+		result1 bool
+	}
+	allocateContainerReturnsOnCall map[int]struct {
+		result1 bool
+	}
 	StopContainerStub        func(logger lager.Logger, guid string) bool
 	stopContainerMutex       sync.RWMutex
 	stopContainerArgsForCall []struct {
@@ -152,6 +164,8 @@ func (fake *FakeContainerDelegate) RunContainer(logger lager.Logger, req *execut
 func (fake *FakeContainerDelegate) RunContainerCallCount() int {
 	fake.runContainerMutex.RLock()
 	defer fake.runContainerMutex.RUnlock()
+	fake.allocateContainerMutex.RLock()
+	defer fake.allocateContainerMutex.RUnlock()
 	return len(fake.runContainerArgsForCall)
 }
 
@@ -180,6 +194,55 @@ func (fake *FakeContainerDelegate) RunContainerReturnsOnCall(i int, result1 bool
 	}{result1}
 }
 
+func (fake *FakeContainerDelegate) AllocateContainer(logger lager.Logger, req *executor.AllocationRequest) bool {
+	fake.allocateContainerMutex.Lock()
+	ret, specificReturn := fake.allocateContainerReturnsOnCall[len(fake.allocateContainerArgsForCall)]
+	fake.allocateContainerArgsForCall = append(fake.allocateContainerArgsForCall, struct {
+		logger lager.Logger
+		req    *executor.AllocationRequest
+	}{logger, req})
+	fake.recordInvocation("AllocateContainer", []interface{}{logger, req})
+	fake.allocateContainerMutex.Unlock()
+	if fake.AllocateContainerStub != nil {
+		return fake.AllocateContainerStub(logger, req)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.allocateContainerReturns.result1
+}
+
+func (fake *FakeContainerDelegate) AllocateContainerCallCount() int {
+	fake.allocateContainerMutex.RLock()
+	defer fake.allocateContainerMutex.RUnlock()
+	return len(fake.allocateContainerArgsForCall)
+}
+
+func (fake *FakeContainerDelegate) AllocateContainerArgsForCall(i int) (lager.Logger, *executor.AllocationRequest) {
+	fake.allocateContainerMutex.RLock()
+	defer fake.allocateContainerMutex.RUnlock()
+	return fake.allocateContainerArgsForCall[i].logger, fake.allocateContainerArgsForCall[i].req
+}
+
+func (fake *FakeContainerDelegate) AllocateContainerReturns(result1 bool) {
+	fake.AllocateContainerStub = nil
+	fake.allocateContainerReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeContainerDelegate) AllocateContainerReturnsOnCall(i int, result1 bool) {
+	fake.AllocateContainerStub = nil
+	if fake.allocateContainerReturnsOnCall == nil {
+		fake.allocateContainerReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.allocateContainerReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
 func (fake *FakeContainerDelegate) StopContainer(logger lager.Logger, guid string) bool {
 	fake.stopContainerMutex.Lock()
 	ret, specificReturn := fake.stopContainerReturnsOnCall[len(fake.stopContainerArgsForCall)]
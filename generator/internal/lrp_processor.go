@@ -38,13 +38,15 @@ type lrpProcessor struct {
 func NewLRPProcessor(
 	bbsClient bbs.InternalClient,
 	containerDelegate ContainerDelegate,
+	retrier Retrier,
+	crashDiagnostics CrashDiagnostics,
 	metronClient loggingclient.IngressClient,
 	cellID string,
 	evacuationReporter evacuation_context.EvacuationReporter,
 	evacuationTTLInSeconds uint64,
 ) LRPProcessor {
-	ordinaryProcessor := newOrdinaryLRPProcessor(bbsClient, containerDelegate, cellID)
-	evacuationProcessor := newEvacuationLRPProcessor(bbsClient, containerDelegate, metronClient, cellID, evacuationTTLInSeconds)
+	ordinaryProcessor := newOrdinaryLRPProcessor(bbsClient, containerDelegate, retrier, crashDiagnostics, cellID)
+	evacuationProcessor := newEvacuationLRPProcessor(bbsClient, containerDelegate, retrier, crashDiagnostics, metronClient, cellID, evacuationTTLInSeconds)
 	return &lrpProcessor{
 		evacuationReporter:  evacuationReporter,
 		ordinaryProcessor:   ordinaryProcessor,
@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/rep/history"
+)
+
+// BBSCallObserver is told about every request made through an observed BBS
+// client, with the logger the request was made with.
+type BBSCallObserver func(logger lager.Logger, name string, duration time.Duration, err error)
+
+type observedBBSClient struct {
+	bbs.InternalClient
+	clock     clock.Clock
+	observers []BBSCallObserver
+}
+
+// NewObservedBBSClient wraps the client so that the requests the processors
+// and operations make are reported to the observers. The wrapped client is
+// handed the logger without its history recording, so that its own sessions
+// are not recorded in the operation's path.
+func NewObservedBBSClient(client bbs.InternalClient, clock clock.Clock, observers ...BBSCallObserver) bbs.InternalClient {
+	return &observedBBSClient{
+		InternalClient: client,
+		clock:          clock,
+		observers:      observers,
+	}
+}
+
+// begin starts timing a request. The returned func reports the request to
+// the observers once it returns.
+func (c *observedBBSClient) begin(logger lager.Logger, name string) func(error) {
+	started := c.clock.Now()
+	return func(err error) {
+		duration := c.clock.Since(started)
+		for _, observe := range c.observers {
+			observe(logger, name, duration, err)
+		}
+	}
+}
+
+func (c *observedBBSClient) DesiredLRPByProcessGuid(logger lager.Logger, processGuid string) (*models.DesiredLRP, error) {
+	finish := c.begin(logger, "DesiredLRPByProcessGuid")
+	desired, err := c.InternalClient.DesiredLRPByProcessGuid(history.Unwrap(logger), processGuid)
+	finish(err)
+	return desired, err
+}
+
+func (c *observedBBSClient) ClaimActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey) error {
+	finish := c.begin(logger, "ClaimActualLRP")
+	err := c.InternalClient.ClaimActualLRP(history.Unwrap(logger), key, instanceKey)
+	finish(err)
+	return err
+}
+
+func (c *observedBBSClient) StartActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey, netInfo *models.ActualLRPNetInfo) error {
+	finish := c.begin(logger, "StartActualLRP")
+	err := c.InternalClient.StartActualLRP(history.Unwrap(logger), key, instanceKey, netInfo)
+	finish(err)
+	return err
+}
+
+func (c *observedBBSClient) CrashActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey, errorMessage string) error {
+	finish := c.begin(logger, "CrashActualLRP")
+	err := c.InternalClient.CrashActualLRP(history.Unwrap(logger), key, instanceKey, errorMessage)
+	finish(err)
+	return err
+}
+
+func (c *observedBBSClient) RemoveActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey) error {
+	finish := c.begin(logger, "RemoveActualLRP")
+	err := c.InternalClient.RemoveActualLRP(history.Unwrap(logger), key, instanceKey)
+	finish(err)
+	return err
+}
+
+func (c *observedBBSClient) EvacuateClaimedActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey) (bool, error) {
+	finish := c.begin(logger, "EvacuateClaimedActualLRP")
+	keepContainer, err := c.InternalClient.EvacuateClaimedActualLRP(history.Unwrap(logger), key, instanceKey)
+	finish(err)
+	return keepContainer, err
+}
+
+func (c *observedBBSClient) EvacuateRunningActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey, netInfo *models.ActualLRPNetInfo, ttl uint64) (bool, error) {
+	finish := c.begin(logger, "EvacuateRunningActualLRP")
+	keepContainer, err := c.InternalClient.EvacuateRunningActualLRP(history.Unwrap(logger), key, instanceKey, netInfo, ttl)
+	finish(err)
+	return keepContainer, err
+}
+
+func (c *observedBBSClient) EvacuateStoppedActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey) (bool, error) {
+	finish := c.begin(logger, "EvacuateStoppedActualLRP")
+	keepContainer, err := c.InternalClient.EvacuateStoppedActualLRP(history.Unwrap(logger), key, instanceKey)
+	finish(err)
+	return keepContainer, err
+}
+
+func (c *observedBBSClient) EvacuateCrashedActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey, errorMessage string) (bool, error) {
+	finish := c.begin(logger, "EvacuateCrashedActualLRP")
+	keepContainer, err := c.InternalClient.EvacuateCrashedActualLRP(history.Unwrap(logger), key, instanceKey, errorMessage)
+	finish(err)
+	return keepContainer, err
+}
+
+func (c *observedBBSClient) RemoveEvacuatingActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey) error {
+	finish := c.begin(logger, "RemoveEvacuatingActualLRP")
+	err := c.InternalClient.RemoveEvacuatingActualLRP(history.Unwrap(logger), key, instanceKey)
+	finish(err)
+	return err
+}
+
+func (c *observedBBSClient) StartTask(logger lager.Logger, taskGuid, cellID string) (bool, error) {
+	finish := c.begin(logger, "StartTask")
+	changed, err := c.InternalClient.StartTask(history.Unwrap(logger), taskGuid, cellID)
+	finish(err)
+	return changed, err
+}
+
+func (c *observedBBSClient) TaskByGuid(logger lager.Logger, taskGuid string) (*models.Task, error) {
+	finish := c.begin(logger, "TaskByGuid")
+	task, err := c.InternalClient.TaskByGuid(history.Unwrap(logger), taskGuid)
+	finish(err)
+	return task, err
+}
+
+func (c *observedBBSClient) CompleteTask(logger lager.Logger, taskGuid, cellID string, failed bool, failureReason, result string) error {
+	finish := c.begin(logger, "CompleteTask")
+	err := c.InternalClient.CompleteTask(history.Unwrap(logger), taskGuid, cellID, failed, failureReason, result)
+	finish(err)
+	return err
+}
+
+func (c *observedBBSClient) RejectTask(logger lager.Logger, taskGuid, failureReason string) error {
+	finish := c.begin(logger, "RejectTask")
+	err := c.InternalClient.RejectTask(history.Unwrap(logger), taskGuid, failureReason)
+	finish(err)
+	return err
+}
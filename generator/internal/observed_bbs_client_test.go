@@ -0,0 +1,97 @@
+package internal_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/fake_bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/rep/generator/internal"
+	"code.cloudfoundry.org/rep/history"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ObservedBBSClient", func() {
+	type observedCall struct {
+		logger   lager.Logger
+		name     string
+		duration time.Duration
+		err      error
+	}
+
+	var (
+		logger    *lagertest.TestLogger
+		fakeClock *fakeclock.FakeClock
+		fakeBBS   *fake_bbs.FakeInternalClient
+		calls     []observedCall
+		client    bbs.InternalClient
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeBBS = new(fake_bbs.FakeInternalClient)
+		calls = nil
+
+		observer := func(logger lager.Logger, name string, duration time.Duration, err error) {
+			calls = append(calls, observedCall{logger, name, duration, err})
+		}
+		client = internal.NewObservedBBSClient(fakeBBS, fakeClock, observer)
+	})
+
+	It("reports requests and their results to the observers", func() {
+		fakeBBS.StartTaskStub = func(lager.Logger, string, string) (bool, error) {
+			fakeClock.Increment(time.Second)
+			return false, models.ErrResourceNotFound
+		}
+
+		changed, err := client.StartTask(logger, "task-guid", "cell-id")
+		Expect(changed).To(BeFalse())
+		Expect(err).To(Equal(models.ErrResourceNotFound))
+
+		Expect(fakeBBS.StartTaskCallCount()).To(Equal(1))
+		Expect(calls).To(Equal([]observedCall{
+			{logger, "StartTask", time.Second, models.ErrResourceNotFound},
+		}))
+	})
+
+	It("reports successful requests", func() {
+		err := client.ClaimActualLRP(logger, &models.ActualLRPKey{}, &models.ActualLRPInstanceKey{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(calls).To(HaveLen(1))
+		Expect(calls[0].name).To(Equal("ClaimActualLRP"))
+		Expect(calls[0].err).NotTo(HaveOccurred())
+	})
+
+	It("hands the wrapped client a logger that is not recorded in the operation's path", func() {
+		fakeBBS.ClaimActualLRPStub = func(logger lager.Logger, _ *models.ActualLRPKey, _ *models.ActualLRPInstanceKey) error {
+			logger.Session("do-request")
+			return nil
+		}
+
+		operationHistory := history.New(1, fakeClock)
+		opLogger, recording := operationHistory.Start(logger, "container", "some-guid")
+		opLogger = opLogger.Session("lrp-processor")
+		err := client.ClaimActualLRP(opLogger, &models.ActualLRPKey{}, &models.ActualLRPInstanceKey{})
+		Expect(err).NotTo(HaveOccurred())
+		recording.Finish()
+
+		Expect(operationHistory.All()[0].Path).To(Equal([]string{"lrp-processor"}))
+		Expect(calls[0].logger).To(Equal(opLogger))
+	})
+
+	It("passes requests it does not observe through", func() {
+		fakeBBS.TasksByCellIDReturns(nil, errors.New("boom"))
+
+		_, err := client.TasksByCellID(logger, "cell-id")
+		Expect(err).To(MatchError("boom"))
+		Expect(calls).To(BeEmpty())
+	})
+})
@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"fmt"
+
 	"code.cloudfoundry.org/bbs"
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/executor"
@@ -11,17 +13,23 @@ import (
 type ordinaryLRPProcessor struct {
 	bbsClient         bbs.InternalClient
 	containerDelegate ContainerDelegate
+	retrier           Retrier
+	crashDiagnostics  CrashDiagnostics
 	cellID            string
 }
 
 func newOrdinaryLRPProcessor(
 	bbsClient bbs.InternalClient,
 	containerDelegate ContainerDelegate,
+	retrier Retrier,
+	crashDiagnostics CrashDiagnostics,
 	cellID string,
 ) LRPProcessor {
 	return &ordinaryLRPProcessor{
 		bbsClient:         bbsClient,
 		containerDelegate: containerDelegate,
+		retrier:           retrier,
+		crashDiagnostics:  crashDiagnostics,
 		cellID:            cellID,
 	}
 }
@@ -76,6 +84,7 @@ func (p *ordinaryLRPProcessor) processReservedContainer(logger lager.Logger, lrp
 	desired, err := p.bbsClient.DesiredLRPByProcessGuid(logger, lrpContainer.ProcessGuid)
 	if err != nil {
 		logger.Error("failed-to-fetch-desired", err)
+		p.retrier.Failed(logger, lrpContainer.Container, err)
 		return
 	}
 
@@ -89,16 +98,21 @@ func (p *ordinaryLRPProcessor) processReservedContainer(logger lager.Logger, lrp
 		p.bbsClient.RemoveActualLRP(logger, lrpContainer.ActualLRPKey, lrpContainer.ActualLRPInstanceKey)
 		return
 	}
+	p.retrier.Succeeded(lrpContainer.Guid)
 }
 
 func (p *ordinaryLRPProcessor) processInitializingContainer(logger lager.Logger, lrpContainer *lrpContainer) {
 	logger = logger.Session("process-initializing-container")
-	p.claimLRPContainer(logger, lrpContainer)
+	if p.claimLRPContainer(logger, lrpContainer) {
+		p.retrier.Succeeded(lrpContainer.Guid)
+	}
 }
 
 func (p *ordinaryLRPProcessor) processCreatedContainer(logger lager.Logger, lrpContainer *lrpContainer) {
 	logger = logger.Session("process-created-container")
-	p.claimLRPContainer(logger, lrpContainer)
+	if p.claimLRPContainer(logger, lrpContainer) {
+		p.retrier.Succeeded(lrpContainer.Guid)
+	}
 }
 
 func (p *ordinaryLRPProcessor) processRunningContainer(logger lager.Logger, lrpContainer *lrpContainer) {
@@ -115,26 +129,45 @@ func (p *ordinaryLRPProcessor) processRunningContainer(logger lager.Logger, lrpC
 	logger.Info("bbs-start-actual-lrp", lager.Data{"net_info": netInfo})
 	err = p.bbsClient.StartActualLRP(logger, lrpContainer.ActualLRPKey, lrpContainer.ActualLRPInstanceKey, netInfo)
 	bbsErr := models.ConvertError(err)
-	if bbsErr != nil && bbsErr.Type == models.Error_ActualLRPCannotBeStarted {
+	switch {
+	case bbsErr == nil:
+		p.retrier.Succeeded(lrpContainer.Guid)
+	case bbsErr.Type == models.Error_ActualLRPCannotBeStarted:
 		p.containerDelegate.StopContainer(logger, lrpContainer.Guid)
+	default:
+		p.retrier.Failed(logger, lrpContainer.Container, err)
 	}
 }
 
 func (p *ordinaryLRPProcessor) processCompletedContainer(logger lager.Logger, lrpContainer *lrpContainer) {
 	logger = logger.Session("process-completed-container")
 
+	var err error
 	if lrpContainer.RunResult.Stopped {
-		err := p.bbsClient.RemoveActualLRP(logger, lrpContainer.ActualLRPKey, lrpContainer.ActualLRPInstanceKey)
+		err = p.bbsClient.RemoveActualLRP(logger, lrpContainer.ActualLRPKey, lrpContainer.ActualLRPInstanceKey)
 		if err != nil {
 			logger.Info("failed-to-remove-actual-lrp", lager.Data{"error": err})
 		}
 	} else {
-		err := p.bbsClient.CrashActualLRP(logger, lrpContainer.ActualLRPKey, lrpContainer.ActualLRPInstanceKey, lrpContainer.RunResult.FailureReason)
+		reason := lrpContainer.RunResult.FailureReason
+		if summary := p.crashDiagnostics.Collect(logger, lrpContainer.Container); summary != "" {
+			reason = fmt.Sprintf("%s\n%s", reason, summary)
+		}
+
+		err = p.bbsClient.CrashActualLRP(logger, lrpContainer.ActualLRPKey, lrpContainer.ActualLRPInstanceKey, reason)
 		if err != nil {
 			logger.Info("failed-to-crash-actual-lrp", lager.Data{"error": err})
+		} else {
+			p.crashDiagnostics.Stream(logger, lrpContainer.Container)
 		}
 	}
 
+	if err != nil && p.retrier.Failed(logger, lrpContainer.Container, err) {
+		return
+	}
+
+	p.crashDiagnostics.Forget(lrpContainer.Guid)
+	p.retrier.Succeeded(lrpContainer.Guid)
 	p.containerDelegate.DeleteContainer(logger, lrpContainer.Guid)
 }
 
@@ -149,6 +182,8 @@ func (p *ordinaryLRPProcessor) claimLRPContainer(logger lager.Logger, lrpContain
 	if err != nil {
 		if bbsErr.Type == models.Error_ActualLRPCannotBeClaimed {
 			p.containerDelegate.DeleteContainer(logger, lrpContainer.Guid)
+		} else {
+			p.retrier.Failed(logger, lrpContainer.Container, err)
 		}
 		return false
 	}
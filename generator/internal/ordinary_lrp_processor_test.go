@@ -29,6 +29,8 @@ var _ = Describe("OrdinaryLRPProcessor", func() {
 		bbsClient          *fake_bbs.FakeInternalClient
 		containerDelegate  *fake_internal.FakeContainerDelegate
 		evacuationReporter *fake_evacuation_context.FakeEvacuationReporter
+		retrier            *fake_internal.FakeRetrier
+		crashDiagnostics   *fake_internal.FakeCrashDiagnostics
 	)
 
 	BeforeEach(func() {
@@ -36,7 +38,9 @@ var _ = Describe("OrdinaryLRPProcessor", func() {
 		containerDelegate = new(fake_internal.FakeContainerDelegate)
 		evacuationReporter = &fake_evacuation_context.FakeEvacuationReporter{}
 		evacuationReporter.EvacuatingReturns(false)
-		processor = internal.NewLRPProcessor(bbsClient, containerDelegate, nil, expectedCellID, evacuationReporter, 124)
+		retrier = new(fake_internal.FakeRetrier)
+		crashDiagnostics = new(fake_internal.FakeCrashDiagnostics)
+		processor = internal.NewLRPProcessor(bbsClient, containerDelegate, retrier, crashDiagnostics, nil, expectedCellID, evacuationReporter, 124)
 		logger = lagertest.NewTestLogger("test")
 	})
 
@@ -127,6 +131,13 @@ var _ = Describe("OrdinaryLRPProcessor", func() {
 					It("does not try to run the container", func() {
 						Expect(containerDelegate.RunContainerCallCount()).To(Equal(0))
 					})
+
+					It("reports the failure to the retrier", func() {
+						Expect(retrier.FailedCallCount()).To(Equal(1))
+						_, retriedContainer, err := retrier.FailedArgsForCall(0)
+						Expect(retriedContainer.Guid).To(Equal(container.Guid))
+						Expect(err).To(MatchError("boom"))
+					})
 				})
 
 				Context("when claiming succeeds", func() {
@@ -256,6 +267,18 @@ var _ = Describe("OrdinaryLRPProcessor", func() {
 							Expect(containerDelegate.StopContainerCallCount()).To(Equal(0))
 							Expect(containerDelegate.DeleteContainerCallCount()).To(Equal(0))
 						})
+
+						It("reports the failure to the retrier", func() {
+							Expect(retrier.FailedCallCount()).To(Equal(1))
+							Expect(retrier.SucceededCallCount()).To(Equal(0))
+						})
+					})
+
+					Context("when starting succeeds", func() {
+						It("resets the retries of the container", func() {
+							Expect(retrier.SucceededCallCount()).To(Equal(1))
+							Expect(retrier.SucceededArgsForCall(0)).To(Equal(container.Guid))
+						})
 					})
 				})
 
@@ -298,6 +321,17 @@ var _ = Describe("OrdinaryLRPProcessor", func() {
 								Expect(containerGuid).To(Equal(container.Guid))
 								Expect(delegateLogger.SessionName()).To(Equal(expectedSessionName))
 							})
+
+							Context("and a retry is scheduled", func() {
+								BeforeEach(func() {
+									retrier.FailedReturns(true)
+								})
+
+								It("keeps the container for the retry", func() {
+									Expect(retrier.FailedCallCount()).To(Equal(1))
+									Expect(containerDelegate.DeleteContainerCallCount()).To(Equal(0))
+								})
+							})
 						})
 					})
 
@@ -315,6 +349,58 @@ var _ = Describe("OrdinaryLRPProcessor", func() {
 							Expect(reason).To(Equal("crashed"))
 						})
 
+						It("collects crash diagnostics before deleting the container", func() {
+							Expect(crashDiagnostics.CollectCallCount()).To(Equal(1))
+							_, diagnosedContainer := crashDiagnostics.CollectArgsForCall(0)
+							Expect(diagnosedContainer.Guid).To(Equal(container.Guid))
+						})
+
+						It("streams the crash diagnostics once the actual LRP has crashed, then forgets them", func() {
+							Expect(crashDiagnostics.StreamCallCount()).To(Equal(1))
+							_, streamedContainer := crashDiagnostics.StreamArgsForCall(0)
+							Expect(streamedContainer.Guid).To(Equal(container.Guid))
+							Expect(crashDiagnostics.ForgetCallCount()).To(Equal(1))
+							Expect(crashDiagnostics.ForgetArgsForCall(0)).To(Equal(container.Guid))
+						})
+
+						Context("when crash diagnostics were collected", func() {
+							BeforeEach(func() {
+								crashDiagnostics.CollectReturns("/logs/crash.log: out of cheese")
+							})
+
+							It("attaches their summary to the crash reason", func() {
+								_, _, _, reason := bbsClient.CrashActualLRPArgsForCall(0)
+								Expect(reason).To(Equal("crashed\n/logs/crash.log: out of cheese"))
+							})
+						})
+
+						Context("when crashing the actual LRP fails", func() {
+							BeforeEach(func() {
+								bbsClient.CrashActualLRPReturns(errors.New("whoops"))
+							})
+
+							It("does not stream the crash diagnostics", func() {
+								Expect(crashDiagnostics.StreamCallCount()).To(Equal(0))
+							})
+
+							Context("and a retry is scheduled", func() {
+								BeforeEach(func() {
+									retrier.FailedReturns(true)
+								})
+
+								It("keeps the crash diagnostics for the retry", func() {
+									Expect(crashDiagnostics.ForgetCallCount()).To(Equal(0))
+									Expect(containerDelegate.DeleteContainerCallCount()).To(Equal(0))
+								})
+							})
+
+							Context("and no retry is scheduled", func() {
+								It("forgets the crash diagnostics", func() {
+									Expect(crashDiagnostics.ForgetCallCount()).To(Equal(1))
+								})
+							})
+						})
+
 						It("deletes the container", func() {
 							Expect(containerDelegate.DeleteContainerCallCount()).To(Equal(1))
 							delegateLogger, containerGuid := containerDelegate.DeleteContainerArgsForCall(0)
@@ -0,0 +1,197 @@
+package internal
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock"
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/rep/ratelimit"
+)
+
+const (
+	bbsRetriesScheduledCounter = "RepBBSRetriesScheduled"
+	bbsRetriesExhaustedCounter = "RepBBSRetriesExhausted"
+	bbsTerminalErrorsCounter   = "RepBBSTerminalErrors"
+)
+
+// RetryPolicy bounds how often a container whose BBS call failed with a
+// transient error is processed again. The backoff doubles with every attempt
+// up to MaxBackoff, and a random jitter of up to half the backoff is
+// subtracted so that retries for many containers do not line up.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff
+}
+
+// IsTransientBBSError is true for errors that may succeed when retried, such
+// as failures to reach the BBS. Errors describing the state of a record, like
+// invalid state transitions or missing records, are terminal.
+func IsTransientBBSError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	switch models.ConvertError(err).Type {
+	case models.Error_UnknownError,
+		models.Error_InvalidResponse,
+		models.Error_FailedToOpenEnvelope,
+		models.Error_Deadlock:
+		return true
+	default:
+		return false
+	}
+}
+
+//go:generate counterfeiter -o fake_internal/fake_retrier.go retrier.go Retrier
+
+type Retrier interface {
+	// Failed records a failed BBS call made while processing the container.
+	// Transient failures get the container processed again after a backoff.
+	// Requests canceled by the rate limiter while the rep shuts down are not
+	// retried. It returns whether the caller should leave the container in
+	// place, because a retry was scheduled or the request was canceled.
+	Failed(logger lager.Logger, container executor.Container, err error) bool
+
+	// Succeeded resets the backoff of the container.
+	Succeeded(guid string)
+
+	// Prune forgets the backoff of containers missing from containers, such
+	// as containers deleted outside of the rep.
+	Prune(containers map[string]executor.Container)
+}
+
+type retrier struct {
+	clock        clock.Clock
+	policy       RetryPolicy
+	metronClient loggingclient.IngressClient
+	retry        func(lager.Logger, executor.Container)
+
+	lock      sync.Mutex
+	attempts  map[string]int
+	scheduled map[string]struct{}
+	random    *rand.Rand
+}
+
+// NewRetrier returns a Retrier calling retry once a container's backoff has
+// elapsed. A policy without attempts never schedules retries.
+func NewRetrier(
+	clock clock.Clock,
+	policy RetryPolicy,
+	metronClient loggingclient.IngressClient,
+	retry func(lager.Logger, executor.Container),
+) Retrier {
+	return &retrier{
+		clock:        clock,
+		policy:       policy,
+		metronClient: metronClient,
+		retry:        retry,
+		attempts:     map[string]int{},
+		scheduled:    map[string]struct{}{},
+		random:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (r *retrier) Failed(logger lager.Logger, container executor.Container, err error) bool {
+	if err == ratelimit.ErrCanceled {
+		logger.Session("retrier").Info("not-retrying-canceled-request")
+		return true
+	}
+
+	if r.policy.MaxAttempts <= 0 {
+		return false
+	}
+
+	logger = logger.Session("retrier")
+
+	if !IsTransientBBSError(err) {
+		logger.Info("not-retrying-terminal-error", lager.Data{"error": err.Error()})
+		r.increment(logger, bbsTerminalErrorsCounter)
+		r.Succeeded(container.Guid)
+		return false
+	}
+
+	r.lock.Lock()
+	if _, ok := r.scheduled[container.Guid]; ok {
+		r.lock.Unlock()
+		logger.Debug("retry-already-scheduled")
+		return true
+	}
+
+	attempt := r.attempts[container.Guid] + 1
+	if attempt > r.policy.MaxAttempts {
+		delete(r.attempts, container.Guid)
+		r.lock.Unlock()
+		logger.Error("retries-exhausted", err, lager.Data{"attempts": attempt - 1})
+		r.increment(logger, bbsRetriesExhaustedCounter)
+		return false
+	}
+	r.attempts[container.Guid] = attempt
+	r.scheduled[container.Guid] = struct{}{}
+
+	backoff := r.policy.backoff(attempt)
+	if jitter := int64(backoff / 2); jitter > 0 {
+		backoff -= time.Duration(r.random.Int63n(jitter))
+	}
+	r.lock.Unlock()
+
+	logger.Info("scheduling-retry", lager.Data{"attempt": attempt, "backoff": backoff.String(), "error": err.Error()})
+	r.increment(logger, bbsRetriesScheduledCounter)
+
+	timer := r.clock.NewTimer(backoff)
+	go func() {
+		<-timer.C()
+
+		r.lock.Lock()
+		delete(r.scheduled, container.Guid)
+		r.lock.Unlock()
+
+		r.retry(logger, container)
+	}()
+
+	return true
+}
+
+func (r *retrier) Succeeded(guid string) {
+	r.lock.Lock()
+	delete(r.attempts, guid)
+	r.lock.Unlock()
+}
+
+func (r *retrier) Prune(containers map[string]executor.Container) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for guid := range r.attempts {
+		if _, found := containers[guid]; !found {
+			delete(r.attempts, guid)
+		}
+	}
+}
+
+func (r *retrier) increment(logger lager.Logger, counter string) {
+	if r.metronClient == nil {
+		return
+	}
+
+	err := r.metronClient.IncrementCounter(counter)
+	if err != nil {
+		logger.Error("failed-to-increment-counter", err, lager.Data{"counter": counter})
+	}
+}
@@ -0,0 +1,199 @@
+package internal_test
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/rep/generator/internal"
+	"code.cloudfoundry.org/rep/ratelimit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Retrier", func() {
+	var (
+		logger           *lagertest.TestLogger
+		fakeClock        *fakeclock.FakeClock
+		fakeMetronClient *mfakes.FakeIngressClient
+		policy           internal.RetryPolicy
+		retrier          internal.Retrier
+		container        executor.Container
+
+		retriedLock sync.Mutex
+		retried     []executor.Container
+	)
+
+	retriedCount := func() int {
+		retriedLock.Lock()
+		defer retriedLock.Unlock()
+		return len(retried)
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeMetronClient = new(mfakes.FakeIngressClient)
+		policy = internal.RetryPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Second,
+			MaxBackoff:     10 * time.Second,
+		}
+		container = executor.Container{Guid: "some-guid"}
+
+		retriedLock.Lock()
+		retried = nil
+		retriedLock.Unlock()
+	})
+
+	JustBeforeEach(func() {
+		retrier = internal.NewRetrier(fakeClock, policy, fakeMetronClient, func(_ lager.Logger, c executor.Container) {
+			retriedLock.Lock()
+			retried = append(retried, c)
+			retriedLock.Unlock()
+		})
+	})
+
+	Context("when the error is transient", func() {
+		var err error
+
+		BeforeEach(func() {
+			err = errors.New("connection refused")
+		})
+
+		It("retries the container after the backoff", func() {
+			Expect(retrier.Failed(logger, container, err)).To(BeTrue())
+
+			Eventually(fakeClock.WatcherCount).Should(Equal(1))
+			fakeClock.WaitForWatcherAndIncrement(time.Second / 2)
+			Consistently(retriedCount).Should(Equal(0))
+
+			fakeClock.Increment(time.Second / 2)
+			Eventually(retriedCount).Should(Equal(1))
+		})
+
+		It("counts the scheduled retry", func() {
+			retrier.Failed(logger, container, err)
+
+			Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(1))
+			Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("RepBBSRetriesScheduled"))
+		})
+
+		It("does not schedule a second retry while one is pending", func() {
+			Expect(retrier.Failed(logger, container, err)).To(BeTrue())
+			Expect(retrier.Failed(logger, container, err)).To(BeTrue())
+
+			fakeClock.WaitForWatcherAndIncrement(policy.MaxBackoff)
+			Eventually(retriedCount).Should(Equal(1))
+			Consistently(retriedCount).Should(Equal(1))
+		})
+
+		It("doubles the backoff for every attempt", func() {
+			retrier.Failed(logger, container, err)
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+			Eventually(retriedCount).Should(Equal(1))
+
+			retrier.Failed(logger, container, err)
+			fakeClock.WaitForWatcherAndIncrement(time.Second - time.Millisecond)
+			Consistently(retriedCount).Should(Equal(1))
+
+			fakeClock.Increment(policy.MaxBackoff)
+			Eventually(retriedCount).Should(Equal(2))
+		})
+
+		Context("when the attempts are exhausted", func() {
+			JustBeforeEach(func() {
+				for i := 0; i < policy.MaxAttempts; i++ {
+					Expect(retrier.Failed(logger, container, err)).To(BeTrue())
+					fakeClock.WaitForWatcherAndIncrement(policy.MaxBackoff)
+					Eventually(retriedCount).Should(Equal(i + 1))
+				}
+			})
+
+			It("gives up and counts the exhausted retries", func() {
+				Expect(retrier.Failed(logger, container, err)).To(BeFalse())
+				Expect(fakeMetronClient.IncrementCounterArgsForCall(policy.MaxAttempts)).To(Equal("RepBBSRetriesExhausted"))
+			})
+
+			It("starts over once the container succeeded", func() {
+				retrier.Succeeded(container.Guid)
+				Expect(retrier.Failed(logger, container, err)).To(BeTrue())
+			})
+
+			It("starts over once the container is pruned", func() {
+				retrier.Prune(map[string]executor.Container{})
+				Expect(retrier.Failed(logger, container, err)).To(BeTrue())
+			})
+
+			It("keeps the attempts of containers that still exist", func() {
+				retrier.Prune(map[string]executor.Container{container.Guid: container})
+				Expect(retrier.Failed(logger, container, err)).To(BeFalse())
+			})
+		})
+
+		Context("when retries are disabled", func() {
+			BeforeEach(func() {
+				policy.MaxAttempts = 0
+			})
+
+			It("does not schedule a retry", func() {
+				Expect(retrier.Failed(logger, container, err)).To(BeFalse())
+				Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Context("when the error is terminal", func() {
+		It("does not schedule a retry", func() {
+			Expect(retrier.Failed(logger, container, models.ErrResourceNotFound)).To(BeFalse())
+			Expect(fakeClock.WatcherCount()).To(Equal(0))
+		})
+
+		It("counts the terminal error", func() {
+			retrier.Failed(logger, container, models.ErrActualLRPCannotBeClaimed)
+
+			Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(1))
+			Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("RepBBSTerminalErrors"))
+		})
+	})
+
+	Context("when the request was canceled by the rate limiter", func() {
+		It("keeps the container without scheduling a retry", func() {
+			Expect(retrier.Failed(logger, container, ratelimit.ErrCanceled)).To(BeTrue())
+			Expect(fakeClock.WatcherCount()).To(Equal(0))
+			Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(0))
+		})
+
+		Context("when retries are disabled", func() {
+			BeforeEach(func() {
+				policy.MaxAttempts = 0
+			})
+
+			It("still keeps the container", func() {
+				Expect(retrier.Failed(logger, container, ratelimit.ErrCanceled)).To(BeTrue())
+			})
+		})
+	})
+})
+
+var _ = Describe("IsTransientBBSError", func() {
+	It("treats unknown and transport errors as transient", func() {
+		Expect(internal.IsTransientBBSError(errors.New("i/o timeout"))).To(BeTrue())
+		Expect(internal.IsTransientBBSError(models.ErrDeadlock)).To(BeTrue())
+		Expect(internal.IsTransientBBSError(models.ErrFailedToOpenEnvelope)).To(BeTrue())
+	})
+
+	It("treats record state errors as terminal", func() {
+		Expect(internal.IsTransientBBSError(nil)).To(BeFalse())
+		Expect(internal.IsTransientBBSError(models.ErrResourceNotFound)).To(BeFalse())
+		Expect(internal.IsTransientBBSError(models.ErrActualLRPCannotBeStarted)).To(BeFalse())
+		Expect(internal.IsTransientBBSError(models.NewTaskTransitionError(models.Task_Running, models.Task_Completed))).To(BeFalse())
+	})
+})
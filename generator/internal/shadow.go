@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/lager"
+)
+
+// ShadowObserver is told about every BBS request and container action that
+// was skipped in shadow mode, with the logger it would have been made with.
+type ShadowObserver func(logger lager.Logger, name string)
+
+func shadow(logger lager.Logger, message, name string, data lager.Data, observers []ShadowObserver) {
+	logData := lager.Data{"call": name}
+	for k, v := range data {
+		logData[k] = v
+	}
+	logger.Info(message, logData)
+
+	for _, observe := range observers {
+		observe(logger, name)
+	}
+}
+
+type shadowBBSClient struct {
+	bbs.InternalClient
+	observers []ShadowObserver
+}
+
+// NewShadowBBSClient wraps the client so that requests that would change the
+// BBS are only logged and reported to the observers. Reads are still made,
+// so the processors take the same decisions they would take for real.
+// Requests that are skipped succeed; evacuating a running LRP reports that
+// the container should be kept, as the BBS would once the evacuating
+// instance exists.
+func NewShadowBBSClient(client bbs.InternalClient, observers ...ShadowObserver) bbs.InternalClient {
+	return &shadowBBSClient{
+		InternalClient: client,
+		observers:      observers,
+	}
+}
+
+func (c *shadowBBSClient) skip(logger lager.Logger, name string, data lager.Data) {
+	shadow(logger, "shadowed-bbs-call", name, data, c.observers)
+}
+
+func (c *shadowBBSClient) ClaimActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey) error {
+	c.skip(logger, "ClaimActualLRP", lager.Data{"lrp-key": key, "lrp-instance-key": instanceKey})
+	return nil
+}
+
+func (c *shadowBBSClient) StartActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey, netInfo *models.ActualLRPNetInfo) error {
+	c.skip(logger, "StartActualLRP", lager.Data{"lrp-key": key, "lrp-instance-key": instanceKey, "net-info": netInfo})
+	return nil
+}
+
+func (c *shadowBBSClient) CrashActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey, errorMessage string) error {
+	c.skip(logger, "CrashActualLRP", lager.Data{"lrp-key": key, "lrp-instance-key": instanceKey, "error-message": errorMessage})
+	return nil
+}
+
+func (c *shadowBBSClient) RemoveActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey) error {
+	c.skip(logger, "RemoveActualLRP", lager.Data{"lrp-key": key, "lrp-instance-key": instanceKey})
+	return nil
+}
+
+func (c *shadowBBSClient) EvacuateClaimedActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey) (bool, error) {
+	c.skip(logger, "EvacuateClaimedActualLRP", lager.Data{"lrp-key": key, "lrp-instance-key": instanceKey})
+	return false, nil
+}
+
+func (c *shadowBBSClient) EvacuateRunningActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey, netInfo *models.ActualLRPNetInfo, ttl uint64) (bool, error) {
+	c.skip(logger, "EvacuateRunningActualLRP", lager.Data{"lrp-key": key, "lrp-instance-key": instanceKey, "net-info": netInfo, "ttl": ttl})
+	return true, nil
+}
+
+func (c *shadowBBSClient) EvacuateStoppedActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey) (bool, error) {
+	c.skip(logger, "EvacuateStoppedActualLRP", lager.Data{"lrp-key": key, "lrp-instance-key": instanceKey})
+	return false, nil
+}
+
+func (c *shadowBBSClient) EvacuateCrashedActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey, errorMessage string) (bool, error) {
+	c.skip(logger, "EvacuateCrashedActualLRP", lager.Data{"lrp-key": key, "lrp-instance-key": instanceKey, "error-message": errorMessage})
+	return false, nil
+}
+
+func (c *shadowBBSClient) RemoveEvacuatingActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey) error {
+	c.skip(logger, "RemoveEvacuatingActualLRP", lager.Data{"lrp-key": key, "lrp-instance-key": instanceKey})
+	return nil
+}
+
+func (c *shadowBBSClient) StartTask(logger lager.Logger, taskGuid, cellID string) (bool, error) {
+	c.skip(logger, "StartTask", lager.Data{"task-guid": taskGuid, "cell-id": cellID})
+	return true, nil
+}
+
+func (c *shadowBBSClient) CompleteTask(logger lager.Logger, taskGuid, cellID string, failed bool, failureReason, result string) error {
+	c.skip(logger, "CompleteTask", lager.Data{"task-guid": taskGuid, "cell-id": cellID, "failed": failed, "failure-reason": failureReason, "result-size": len(result)})
+	return nil
+}
+
+func (c *shadowBBSClient) RejectTask(logger lager.Logger, taskGuid, failureReason string) error {
+	c.skip(logger, "RejectTask", lager.Data{"task-guid": taskGuid, "failure-reason": failureReason})
+	return nil
+}
+
+type shadowContainerDelegate struct {
+	ContainerDelegate
+	observers []ShadowObserver
+}
+
+// NewShadowContainerDelegate wraps the delegate so that container actions
+// are only logged and reported to the observers, and report success.
+// Containers and their result files are still read.
+func NewShadowContainerDelegate(delegate ContainerDelegate, observers ...ShadowObserver) ContainerDelegate {
+	return &shadowContainerDelegate{
+		ContainerDelegate: delegate,
+		observers:         observers,
+	}
+}
+
+func (d *shadowContainerDelegate) skip(logger lager.Logger, name string, data lager.Data) {
+	shadow(logger, "shadowed-container-action", name, data, d.observers)
+}
+
+func (d *shadowContainerDelegate) RunContainer(logger lager.Logger, req *executor.RunRequest) bool {
+	d.skip(logger, "RunContainer", lager.Data{"container-guid": req.Guid})
+	return true
+}
+
+func (d *shadowContainerDelegate) AllocateContainer(logger lager.Logger, req *executor.AllocationRequest) bool {
+	d.skip(logger, "AllocateContainer", lager.Data{"container-guid": req.Guid})
+	return true
+}
+
+func (d *shadowContainerDelegate) StopContainer(logger lager.Logger, guid string) bool {
+	d.skip(logger, "StopContainer", lager.Data{"container-guid": guid})
+	return true
+}
+
+func (d *shadowContainerDelegate) DeleteContainer(logger lager.Logger, guid string) bool {
+	d.skip(logger, "DeleteContainer", lager.Data{"container-guid": guid})
+	return true
+}
+
+type shadowIngressClient struct {
+	loggingclient.IngressClient
+}
+
+// NewShadowIngressClient wraps the client so that messages for application
+// log streams are dropped. Metrics are still emitted.
+func NewShadowIngressClient(client loggingclient.IngressClient) loggingclient.IngressClient {
+	return &shadowIngressClient{IngressClient: client}
+}
+
+func (c *shadowIngressClient) SendAppLog(appID, message, sourceType, sourceInstance string) error {
+	return nil
+}
+
+func (c *shadowIngressClient) SendAppErrorLog(appID, message, sourceType, sourceInstance string) error {
+	return nil
+}
@@ -0,0 +1,120 @@
+package internal_test
+
+import (
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/fake_bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/rep/generator/internal"
+	"code.cloudfoundry.org/rep/generator/internal/fake_internal"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Shadow mode", func() {
+	var (
+		logger   *lagertest.TestLogger
+		shadowed []string
+		observer internal.ShadowObserver
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		shadowed = nil
+		observer = func(_ lager.Logger, name string) {
+			shadowed = append(shadowed, name)
+		}
+	})
+
+	Describe("ShadowBBSClient", func() {
+		var (
+			fakeBBS *fake_bbs.FakeInternalClient
+			client  bbs.InternalClient
+
+			lrpKey      models.ActualLRPKey
+			instanceKey models.ActualLRPInstanceKey
+		)
+
+		BeforeEach(func() {
+			fakeBBS = new(fake_bbs.FakeInternalClient)
+			client = internal.NewShadowBBSClient(fakeBBS, observer)
+
+			lrpKey = models.NewActualLRPKey("process-guid", 0, "domain")
+			instanceKey = models.NewActualLRPInstanceKey("instance-guid", "cell-id")
+		})
+
+		It("logs and reports writes without making them", func() {
+			Expect(client.ClaimActualLRP(logger, &lrpKey, &instanceKey)).To(Succeed())
+			Expect(client.RejectTask(logger, "task-guid", "no room")).To(Succeed())
+
+			Expect(fakeBBS.ClaimActualLRPCallCount()).To(BeZero())
+			Expect(fakeBBS.RejectTaskCallCount()).To(BeZero())
+			Expect(shadowed).To(Equal([]string{"ClaimActualLRP", "RejectTask"}))
+			Expect(logger).To(gbytes.Say("shadowed-bbs-call.*ClaimActualLRP"))
+			Expect(logger).To(gbytes.Say("shadowed-bbs-call.*RejectTask.*no room"))
+		})
+
+		It("reports that started tasks changed and that running LRPs keep their containers", func() {
+			changed, err := client.StartTask(logger, "task-guid", "cell-id")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).To(BeTrue())
+
+			keepContainer, err := client.EvacuateRunningActualLRP(logger, &lrpKey, &instanceKey, &models.ActualLRPNetInfo{}, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keepContainer).To(BeTrue())
+
+			keepContainer, err = client.EvacuateStoppedActualLRP(logger, &lrpKey, &instanceKey)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keepContainer).To(BeFalse())
+		})
+
+		It("still makes reads", func() {
+			fakeBBS.TaskByGuidReturns(&models.Task{TaskGuid: "task-guid"}, nil)
+
+			task, err := client.TaskByGuid(logger, "task-guid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(task.TaskGuid).To(Equal("task-guid"))
+			Expect(fakeBBS.TaskByGuidCallCount()).To(Equal(1))
+			Expect(shadowed).To(BeEmpty())
+		})
+	})
+
+	Describe("ShadowContainerDelegate", func() {
+		var (
+			fakeDelegate *fake_internal.FakeContainerDelegate
+			delegate     internal.ContainerDelegate
+		)
+
+		BeforeEach(func() {
+			fakeDelegate = new(fake_internal.FakeContainerDelegate)
+			delegate = internal.NewShadowContainerDelegate(fakeDelegate, observer)
+		})
+
+		It("logs and reports container actions without taking them", func() {
+			Expect(delegate.AllocateContainer(logger, &executor.AllocationRequest{Guid: "some-guid"})).To(BeTrue())
+			Expect(delegate.RunContainer(logger, &executor.RunRequest{Guid: "some-guid"})).To(BeTrue())
+			Expect(delegate.StopContainer(logger, "some-guid")).To(BeTrue())
+			Expect(delegate.DeleteContainer(logger, "some-guid")).To(BeTrue())
+
+			Expect(fakeDelegate.AllocateContainerCallCount()).To(BeZero())
+			Expect(fakeDelegate.RunContainerCallCount()).To(BeZero())
+			Expect(fakeDelegate.StopContainerCallCount()).To(BeZero())
+			Expect(fakeDelegate.DeleteContainerCallCount()).To(BeZero())
+			Expect(shadowed).To(Equal([]string{"AllocateContainer", "RunContainer", "StopContainer", "DeleteContainer"}))
+			Expect(logger).To(gbytes.Say("shadowed-container-action.*AllocateContainer.*some-guid"))
+		})
+
+		It("still reads containers", func() {
+			fakeDelegate.GetContainerReturns(executor.Container{Guid: "some-guid"}, true)
+
+			container, ok := delegate.GetContainer(logger, "some-guid")
+			Expect(ok).To(BeTrue())
+			Expect(container.Guid).To(Equal("some-guid"))
+			Expect(shadowed).To(BeEmpty())
+		})
+	})
+})
@@ -6,6 +6,7 @@ import (
 	"code.cloudfoundry.org/rep"
 
 	"code.cloudfoundry.org/bbs"
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
 	"code.cloudfoundry.org/lager"
 )
 
@@ -24,13 +25,19 @@ type TaskProcessor interface {
 type taskProcessor struct {
 	bbsClient         bbs.InternalClient
 	containerDelegate ContainerDelegate
+	retrier           Retrier
+	taskRetrier       TaskRetrier
+	metronClient      loggingclient.IngressClient
 	cellID            string
 }
 
-func NewTaskProcessor(bbs bbs.InternalClient, containerDelegate ContainerDelegate, cellID string) TaskProcessor {
+func NewTaskProcessor(bbs bbs.InternalClient, containerDelegate ContainerDelegate, retrier Retrier, taskRetrier TaskRetrier, metronClient loggingclient.IngressClient, cellID string) TaskProcessor {
 	return &taskProcessor{
 		bbsClient:         bbs,
 		containerDelegate: containerDelegate,
+		retrier:           retrier,
+		taskRetrier:       taskRetrier,
+		metronClient:      metronClient,
 		cellID:            cellID,
 	}
 }
@@ -64,7 +71,7 @@ func (p *taskProcessor) Process(logger lager.Logger, container executor.Containe
 }
 
 func (p *taskProcessor) processActiveContainer(logger lager.Logger, container executor.Container) {
-	ok := p.startTask(logger, container.Guid)
+	ok := p.startTask(logger, container)
 	if !ok {
 		return
 	}
@@ -72,6 +79,7 @@ func (p *taskProcessor) processActiveContainer(logger lager.Logger, container ex
 	task, err := p.bbsClient.TaskByGuid(logger, container.Guid)
 	if err != nil {
 		logger.Error("failed-fetching-task", err)
+		p.retrier.Failed(logger, container, err)
 		return
 	}
 
@@ -86,16 +94,27 @@ func (p *taskProcessor) processActiveContainer(logger lager.Logger, container ex
 		err = p.bbsClient.CompleteTask(logger, container.Guid, p.cellID, true, TaskCompletionReasonFailedToRunContainer, "")
 		if err != nil {
 			logger.Error("failed-completing-task", err)
+			p.retrier.Failed(logger, container, err)
+			return
 		}
 	}
+	p.retrier.Succeeded(container.Guid)
 }
 
 func (p *taskProcessor) processCompletedContainer(logger lager.Logger, container executor.Container) {
-	p.completeTask(logger, container)
+	retrying := p.completeTask(logger, container)
+	if retrying {
+		return
+	}
+
+	p.retrier.Succeeded(container.Guid)
+	p.taskRetrier.Forget(container.Guid)
 	p.containerDelegate.DeleteContainer(logger, container.Guid)
 }
 
-func (p *taskProcessor) startTask(logger lager.Logger, guid string) bool {
+func (p *taskProcessor) startTask(logger lager.Logger, container executor.Container) bool {
+	guid := container.Guid
+
 	logger.Info("starting-task")
 	changed, err := p.bbsClient.StartTask(logger, guid, p.cellID)
 	if err != nil {
@@ -107,6 +126,8 @@ func (p *taskProcessor) startTask(logger lager.Logger, guid string) bool {
 			p.containerDelegate.DeleteContainer(logger, guid)
 		case models.Error_ResourceNotFound:
 			p.containerDelegate.DeleteContainer(logger, guid)
+		default:
+			p.retrier.Failed(logger, container, err)
 		}
 		return false
 	}
@@ -120,17 +141,21 @@ func (p *taskProcessor) startTask(logger lager.Logger, guid string) bool {
 	return changed
 }
 
-func (p *taskProcessor) completeTask(logger lager.Logger, container executor.Container) {
+// completeTask reports the result of the task to the BBS. It returns true
+// when the container must be kept around, either because a BBS call failed
+// transiently or because the task will be rerun on this cell.
+func (p *taskProcessor) completeTask(logger lager.Logger, container executor.Container) bool {
 	var result string
 	var err error
 
 	if container.RunResult.Failed && container.RunResult.Retryable {
-		logger.Info("rejecting-task")
-		err = p.bbsClient.RejectTask(logger, container.Guid, TaskRejectionReasonContainerCreationFailed)
-		if err != nil {
-			logger.Error("failed-rejecting-task", err)
+		if p.taskRetrier.Due(container.Guid) {
+			return p.rerunTask(logger, container)
 		}
-		return
+		if p.taskRetrier.Schedule(logger, container) {
+			return true
+		}
+		return p.rejectTask(logger, container)
 	}
 
 	resultFile := container.Tags[rep.ResultFileTag]
@@ -140,11 +165,13 @@ func (p *taskProcessor) completeTask(logger lager.Logger, container executor.Con
 			err = p.bbsClient.CompleteTask(logger, container.Guid, p.cellID, true, TaskCompletionReasonFailedToFetchResult, "")
 			if err != nil {
 				logger.Error("failed-completing-task", err)
+				return p.retrier.Failed(logger, container, err)
 			}
-			return
+			return false
 		}
 	}
 
+	logger = logger.WithData(lager.Data{"result-size": len(result)})
 	logger.Info("completing-task")
 	err = p.bbsClient.CompleteTask(logger, container.Guid, p.cellID, container.RunResult.Failed, container.RunResult.FailureReason, result)
 	if err != nil {
@@ -156,9 +183,82 @@ func (p *taskProcessor) completeTask(logger lager.Logger, container executor.Con
 			if err != nil {
 				logger.Error("failed-completing-task", err)
 			}
+			return false
 		}
-		return
+		return p.retrier.Failed(logger, container, err)
 	}
 
 	logger.Info("succeeded-completing-task")
+	return false
+}
+
+// rejectTask hands the task back to the BBS to be placed on another cell. It
+// returns true when the rejection failed transiently and will be retried.
+func (p *taskProcessor) rejectTask(logger lager.Logger, container executor.Container) bool {
+	reason := container.RunResult.FailureReason
+	if reason == "" {
+		reason = TaskRejectionReasonContainerCreationFailed
+	}
+	class := ClassifyTaskRejection(reason)
+
+	logger.Info("rejecting-task", lager.Data{"rejection-reason": reason, "rejection-class": class})
+	err := p.bbsClient.RejectTask(logger, container.Guid, reason)
+	if err != nil {
+		logger.Error("failed-rejecting-task", err)
+		return p.retrier.Failed(logger, container, err)
+	}
+
+	p.countRejection(logger, class)
+	return false
+}
+
+// rerunTask replaces the completed container with a new one running the
+// same task. The task is rejected when it cannot be run again. Like
+// completeTask, it returns true when the container must be kept around.
+//
+// The completed container is only deleted once everything needed to run the
+// task again is at hand, so that a rejection failing transiently is retried
+// from it. Once it is deleted, a rejection that cannot be retried leaves the
+// task to be failed by the residual task operation.
+func (p *taskProcessor) rerunTask(logger lager.Logger, container executor.Container) bool {
+	logger = logger.Session("rerun-task")
+	logger.Info("starting")
+	defer logger.Info("finished")
+
+	task, err := p.bbsClient.TaskByGuid(logger, container.Guid)
+	if err != nil {
+		logger.Error("failed-fetching-task", err)
+		return p.rejectTask(logger, container)
+	}
+
+	runReq, err := rep.NewRunRequestFromTask(task)
+	if err != nil {
+		logger.Error("failed-to-construct-run-request", err)
+		return p.rejectTask(logger, container)
+	}
+
+	if !p.containerDelegate.DeleteContainer(logger, container.Guid) {
+		return p.rejectTask(logger, container)
+	}
+
+	allocationReq := executor.NewAllocationRequest(container.Guid, &container.Resource, container.Tags)
+	if !p.containerDelegate.AllocateContainer(logger, &allocationReq) {
+		return p.rejectTask(logger, container)
+	}
+
+	if !p.containerDelegate.RunContainer(logger, &runReq) {
+		return p.rejectTask(logger, container)
+	}
+
+	return true
+}
+
+func (p *taskProcessor) countRejection(logger lager.Logger, class string) {
+	if p.metronClient == nil {
+		return
+	}
+	err := p.metronClient.IncrementCounter(TaskRejectionCountMetric(class))
+	if err != nil {
+		logger.Error("failed-to-increment-rejection-counter", err)
+	}
 }
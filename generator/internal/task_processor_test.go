@@ -6,6 +6,7 @@ import (
 	"code.cloudfoundry.org/bbs/fake_bbs"
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/bbs/models/test/model_helpers"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
 	"code.cloudfoundry.org/executor"
 	"code.cloudfoundry.org/lager/lagertest"
 	"code.cloudfoundry.org/rep"
@@ -14,6 +15,7 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 )
 
 var processor internal.TaskProcessor
@@ -23,6 +25,9 @@ var _ = Describe("TaskProcessor", func() {
 		bbsClient                *fake_bbs.FakeInternalClient
 		expectedCellID, taskGuid string
 		containerDelegate        *fake_internal.FakeContainerDelegate
+		retrier                  *fake_internal.FakeRetrier
+		taskRetrier              *fake_internal.FakeTaskRetrier
+		fakeMetronClient         *mfakes.FakeIngressClient
 		logger                   *lagertest.TestLogger
 		task                     *models.Task
 		expectedRunRequest       executor.RunRequest
@@ -34,12 +39,15 @@ var _ = Describe("TaskProcessor", func() {
 
 		bbsClient = &fake_bbs.FakeInternalClient{}
 		containerDelegate = &fake_internal.FakeContainerDelegate{}
+		retrier = &fake_internal.FakeRetrier{}
+		taskRetrier = &fake_internal.FakeTaskRetrier{}
+		fakeMetronClient = &mfakes.FakeIngressClient{}
 		logger = lagertest.NewTestLogger("task-processor")
 
 		expectedCellID = "the-cell"
 		taskGuid = "the-guid"
 
-		processor = internal.NewTaskProcessor(bbsClient, containerDelegate, expectedCellID)
+		processor = internal.NewTaskProcessor(bbsClient, containerDelegate, retrier, taskRetrier, fakeMetronClient, expectedCellID)
 
 		task = model_helpers.NewValidTask(taskGuid)
 		expectedRunRequest, err = rep.NewRunRequestFromTask(task)
@@ -91,6 +99,13 @@ var _ = Describe("TaskProcessor", func() {
 				Expect(containerDelegate.RunContainerCallCount()).To(Equal(0))
 				Expect(containerDelegate.DeleteContainerCallCount()).To(Equal(0))
 			})
+
+			It("reports the failure to the retrier", func() {
+				Expect(retrier.FailedCallCount()).To(Equal(1))
+				_, retriedContainer, err := retrier.FailedArgsForCall(0)
+				Expect(retriedContainer.Guid).To(Equal(taskGuid))
+				Expect(err).To(MatchError("boom"))
+			})
 		})
 
 		Context("when creating the run request fails", func() {
@@ -160,6 +175,10 @@ var _ = Describe("TaskProcessor", func() {
 					Expect(containerDelegate.RunContainerCallCount()).To(Equal(0))
 					Expect(containerDelegate.DeleteContainerCallCount()).To(Equal(0))
 				})
+
+				It("reports the failure to the retrier", func() {
+					Expect(retrier.FailedCallCount()).To(Equal(1))
+				})
 			})
 		})
 	}
@@ -245,11 +264,176 @@ var _ = Describe("TaskProcessor", func() {
 				container.RunResult.Retryable = true
 			})
 
-			It("rejects the task", func() {
+			It("rejects the task with the failure reason", func() {
 				Expect(bbsClient.RejectTaskCallCount()).To(Equal(1))
 				_, guid, reason := bbsClient.RejectTaskArgsForCall(0)
 				Expect(guid).To(Equal(taskGuid))
-				Expect(reason).To(Equal(internal.TaskRejectionReasonContainerCreationFailed))
+				Expect(reason).To(Equal("oh nooooooooooooo mr bill"))
+			})
+
+			It("counts the rejection by class", func() {
+				Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(1))
+				Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("RepTaskRejectionsUnknown"))
+			})
+
+			Context("and the failure reason names a known cause", func() {
+				BeforeEach(func() {
+					container.RunResult.FailureReason = "failed to mount volume"
+				})
+
+				It("counts the rejection in that class", func() {
+					Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("RepTaskRejectionsVolumeMount"))
+				})
+			})
+
+			Context("and there is no failure reason", func() {
+				BeforeEach(func() {
+					container.RunResult.FailureReason = ""
+				})
+
+				It("rejects the task with the generic reason", func() {
+					_, _, reason := bbsClient.RejectTaskArgsForCall(0)
+					Expect(reason).To(Equal(internal.TaskRejectionReasonContainerCreationFailed))
+				})
+			})
+
+			Context("and rejecting the task fails", func() {
+				BeforeEach(func() {
+					bbsClient.RejectTaskReturns(errors.New("boom"))
+				})
+
+				It("does not count the rejection", func() {
+					Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(0))
+				})
+			})
+
+			It("asks the task retrier for a local rerun first", func() {
+				Expect(taskRetrier.ScheduleCallCount()).To(Equal(1))
+				_, retriedContainer := taskRetrier.ScheduleArgsForCall(0)
+				Expect(retriedContainer.Guid).To(Equal(taskGuid))
+			})
+
+			It("deletes the container and forgets the task once it is rejected", func() {
+				Expect(containerDelegate.DeleteContainerCallCount()).To(Equal(1))
+				Expect(taskRetrier.ForgetCallCount()).To(Equal(1))
+				Expect(taskRetrier.ForgetArgsForCall(0)).To(Equal(taskGuid))
+			})
+
+			Context("and a local rerun is scheduled", func() {
+				BeforeEach(func() {
+					taskRetrier.ScheduleReturns(true)
+				})
+
+				It("neither rejects the task nor deletes the container", func() {
+					Expect(bbsClient.RejectTaskCallCount()).To(Equal(0))
+					Expect(containerDelegate.DeleteContainerCallCount()).To(Equal(0))
+					Expect(taskRetrier.ForgetCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("and a local rerun is due", func() {
+				BeforeEach(func() {
+					taskRetrier.DueReturns(true)
+					containerDelegate.DeleteContainerReturns(true)
+					containerDelegate.AllocateContainerReturns(true)
+					containerDelegate.RunContainerReturns(true)
+				})
+
+				It("replaces the container with one running the task", func() {
+					Expect(taskRetrier.ScheduleCallCount()).To(Equal(0))
+					Expect(containerDelegate.DeleteContainerCallCount()).To(Equal(1))
+
+					Expect(containerDelegate.AllocateContainerCallCount()).To(Equal(1))
+					_, allocationReq := containerDelegate.AllocateContainerArgsForCall(0)
+					Expect(allocationReq.Guid).To(Equal(taskGuid))
+					Expect(allocationReq.Tags).To(Equal(container.Tags))
+
+					Expect(containerDelegate.RunContainerCallCount()).To(Equal(1))
+					_, runReq := containerDelegate.RunContainerArgsForCall(0)
+					Expect(*runReq).To(Equal(expectedRunRequest))
+
+					Expect(bbsClient.RejectTaskCallCount()).To(Equal(0))
+					Expect(taskRetrier.ForgetCallCount()).To(Equal(0))
+				})
+
+				itRejectsAndCleansUp := func() {
+					It("rejects the task, deletes the container and forgets the task", func() {
+						Expect(bbsClient.RejectTaskCallCount()).To(Equal(1))
+						_, guid := containerDelegate.DeleteContainerArgsForCall(containerDelegate.DeleteContainerCallCount() - 1)
+						Expect(guid).To(Equal(taskGuid))
+						Expect(taskRetrier.ForgetCallCount()).To(Equal(1))
+					})
+				}
+
+				Context("and the task cannot be fetched", func() {
+					BeforeEach(func() {
+						bbsClient.TaskByGuidReturns(nil, errors.New("boom"))
+					})
+
+					itRejectsAndCleansUp()
+
+					It("does not allocate a container", func() {
+						Expect(containerDelegate.AllocateContainerCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("and the completed container cannot be deleted", func() {
+					BeforeEach(func() {
+						containerDelegate.DeleteContainerReturns(false)
+					})
+
+					itRejectsAndCleansUp()
+
+					It("does not allocate a container", func() {
+						Expect(containerDelegate.AllocateContainerCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("and the container cannot be allocated", func() {
+					BeforeEach(func() {
+						containerDelegate.AllocateContainerReturns(false)
+					})
+
+					itRejectsAndCleansUp()
+
+					It("does not run a container", func() {
+						Expect(containerDelegate.RunContainerCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("and the container fails to run", func() {
+					BeforeEach(func() {
+						containerDelegate.RunContainerReturns(false)
+					})
+
+					itRejectsAndCleansUp()
+				})
+
+				Context("and rejecting the task fails transiently", func() {
+					BeforeEach(func() {
+						bbsClient.TaskByGuidReturns(nil, errors.New("boom"))
+						bbsClient.RejectTaskReturns(errors.New("boom"))
+						retrier.FailedReturns(true)
+					})
+
+					It("keeps the container for the retry", func() {
+						Expect(containerDelegate.DeleteContainerCallCount()).To(Equal(0))
+						Expect(taskRetrier.ForgetCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("and the run request cannot be built", func() {
+					BeforeEach(func() {
+						task.RootFs = "% s"
+						bbsClient.RejectTaskReturns(errors.New("boom"))
+						retrier.FailedReturns(true)
+					})
+
+					It("rejects the task before deleting the container", func() {
+						Expect(bbsClient.RejectTaskCallCount()).To(Equal(1))
+						Expect(containerDelegate.DeleteContainerCallCount()).To(Equal(0))
+					})
+				})
 			})
 		})
 
@@ -268,6 +452,36 @@ var _ = Describe("TaskProcessor", func() {
 					Expect(reason).To(Equal(internal.TaskCompletionReasonInvalidTransition))
 					Expect(result).To(Equal(""))
 				})
+
+				It("does not retry", func() {
+					Expect(retrier.FailedCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("for another reason", func() {
+				BeforeEach(func() {
+					bbsClient.CompleteTaskReturns(errors.New("boom"))
+				})
+
+				It("reports the failure to the retrier", func() {
+					Expect(retrier.FailedCallCount()).To(Equal(1))
+					_, _, err := retrier.FailedArgsForCall(0)
+					Expect(err).To(MatchError("boom"))
+				})
+
+				It("deletes the container when no retry is scheduled", func() {
+					Expect(containerDelegate.DeleteContainerCallCount()).To(Equal(1))
+				})
+
+				Context("and a retry is scheduled", func() {
+					BeforeEach(func() {
+						retrier.FailedReturns(true)
+					})
+
+					It("keeps the container for the retry", func() {
+						Expect(containerDelegate.DeleteContainerCallCount()).To(Equal(0))
+					})
+				})
 			})
 		})
 
@@ -298,6 +512,10 @@ var _ = Describe("TaskProcessor", func() {
 				Expect(result).To(Equal("i am a result yo"))
 			})
 
+			It("logs the result size", func() {
+				Expect(logger).To(gbytes.Say(`"result-size":16`))
+			})
+
 			Context("and there is no result file tag", func() {
 				BeforeEach(func() {
 					container.Tags = executor.Tags{}
@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+)
+
+// Task rejection classes group the executor failure reasons of retryable
+// task failures, so rejections can be counted by cause.
+const (
+	TaskRejectionClassDependencyDownload  = "DependencyDownload"
+	TaskRejectionClassVolumeMount         = "VolumeMount"
+	TaskRejectionClassCredentialDirectory = "CredentialDirectory"
+	TaskRejectionClassOutOfDisk           = "OutOfDisk"
+	TaskRejectionClassContainerCreation   = "ContainerCreation"
+	TaskRejectionClassUnknown             = "Unknown"
+)
+
+// The failure reasons the executor's container store completes containers
+// with when their setup failed and they may be retried on another cell.
+const (
+	executorDependencyDownloadFailed  = "failed to download cached artifacts"
+	executorVolumeMountFailed         = "failed to mount volume"
+	executorCredentialDirectoryFailed = "failed to create credentials directory"
+	executorContainerCreationFailed   = "failed to create container"
+)
+
+var taskRejectionClasses = map[string]string{
+	executorDependencyDownloadFailed:  TaskRejectionClassDependencyDownload,
+	executorVolumeMountFailed:         TaskRejectionClassVolumeMount,
+	executorCredentialDirectoryFailed: TaskRejectionClassCredentialDirectory,
+	executorContainerCreationFailed:   TaskRejectionClassContainerCreation,
+}
+
+// TaskRejectionCountMetric names the counter of task rejections in a class.
+func TaskRejectionCountMetric(class string) string {
+	return fmt.Sprintf("RepTaskRejections%s", class)
+}
+
+// ClassifyTaskRejection maps an executor failure reason to a task rejection
+// class. The executor reports a failure as one of its messages, optionally
+// followed by the underlying error, so the message is matched exactly. A
+// container creation failure caused by the cell running out of disk is
+// classified as such.
+func ClassifyTaskRejection(failureReason string) string {
+	message, cause := failureReason, ""
+	if i := strings.IndexAny(failureReason, ":,"); i >= 0 {
+		message, cause = failureReason[:i], failureReason[i+1:]
+	}
+
+	if failureReason == TaskRejectionReasonContainerCreationFailed {
+		message = executorContainerCreationFailed
+	}
+
+	class, ok := taskRejectionClasses[message]
+	if !ok {
+		return TaskRejectionClassUnknown
+	}
+
+	if class == TaskRejectionClassContainerCreation && isOutOfDisk(cause) {
+		return TaskRejectionClassOutOfDisk
+	}
+	return class
+}
+
+func isOutOfDisk(cause string) bool {
+	cause = strings.TrimSpace(cause)
+	return strings.HasSuffix(cause, syscall.ENOSPC.Error()) || strings.HasSuffix(cause, syscall.EDQUOT.Error())
+}
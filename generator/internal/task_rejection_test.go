@@ -0,0 +1,35 @@
+package internal_test
+
+import (
+	"code.cloudfoundry.org/rep/generator/internal"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClassifyTaskRejection", func() {
+	// Failure reasons as reported by the executor's container store.
+	reasons := []struct {
+		failureReason string
+		class         string
+	}{
+		{"failed to download cached artifacts", internal.TaskRejectionClassDependencyDownload},
+		{"failed to mount volume", internal.TaskRejectionClassVolumeMount},
+		{"failed to mount volume, errors: failed to mount nfs share", internal.TaskRejectionClassVolumeMount},
+		{"failed to create credentials directory", internal.TaskRejectionClassCredentialDirectory},
+		{"failed to create container: running image plugin create: pulling the image: unexpected EOF", internal.TaskRejectionClassContainerCreation},
+		{"failed to create container: write /var/vcap/data/grootfs/store: no space left on device", internal.TaskRejectionClassOutOfDisk},
+		{"failed to create container: creating volume: disk quota exceeded", internal.TaskRejectionClassOutOfDisk},
+		{internal.TaskRejectionReasonContainerCreationFailed, internal.TaskRejectionClassContainerCreation},
+		{"Exited with status 1", internal.TaskRejectionClassUnknown},
+		{"volume mounted but failed to create container", internal.TaskRejectionClassUnknown},
+		{"", internal.TaskRejectionClassUnknown},
+	}
+
+	for _, reason := range reasons {
+		reason := reason
+		It("classifies "+reason.failureReason+" as "+reason.class, func() {
+			Expect(internal.ClassifyTaskRejection(reason.failureReason)).To(Equal(reason.class))
+		})
+	}
+})
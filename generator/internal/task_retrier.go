@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/clock"
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/lager"
+)
+
+const (
+	taskLocalRetriesScheduledCounter = "RepTaskLocalRetriesScheduled"
+	taskLocalRetriesExhaustedCounter = "RepTaskLocalRetriesExhausted"
+)
+
+//go:generate counterfeiter -o fake_internal/fake_task_retrier.go task_retrier.go TaskRetrier
+
+// TaskRetrier re-runs tasks that failed with a retryable error on this cell
+// before they are rejected back to the BBS.
+type TaskRetrier interface {
+	// Schedule has the completed container processed again once its backoff
+	// has elapsed. It returns false when the task's retry budget is
+	// exhausted, in which case the task should be rejected. While a rerun is
+	// pending it returns true, and the container should be left in place.
+	Schedule(logger lager.Logger, container executor.Container) bool
+
+	// Due reports whether the backoff of a scheduled rerun of the task has
+	// elapsed, in which case the task should be rerun now. A rerun is only
+	// reported due once.
+	Due(guid string) bool
+
+	// Forget resets the retry budget of the task once its container is
+	// deleted.
+	Forget(guid string)
+
+	// Prune forgets the tasks whose containers are missing from containers,
+	// such as containers deleted outside of the rep or while a rerun failed.
+	Prune(containers map[string]executor.Container)
+}
+
+type taskRetrier struct {
+	clock        clock.Clock
+	policy       RetryPolicy
+	metronClient loggingclient.IngressClient
+	retry        func(lager.Logger, executor.Container)
+
+	lock      sync.Mutex
+	attempts  map[string]int
+	scheduled map[string]struct{}
+	due       map[string]struct{}
+}
+
+// NewTaskRetrier returns a TaskRetrier allowing each task up to
+// policy.MaxAttempts local reruns, calling retry once the backoff of a rerun
+// has elapsed. A policy without attempts never retries.
+func NewTaskRetrier(
+	clock clock.Clock,
+	policy RetryPolicy,
+	metronClient loggingclient.IngressClient,
+	retry func(lager.Logger, executor.Container),
+) TaskRetrier {
+	return &taskRetrier{
+		clock:        clock,
+		policy:       policy,
+		metronClient: metronClient,
+		retry:        retry,
+		attempts:     map[string]int{},
+		scheduled:    map[string]struct{}{},
+		due:          map[string]struct{}{},
+	}
+}
+
+func (r *taskRetrier) Schedule(logger lager.Logger, container executor.Container) bool {
+	if r.policy.MaxAttempts <= 0 {
+		return false
+	}
+
+	logger = logger.Session("task-retrier")
+
+	r.lock.Lock()
+	if _, ok := r.scheduled[container.Guid]; ok {
+		r.lock.Unlock()
+		logger.Debug("rerun-already-scheduled")
+		return true
+	}
+
+	attempt := r.attempts[container.Guid] + 1
+	if attempt > r.policy.MaxAttempts {
+		r.lock.Unlock()
+		logger.Info("local-retries-exhausted", lager.Data{"attempts": attempt - 1})
+		r.increment(logger, taskLocalRetriesExhaustedCounter)
+		return false
+	}
+	r.attempts[container.Guid] = attempt
+	r.scheduled[container.Guid] = struct{}{}
+	r.lock.Unlock()
+
+	backoff := r.policy.backoff(attempt)
+	logger.Info("scheduling-rerun", lager.Data{
+		"attempt":        attempt,
+		"backoff":        backoff.String(),
+		"failure-reason": container.RunResult.FailureReason,
+	})
+	r.increment(logger, taskLocalRetriesScheduledCounter)
+
+	timer := r.clock.NewTimer(backoff)
+	go func() {
+		<-timer.C()
+
+		r.lock.Lock()
+		_, stillScheduled := r.scheduled[container.Guid]
+		if stillScheduled {
+			r.due[container.Guid] = struct{}{}
+		}
+		r.lock.Unlock()
+
+		if stillScheduled {
+			logger.Info("rerun-due", lager.Data{"attempt": attempt})
+			r.retry(logger, container)
+		}
+	}()
+
+	return true
+}
+
+func (r *taskRetrier) Due(guid string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.due[guid]; !ok {
+		return false
+	}
+	delete(r.due, guid)
+	delete(r.scheduled, guid)
+	return true
+}
+
+func (r *taskRetrier) Forget(guid string) {
+	r.lock.Lock()
+	delete(r.attempts, guid)
+	delete(r.scheduled, guid)
+	delete(r.due, guid)
+	r.lock.Unlock()
+}
+
+func (r *taskRetrier) Prune(containers map[string]executor.Container) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for guid := range r.attempts {
+		if _, found := containers[guid]; !found {
+			delete(r.attempts, guid)
+			delete(r.scheduled, guid)
+			delete(r.due, guid)
+		}
+	}
+}
+
+func (r *taskRetrier) increment(logger lager.Logger, counter string) {
+	if r.metronClient == nil {
+		return
+	}
+
+	err := r.metronClient.IncrementCounter(counter)
+	if err != nil {
+		logger.Error("failed-to-increment-counter", err, lager.Data{"counter": counter})
+	}
+}
@@ -0,0 +1,152 @@
+package internal_test
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/rep/generator/internal"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TaskRetrier", func() {
+	var (
+		logger           *lagertest.TestLogger
+		fakeClock        *fakeclock.FakeClock
+		fakeMetronClient *mfakes.FakeIngressClient
+		policy           internal.RetryPolicy
+		taskRetrier      internal.TaskRetrier
+		container        executor.Container
+
+		retryLock sync.Mutex
+		retries   []executor.Container
+	)
+
+	retryCount := func() int {
+		retryLock.Lock()
+		defer retryLock.Unlock()
+		return len(retries)
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeMetronClient = new(mfakes.FakeIngressClient)
+		policy = internal.RetryPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Second,
+			MaxBackoff:     10 * time.Second,
+		}
+		container = executor.Container{Guid: "task-guid"}
+
+		retryLock.Lock()
+		retries = nil
+		retryLock.Unlock()
+	})
+
+	JustBeforeEach(func() {
+		taskRetrier = internal.NewTaskRetrier(fakeClock, policy, fakeMetronClient, func(_ lager.Logger, c executor.Container) {
+			retryLock.Lock()
+			retries = append(retries, c)
+			retryLock.Unlock()
+		})
+	})
+
+	It("has the container processed again once the backoff elapses", func() {
+		Expect(taskRetrier.Schedule(logger, container)).To(BeTrue())
+		Expect(taskRetrier.Due(container.Guid)).To(BeFalse())
+
+		Eventually(fakeClock.WatcherCount).Should(Equal(1))
+		fakeClock.Increment(time.Second - time.Millisecond)
+		Consistently(retryCount).Should(Equal(0))
+
+		fakeClock.Increment(time.Millisecond)
+		Eventually(retryCount).Should(Equal(1))
+		Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("RepTaskLocalRetriesScheduled"))
+
+		By("reporting the rerun due once")
+		Expect(taskRetrier.Due(container.Guid)).To(BeTrue())
+		Expect(taskRetrier.Due(container.Guid)).To(BeFalse())
+	})
+
+	It("does not schedule a second rerun while one is pending", func() {
+		Expect(taskRetrier.Schedule(logger, container)).To(BeTrue())
+		Expect(taskRetrier.Schedule(logger, container)).To(BeTrue())
+
+		Eventually(fakeClock.WatcherCount).Should(Equal(1))
+		fakeClock.Increment(time.Second)
+		Eventually(retryCount).Should(Equal(1))
+		Consistently(retryCount).Should(Equal(1))
+	})
+
+	It("doubles the backoff and stops once the budget is exhausted", func() {
+		Expect(taskRetrier.Schedule(logger, container)).To(BeTrue())
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Eventually(retryCount).Should(Equal(1))
+		Expect(taskRetrier.Due(container.Guid)).To(BeTrue())
+
+		Expect(taskRetrier.Schedule(logger, container)).To(BeTrue())
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Consistently(retryCount).Should(Equal(1))
+		fakeClock.Increment(time.Second)
+		Eventually(retryCount).Should(Equal(2))
+		Expect(taskRetrier.Due(container.Guid)).To(BeTrue())
+
+		Expect(taskRetrier.Schedule(logger, container)).To(BeFalse())
+		Expect(fakeMetronClient.IncrementCounterArgsForCall(2)).To(Equal("RepTaskLocalRetriesExhausted"))
+
+		By("staying exhausted until the task is forgotten")
+		Expect(taskRetrier.Schedule(logger, container)).To(BeFalse())
+
+		taskRetrier.Forget(container.Guid)
+		Expect(taskRetrier.Schedule(logger, container)).To(BeTrue())
+	})
+
+	Context("when the task is forgotten while a rerun is pending", func() {
+		It("does not have the container processed again", func() {
+			Expect(taskRetrier.Schedule(logger, container)).To(BeTrue())
+			taskRetrier.Forget(container.Guid)
+
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+			Consistently(retryCount).Should(Equal(0))
+			Expect(taskRetrier.Due(container.Guid)).To(BeFalse())
+		})
+	})
+
+	Context("when the container is pruned", func() {
+		It("does not have the container processed again", func() {
+			Expect(taskRetrier.Schedule(logger, container)).To(BeTrue())
+			taskRetrier.Prune(map[string]executor.Container{})
+
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+			Consistently(retryCount).Should(Equal(0))
+			Expect(taskRetrier.Due(container.Guid)).To(BeFalse())
+		})
+
+		It("keeps the tasks whose containers still exist", func() {
+			Expect(taskRetrier.Schedule(logger, container)).To(BeTrue())
+			taskRetrier.Prune(map[string]executor.Container{container.Guid: container})
+
+			Expect(taskRetrier.Schedule(logger, container)).To(BeTrue())
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+			Eventually(retryCount).Should(Equal(1))
+		})
+	})
+
+	Context("when the policy has no attempts", func() {
+		BeforeEach(func() {
+			policy.MaxAttempts = 0
+		})
+
+		It("never reruns the task", func() {
+			Expect(taskRetrier.Schedule(logger, container)).To(BeFalse())
+			Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(0))
+		})
+	})
+})
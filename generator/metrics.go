@@ -0,0 +1,161 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock"
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/rep"
+	"code.cloudfoundry.org/rep/generator/internal"
+	"code.cloudfoundry.org/rep/history"
+)
+
+// Operation types used in metric names. Container operations are named after
+// the lifecycle and state of the container, e.g. RepLRPRunningContainerOperationDuration.
+const (
+	OperationTypeResidualInstanceLRP   = "ResidualInstanceLRP"
+	OperationTypeResidualEvacuatingLRP = "ResidualEvacuatingLRP"
+	OperationTypeResidualJointLRP      = "ResidualJointLRP"
+	OperationTypeResidualTask          = "ResidualTask"
+	OperationTypeMissingContainer      = "MissingContainer"
+)
+
+// metricNameAcronyms spells lifecycles whose tag is an acronym in capitals.
+var metricNameAcronyms = map[string]string{
+	rep.LRPLifecycle: "LRP",
+}
+
+var stateMetricNames = map[executor.State]string{
+	executor.StateReserved:     "Reserved",
+	executor.StateInitializing: "Initializing",
+	executor.StateCreated:      "Created",
+	executor.StateRunning:      "Running",
+	executor.StateCompleted:    "Completed",
+}
+
+// ContainerOperationType names the operation type of a container operation
+// for a container with the given lifecycle and state. Registered lifecycles
+// are named after their tag, e.g. "system-daemon" as "SystemDaemon".
+func ContainerOperationType(lifecycles *rep.LifecycleRegistry, lifecycle string, state executor.State) string {
+	lifecycleName := "Unknown"
+	if lifecycles.Known(lifecycle) {
+		lifecycleName = lifecycleMetricName(lifecycle)
+	}
+
+	stateName, ok := stateMetricNames[state]
+	if !ok {
+		stateName = "Invalid"
+	}
+
+	return lifecycleName + stateName + "Container"
+}
+
+func lifecycleMetricName(lifecycle string) string {
+	if name, ok := metricNameAcronyms[lifecycle]; ok {
+		return name
+	}
+
+	words := strings.FieldsFunc(lifecycle, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	name := ""
+	for _, word := range words {
+		name += strings.ToUpper(word[:1]) + word[1:]
+	}
+	if name == "" {
+		return "Unknown"
+	}
+	return name
+}
+
+func OperationCountMetric(operationType string) string {
+	return fmt.Sprintf("Rep%sOperationCount", operationType)
+}
+
+func OperationDurationMetric(operationType string) string {
+	return fmt.Sprintf("Rep%sOperationDuration", operationType)
+}
+
+func BBSErrorCountMetric(errorType models.Error_Type) string {
+	return fmt.Sprintf("RepBBS%sErrors", errorType.String())
+}
+
+// instrumentation records executed operations in the history and reports
+// their metrics. Its zero value records nothing.
+type instrumentation struct {
+	history      *history.History
+	metronClient loggingclient.IngressClient
+	clock        clock.Clock
+}
+
+type operationRun struct {
+	logger       lager.Logger
+	recording    *history.Recording
+	metronClient loggingclient.IngressClient
+	clock        clock.Clock
+	started      time.Time
+}
+
+func (i instrumentation) start(logger lager.Logger, operation, guid string) (lager.Logger, *operationRun) {
+	logger, recording := i.history.Start(logger, operation, guid)
+
+	run := &operationRun{
+		logger:       logger,
+		recording:    recording,
+		metronClient: i.metronClient,
+		clock:        i.clock,
+	}
+	if run.clock != nil {
+		run.started = run.clock.Now()
+	}
+
+	return logger, run
+}
+
+func (r *operationRun) finish(operationType string) {
+	r.recording.Finish()
+
+	if r.clock != nil {
+		emitOperationMetrics(r.logger, r.metronClient, operationType, r.clock.Since(r.started))
+	}
+}
+
+// emitOperationMetrics counts an executed operation of the type and reports
+// how long it took.
+func emitOperationMetrics(logger lager.Logger, metronClient loggingclient.IngressClient, operationType string, duration time.Duration) {
+	if metronClient == nil {
+		return
+	}
+
+	err := metronClient.IncrementCounter(OperationCountMetric(operationType))
+	if err != nil {
+		logger.Error("failed-to-increment-operation-count", err, lager.Data{"operation-type": operationType})
+	}
+
+	err = metronClient.SendDuration(OperationDurationMetric(operationType), duration)
+	if err != nil {
+		logger.Error("failed-to-send-operation-duration", err, lager.Data{"operation-type": operationType})
+	}
+}
+
+// countBBSErrors returns an observer counting failed BBS requests by the
+// type of their error.
+func countBBSErrors(metronClient loggingclient.IngressClient) internal.BBSCallObserver {
+	return func(logger lager.Logger, name string, duration time.Duration, err error) {
+		if err == nil {
+			return
+		}
+
+		bbsErr := models.ConvertError(err)
+		sendErr := metronClient.IncrementCounter(BBSErrorCountMetric(bbsErr.Type))
+		if sendErr != nil {
+			logger.Error("failed-to-increment-bbs-error-count", sendErr, lager.Data{"request": name})
+		}
+	}
+}
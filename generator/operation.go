@@ -5,6 +5,7 @@ import (
 
 	"code.cloudfoundry.org/bbs"
 	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/executor"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/rep"
 	"code.cloudfoundry.org/rep/generator/internal"
@@ -17,6 +18,8 @@ type ResidualInstanceLRPOperation struct {
 	containerDelegate internal.ContainerDelegate
 	models.ActualLRPKey
 	models.ActualLRPInstanceKey
+
+	instrumentation
 }
 
 func NewResidualInstanceLRPOperation(logger lager.Logger,
@@ -39,7 +42,10 @@ func (o *ResidualInstanceLRPOperation) Key() string {
 }
 
 func (o *ResidualInstanceLRPOperation) Execute() {
-	logger := o.logger.Session("executing-residual-instance-lrp-operation", lager.Data{
+	logger, run := o.start(o.logger, "residual-instance-lrp", o.GetInstanceGuid())
+	defer run.finish(OperationTypeResidualInstanceLRP)
+
+	logger = logger.Session("executing-residual-instance-lrp-operation", lager.Data{
 		"lrp-key":          o.ActualLRPKey,
 		"lrp-instance-key": o.ActualLRPInstanceKey,
 	})
@@ -65,6 +71,8 @@ type ResidualEvacuatingLRPOperation struct {
 	containerDelegate internal.ContainerDelegate
 	models.ActualLRPKey
 	models.ActualLRPInstanceKey
+
+	instrumentation
 }
 
 func NewResidualEvacuatingLRPOperation(logger lager.Logger,
@@ -87,7 +95,10 @@ func (o *ResidualEvacuatingLRPOperation) Key() string {
 }
 
 func (o *ResidualEvacuatingLRPOperation) Execute() {
-	logger := o.logger.Session("executing-residual-evacuating-lrp-operation", lager.Data{
+	logger, run := o.start(o.logger, "residual-evacuating-lrp", o.GetInstanceGuid())
+	defer run.finish(OperationTypeResidualEvacuatingLRP)
+
+	logger = logger.Session("executing-residual-evacuating-lrp-operation", lager.Data{
 		"lrp-key":          o.ActualLRPKey,
 		"lrp-instance-key": o.ActualLRPInstanceKey,
 	})
@@ -110,6 +121,8 @@ type ResidualJointLRPOperation struct {
 	containerDelegate internal.ContainerDelegate
 	models.ActualLRPKey
 	models.ActualLRPInstanceKey
+
+	instrumentation
 }
 
 func NewResidualJointLRPOperation(logger lager.Logger,
@@ -132,7 +145,10 @@ func (o *ResidualJointLRPOperation) Key() string {
 }
 
 func (o *ResidualJointLRPOperation) Execute() {
-	logger := o.logger.Session("executing-residual-joint-lrp-operation", lager.Data{
+	logger, run := o.start(o.logger, "residual-joint-lrp", o.GetInstanceGuid())
+	defer run.finish(OperationTypeResidualJointLRP)
+
+	logger = logger.Session("executing-residual-joint-lrp-operation", lager.Data{
 		"lrp-key":          o.ActualLRPKey,
 		"lrp-instance-key": o.ActualLRPInstanceKey,
 	})
@@ -158,6 +174,8 @@ type ResidualTaskOperation struct {
 	CellId            string
 	bbsClient         bbs.InternalClient
 	containerDelegate internal.ContainerDelegate
+
+	instrumentation
 }
 
 func NewResidualTaskOperation(
@@ -181,7 +199,10 @@ func (o *ResidualTaskOperation) Key() string {
 }
 
 func (o *ResidualTaskOperation) Execute() {
-	logger := o.logger.Session("executing-residual-task-operation", lager.Data{
+	logger, run := o.start(o.logger, "residual-task", o.TaskGuid)
+	defer run.finish(OperationTypeResidualTask)
+
+	logger = logger.Session("executing-residual-task-operation", lager.Data{
 		"task-guid": o.TaskGuid,
 	})
 	logger.Info("starting")
@@ -203,23 +224,24 @@ func (o *ResidualTaskOperation) Execute() {
 // bbs or container operations necessary to harmonize the state of the world.
 type ContainerOperation struct {
 	logger            lager.Logger
-	lrpProcessor      internal.LRPProcessor
-	taskProcessor     internal.TaskProcessor
+	lifecycles        *rep.LifecycleRegistry
 	containerDelegate internal.ContainerDelegate
 	Guid              string
+
+	priority   Priority
+	evacuation bool
+	instrumentation
 }
 
 func NewContainerOperation(
 	logger lager.Logger,
-	lrpProcessor internal.LRPProcessor,
-	taskProcessor internal.TaskProcessor,
+	lifecycles *rep.LifecycleRegistry,
 	containerDelegate internal.ContainerDelegate,
 	guid string,
 ) *ContainerOperation {
 	return &ContainerOperation{
 		logger:            logger,
-		lrpProcessor:      lrpProcessor,
-		taskProcessor:     taskProcessor,
+		lifecycles:        lifecycles,
 		containerDelegate: containerDelegate,
 		Guid:              guid,
 	}
@@ -230,7 +252,11 @@ func (o *ContainerOperation) Key() string {
 }
 
 func (o *ContainerOperation) Execute() {
-	logger := o.logger.Session("executing-container-operation", lager.Data{
+	logger, run := o.start(o.logger, "container", o.Guid)
+	operationType := OperationTypeMissingContainer
+	defer func() { run.finish(operationType) }()
+
+	logger = logger.Session("executing-container-operation", lager.Data{
 		"container-guid": o.Guid,
 	})
 	logger.Info("starting")
@@ -247,18 +273,26 @@ func (o *ContainerOperation) Execute() {
 	})
 
 	lifecycle := container.Tags[rep.LifecycleTag]
+	operationType = ContainerOperationType(o.lifecycles, lifecycle, container.State)
 
-	switch lifecycle {
-	case rep.LRPLifecycle:
-		o.lrpProcessor.Process(logger, container)
-		return
-
-	case rep.TaskLifecycle:
-		o.taskProcessor.Process(logger, container)
-		return
-
-	default:
+	processor, ok := o.lifecycles.Processor(lifecycle)
+	if !ok {
 		logger.Error("failed-to-process-container-with-unknown-lifecycle", fmt.Errorf("unknown lifecycle: %s", lifecycle))
 		return
 	}
+
+	processor.Process(logger, container)
+}
+
+// RegisterLifecycles registers the processors of the lifecycles the
+// generator handles itself. LRPs are evacuated. Canary containers are managed
+// by the canary and are skipped.
+func RegisterLifecycles(lifecycles *rep.LifecycleRegistry, lrpProcessor internal.LRPProcessor, taskProcessor internal.TaskProcessor) {
+	lifecycles.RegisterProcessor(rep.LRPLifecycle, lrpProcessor)
+	lifecycles.RegisterEvacuated(rep.LRPLifecycle)
+	lifecycles.RegisterProcessor(rep.TaskLifecycle, taskProcessor)
+	lifecycles.RegisterProcessor(rep.CanaryLifecycle, rep.ContainerProcessorFunc(func(logger lager.Logger, _ executor.Container) {
+		logger.Debug("skipped-canary-container")
+	}))
+	lifecycles.RegisterCellManaged(rep.CanaryLifecycle)
 }
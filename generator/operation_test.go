@@ -335,6 +335,7 @@ var _ = Describe("Operation", func() {
 			containerDelegate  *fake_internal.FakeContainerDelegate
 			lrpProcessor       *fake_internal.FakeLRPProcessor
 			taskProcessor      *fake_internal.FakeTaskProcessor
+			lifecycles         *rep.LifecycleRegistry
 			containerOperation *generator.ContainerOperation
 			guid               string
 		)
@@ -344,7 +345,9 @@ var _ = Describe("Operation", func() {
 			lrpProcessor = new(fake_internal.FakeLRPProcessor)
 			taskProcessor = new(fake_internal.FakeTaskProcessor)
 			guid = "the-guid"
-			containerOperation = generator.NewContainerOperation(logger, lrpProcessor, taskProcessor, containerDelegate, guid)
+			lifecycles = rep.NewLifecycleRegistry()
+			generator.RegisterLifecycles(lifecycles, lrpProcessor, taskProcessor)
+			containerOperation = generator.NewContainerOperation(logger, lifecycles, containerDelegate, guid)
 		})
 
 		Describe("Key", func() {
@@ -434,6 +437,47 @@ var _ = Describe("Operation", func() {
 					})
 				})
 
+				Context("when the container has a canary lifecycle tag", func() {
+					BeforeEach(func() {
+						container = executor.Container{
+							Tags: executor.Tags{
+								rep.LifecycleTag: rep.CanaryLifecycle,
+							},
+						}
+						containerDelegate.GetContainerReturns(container, true)
+					})
+
+					It("does not farm the container out to any processor", func() {
+						Expect(lrpProcessor.ProcessCallCount()).To(Equal(0))
+						Expect(taskProcessor.ProcessCallCount()).To(Equal(0))
+						Expect(logger).NotTo(Say("failed-to-process-container-with-unknown-lifecycle"))
+					})
+				})
+
+				Context("when the container has a lifecycle registered outside the generator", func() {
+					var daemonProcessor *fake_internal.FakeTaskProcessor
+
+					BeforeEach(func() {
+						daemonProcessor = new(fake_internal.FakeTaskProcessor)
+						lifecycles.RegisterProcessor("daemon", daemonProcessor)
+
+						container = executor.Container{
+							Tags: executor.Tags{
+								rep.LifecycleTag: "daemon",
+							},
+						}
+						containerDelegate.GetContainerReturns(container, true)
+					})
+
+					It("farms the container out to the registered processor", func() {
+						Expect(daemonProcessor.ProcessCallCount()).To(Equal(1))
+						_, actualContainer := daemonProcessor.ProcessArgsForCall(0)
+						Expect(actualContainer).To(Equal(container))
+						Expect(lrpProcessor.ProcessCallCount()).To(Equal(0))
+						Expect(taskProcessor.ProcessCallCount()).To(Equal(0))
+					})
+				})
+
 				Context("when the container has an unknown lifecycle tag", func() {
 					BeforeEach(func() {
 						container = executor.Container{
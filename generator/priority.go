@@ -0,0 +1,95 @@
+package generator
+
+import (
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/operationq"
+)
+
+// Priority classifies an operation for a priority-aware queue. Higher
+// priorities are executed first.
+type Priority int
+
+const (
+	PriorityResidual Priority = iota
+	PriorityReserved
+	PriorityRunning
+	PriorityCompleted
+)
+
+var Priorities = []Priority{PriorityCompleted, PriorityRunning, PriorityReserved, PriorityResidual}
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityCompleted:
+		return "completed"
+	case PriorityRunning:
+		return "running"
+	case PriorityReserved:
+		return "reserved"
+	default:
+		return "residual"
+	}
+}
+
+// PrioritizedOperation is an operation that knows its priority class.
+// Evacuation operations are boosted ahead of every other operation.
+type PrioritizedOperation interface {
+	operationq.Operation
+	Priority() Priority
+	Evacuation() bool
+}
+
+func containerPriority(state executor.State) Priority {
+	switch state {
+	case executor.StateCompleted:
+		return PriorityCompleted
+	case executor.StateRunning:
+		return PriorityRunning
+	default:
+		return PriorityReserved
+	}
+}
+
+func (o *ResidualInstanceLRPOperation) Priority() Priority {
+	return PriorityResidual
+}
+
+func (o *ResidualInstanceLRPOperation) Evacuation() bool {
+	return false
+}
+
+func (o *ResidualEvacuatingLRPOperation) Priority() Priority {
+	return PriorityResidual
+}
+
+func (o *ResidualEvacuatingLRPOperation) Evacuation() bool {
+	return true
+}
+
+func (o *ResidualJointLRPOperation) Priority() Priority {
+	return PriorityResidual
+}
+
+func (o *ResidualJointLRPOperation) Evacuation() bool {
+	return true
+}
+
+func (o *ResidualTaskOperation) Priority() Priority {
+	return PriorityResidual
+}
+
+func (o *ResidualTaskOperation) Evacuation() bool {
+	return false
+}
+
+// Priority is based on the state of the container when the operation was
+// generated.
+func (o *ContainerOperation) Priority() Priority {
+	return o.priority
+}
+
+// Evacuation is true for containers of evacuated lifecycles while the cell is
+// evacuating.
+func (o *ContainerOperation) Evacuation() bool {
+	return o.evacuation
+}
@@ -0,0 +1,223 @@
+package generator
+
+import (
+	"sort"
+
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/rep"
+)
+
+const (
+	ActionClaimAndRunActualLRP              = "claim-actual-lrp-and-run-container"
+	ActionClaimActualLRP                    = "claim-actual-lrp"
+	ActionStartActualLRP                    = "start-actual-lrp"
+	ActionRemoveActualLRPAndDeleteContainer = "remove-actual-lrp-and-delete-container"
+	ActionCrashActualLRPAndDeleteContainer  = "crash-actual-lrp-and-delete-container"
+	ActionEvacuateClaimedActualLRP          = "evacuate-claimed-actual-lrp-and-delete-container"
+	ActionEvacuateRunningActualLRP          = "evacuate-running-actual-lrp"
+	ActionEvacuateStoppedActualLRP          = "evacuate-stopped-actual-lrp-and-delete-container"
+	ActionEvacuateCrashedActualLRP          = "evacuate-crashed-actual-lrp-and-delete-container"
+	ActionStartTaskAndRunContainer          = "start-task-and-run-container"
+	ActionCompleteTaskAndDeleteContainer    = "complete-task-and-delete-container"
+	ActionProcessContainer                  = "process-container"
+	ActionSkipContainer                     = "skip-container"
+	ActionRemoveActualLRP                   = "remove-actual-lrp"
+	ActionRemoveEvacuatingActualLRP         = "remove-evacuating-actual-lrp"
+	ActionRemoveJointActualLRP              = "remove-actual-lrp-and-evacuating-actual-lrp"
+	ActionFailTask                          = "fail-task"
+)
+
+// ReconciliationEntry is a single guid that is out of sync between the
+// executor and the BBS, and the action a bulk sync would take for it.
+type ReconciliationEntry struct {
+	Guid        string `json:"guid"`
+	ProcessGuid string `json:"process_guid,omitempty"`
+	Domain      string `json:"domain,omitempty"`
+	State       string `json:"state,omitempty"`
+	Action      string `json:"action"`
+}
+
+// ReconciliationReport lists what a bulk sync would act on without
+// generating or executing any operations.
+type ReconciliationReport struct {
+	ContainersWithoutBBSRecords []ReconciliationEntry `json:"containers_without_bbs_records"`
+	ActualLRPsWithoutContainers []ReconciliationEntry `json:"actual_lrps_without_containers"`
+	EvacuatingLeftovers         []ReconciliationEntry `json:"evacuating_leftovers"`
+	ResidualTasks               []ReconciliationEntry `json:"residual_tasks"`
+}
+
+func (g *generator) ReconciliationReport(logger lager.Logger) (ReconciliationReport, error) {
+	logger = logger.Session("reconciliation-report")
+	logger.Info("started")
+
+	observation, err := g.observe(logger)
+	if err != nil {
+		return ReconciliationReport{}, err
+	}
+
+	report := ReconciliationReport{
+		ContainersWithoutBBSRecords: []ReconciliationEntry{},
+		ActualLRPsWithoutContainers: []ReconciliationEntry{},
+		EvacuatingLeftovers:         []ReconciliationEntry{},
+		ResidualTasks:               []ReconciliationEntry{},
+	}
+
+	for guid, container := range observation.containers {
+		_, hasInstance := observation.instanceLRPs[guid]
+		_, hasEvacuating := observation.evacuatingLRPs[guid]
+		_, hasTask := observation.tasks[guid]
+		if hasInstance || hasEvacuating || hasTask {
+			continue
+		}
+
+		if g.lifecycles.CellManaged(container.Tags[rep.LifecycleTag]) {
+			continue
+		}
+
+		entry := ReconciliationEntry{
+			Guid:        guid,
+			ProcessGuid: container.Tags[rep.ProcessGuidTag],
+			Domain:      container.Tags[rep.DomainTag],
+			State:       string(container.State),
+			Action:      g.containerAction(container),
+		}
+		report.ContainersWithoutBBSRecords = append(report.ContainersWithoutBBSRecords, entry)
+	}
+
+	for guid, lrp := range observation.instanceLRPs {
+		if _, found := observation.containers[guid]; found {
+			continue
+		}
+
+		entry := ReconciliationEntry{
+			Guid:        guid,
+			ProcessGuid: lrp.ProcessGuid,
+			Domain:      lrp.Domain,
+			State:       lrp.State,
+		}
+		if _, found := observation.evacuatingLRPs[guid]; found {
+			entry.Action = ActionRemoveJointActualLRP
+			report.EvacuatingLeftovers = append(report.EvacuatingLeftovers, entry)
+		} else {
+			entry.Action = ActionRemoveActualLRP
+			report.ActualLRPsWithoutContainers = append(report.ActualLRPsWithoutContainers, entry)
+		}
+	}
+
+	for guid, lrp := range observation.evacuatingLRPs {
+		if _, found := observation.containers[guid]; found {
+			continue
+		}
+		if _, found := observation.instanceLRPs[guid]; found {
+			continue
+		}
+
+		report.EvacuatingLeftovers = append(report.EvacuatingLeftovers, ReconciliationEntry{
+			Guid:        guid,
+			ProcessGuid: lrp.ProcessGuid,
+			Domain:      lrp.Domain,
+			State:       lrp.State,
+			Action:      ActionRemoveEvacuatingActualLRP,
+		})
+	}
+
+	for guid, task := range observation.tasks {
+		if _, found := observation.containers[guid]; found {
+			continue
+		}
+
+		report.ResidualTasks = append(report.ResidualTasks, ReconciliationEntry{
+			Guid:   guid,
+			Domain: task.Domain,
+			State:  task.State.String(),
+			Action: ActionFailTask,
+		})
+	}
+
+	for _, entries := range [][]ReconciliationEntry{
+		report.ContainersWithoutBBSRecords,
+		report.ActualLRPsWithoutContainers,
+		report.EvacuatingLeftovers,
+		report.ResidualTasks,
+	} {
+		sortEntries(entries)
+	}
+
+	logger.Info("succeeded", lager.Data{
+		"containers-without-bbs-records": len(report.ContainersWithoutBBSRecords),
+		"actual-lrps-without-containers": len(report.ActualLRPsWithoutContainers),
+		"evacuating-leftovers":           len(report.EvacuatingLeftovers),
+		"residual-tasks":                 len(report.ResidualTasks),
+	})
+	return report, nil
+}
+
+// containerAction names what the lifecycle's processor would do with the
+// container in its current state.
+func (g *generator) containerAction(container executor.Container) string {
+	lifecycle := container.Tags[rep.LifecycleTag]
+	switch {
+	case lifecycle == rep.LRPLifecycle && g.lifecycles.Evacuated(lifecycle) && g.evacuationReporter.Evacuating():
+		return evacuationLRPAction(container)
+	case lifecycle == rep.LRPLifecycle:
+		return lrpAction(container)
+	case lifecycle == rep.TaskLifecycle:
+		return taskAction(container)
+	case g.lifecycles.Known(lifecycle):
+		return ActionProcessContainer
+	default:
+		return ActionSkipContainer
+	}
+}
+
+func lrpAction(container executor.Container) string {
+	switch container.State {
+	case executor.StateReserved:
+		return ActionClaimAndRunActualLRP
+	case executor.StateInitializing, executor.StateCreated:
+		return ActionClaimActualLRP
+	case executor.StateRunning:
+		return ActionStartActualLRP
+	case executor.StateCompleted:
+		if container.RunResult.Stopped {
+			return ActionRemoveActualLRPAndDeleteContainer
+		}
+		return ActionCrashActualLRPAndDeleteContainer
+	default:
+		return ActionSkipContainer
+	}
+}
+
+func evacuationLRPAction(container executor.Container) string {
+	switch container.State {
+	case executor.StateReserved, executor.StateInitializing, executor.StateCreated:
+		return ActionEvacuateClaimedActualLRP
+	case executor.StateRunning:
+		return ActionEvacuateRunningActualLRP
+	case executor.StateCompleted:
+		if container.RunResult.Stopped {
+			return ActionEvacuateStoppedActualLRP
+		}
+		return ActionEvacuateCrashedActualLRP
+	default:
+		return ActionSkipContainer
+	}
+}
+
+func taskAction(container executor.Container) string {
+	switch container.State {
+	case executor.StateReserved, executor.StateInitializing, executor.StateCreated, executor.StateRunning:
+		return ActionStartTaskAndRunContainer
+	case executor.StateCompleted:
+		return ActionCompleteTaskAndDeleteContainer
+	default:
+		return ActionSkipContainer
+	}
+}
+
+func sortEntries(entries []ReconciliationEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Guid < entries[j].Guid
+	})
+}
@@ -0,0 +1,132 @@
+package generator_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/executor"
+	efakes "code.cloudfoundry.org/executor/fakes"
+	"code.cloudfoundry.org/rep"
+	"code.cloudfoundry.org/rep/evacuation/evacuation_context/fake_evacuation_context"
+	"code.cloudfoundry.org/rep/generator"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReconciliationReport", func() {
+	const cellID = "some-cell-id"
+
+	var (
+		fakeExecutorClient     *efakes.FakeClient
+		fakeEvacuationReporter *fake_evacuation_context.FakeEvacuationReporter
+		opGenerator            generator.Generator
+
+		report    generator.ReconciliationReport
+		reportErr error
+	)
+
+	BeforeEach(func() {
+		fakeExecutorClient = new(efakes.FakeClient)
+		fakeEvacuationReporter = &fake_evacuation_context.FakeEvacuationReporter{}
+		opGenerator = generator.New(cellID, fakeBBS, fakeExecutorClient, nil, fakeEvacuationReporter, 0, nil, 0, nil, generator.RetryPolicy{}, generator.RetryPolicy{}, generator.ResultFilePolicy{}, generator.CrashDiagnosticsPolicy{}, nil, nil, false, 0)
+
+		lrpKey := models.NewActualLRPKey("process-guid", 0, "domain")
+		newLRP := func(instanceGuid string) *models.ActualLRP {
+			return &models.ActualLRP{
+				ActualLRPKey:         lrpKey,
+				ActualLRPInstanceKey: models.NewActualLRPInstanceKey(instanceGuid, cellID),
+				State:                models.ActualLRPStateRunning,
+			}
+		}
+
+		fakeExecutorClient.ListContainersReturns([]executor.Container{
+			{Guid: "lrp-with-record"},
+			{Guid: "lrp-without-record", State: executor.StateRunning, Tags: executor.Tags{
+				rep.LifecycleTag:   rep.LRPLifecycle,
+				rep.ProcessGuidTag: "other-process-guid",
+				rep.DomainTag:      "domain",
+			}},
+			{Guid: "crashed-lrp-without-record", State: executor.StateCompleted, Tags: executor.Tags{rep.LifecycleTag: rep.LRPLifecycle}},
+			{Guid: "task-without-record", State: executor.StateCompleted, Tags: executor.Tags{rep.LifecycleTag: rep.TaskLifecycle}},
+			{Guid: "canary-guid", Tags: executor.Tags{rep.LifecycleTag: rep.CanaryLifecycle}},
+			{Guid: "unknown-guid", Tags: executor.Tags{rep.LifecycleTag: "banana"}},
+		}, nil)
+
+		fakeBBS.ActualLRPGroupsReturns([]*models.ActualLRPGroup{
+			{Instance: newLRP("lrp-with-record")},
+			{Instance: newLRP("instance-only")},
+			{Instance: newLRP("joint"), Evacuating: newLRP("joint")},
+			{Evacuating: newLRP("evacuating-only")},
+		}, nil)
+
+		fakeBBS.TasksByCellIDReturns([]*models.Task{
+			{TaskGuid: "residual-task", Domain: "domain", State: models.Task_Running},
+		}, nil)
+	})
+
+	JustBeforeEach(func() {
+		report, reportErr = opGenerator.ReconciliationReport(logger)
+	})
+
+	It("reports containers without BBS records with the action for their state", func() {
+		Expect(reportErr).NotTo(HaveOccurred())
+		Expect(report.ContainersWithoutBBSRecords).To(Equal([]generator.ReconciliationEntry{
+			{Guid: "crashed-lrp-without-record", State: string(executor.StateCompleted), Action: generator.ActionCrashActualLRPAndDeleteContainer},
+			{Guid: "lrp-without-record", ProcessGuid: "other-process-guid", Domain: "domain", State: string(executor.StateRunning), Action: generator.ActionStartActualLRP},
+			{Guid: "task-without-record", State: string(executor.StateCompleted), Action: generator.ActionCompleteTaskAndDeleteContainer},
+			{Guid: "unknown-guid", Action: generator.ActionSkipContainer},
+		}))
+	})
+
+	Context("when the cell is evacuating", func() {
+		BeforeEach(func() {
+			fakeEvacuationReporter.EvacuatingReturns(true)
+		})
+
+		It("reports the evacuation action for LRP containers", func() {
+			Expect(report.ContainersWithoutBBSRecords).To(ContainElement(generator.ReconciliationEntry{
+				Guid: "lrp-without-record", ProcessGuid: "other-process-guid", Domain: "domain", State: string(executor.StateRunning), Action: generator.ActionEvacuateRunningActualLRP,
+			}))
+			Expect(report.ContainersWithoutBBSRecords).To(ContainElement(generator.ReconciliationEntry{
+				Guid: "crashed-lrp-without-record", State: string(executor.StateCompleted), Action: generator.ActionEvacuateCrashedActualLRP,
+			}))
+		})
+	})
+
+	It("reports ActualLRPs without containers", func() {
+		Expect(report.ActualLRPsWithoutContainers).To(Equal([]generator.ReconciliationEntry{
+			{Guid: "instance-only", ProcessGuid: "process-guid", Domain: "domain", State: models.ActualLRPStateRunning, Action: generator.ActionRemoveActualLRP},
+		}))
+	})
+
+	It("reports evacuating leftovers", func() {
+		Expect(report.EvacuatingLeftovers).To(Equal([]generator.ReconciliationEntry{
+			{Guid: "evacuating-only", ProcessGuid: "process-guid", Domain: "domain", State: models.ActualLRPStateRunning, Action: generator.ActionRemoveEvacuatingActualLRP},
+			{Guid: "joint", ProcessGuid: "process-guid", Domain: "domain", State: models.ActualLRPStateRunning, Action: generator.ActionRemoveJointActualLRP},
+		}))
+	})
+
+	It("reports residual tasks", func() {
+		Expect(report.ResidualTasks).To(Equal([]generator.ReconciliationEntry{
+			{Guid: "residual-task", Domain: "domain", State: models.Task_Running.String(), Action: generator.ActionFailTask},
+		}))
+	})
+
+	It("does not act on anything", func() {
+		Expect(fakeBBS.RemoveActualLRPCallCount()).To(Equal(0))
+		Expect(fakeBBS.RemoveEvacuatingActualLRPCallCount()).To(Equal(0))
+		Expect(fakeBBS.CompleteTaskCallCount()).To(Equal(0))
+		Expect(fakeExecutorClient.GetContainerCallCount()).To(Equal(0))
+	})
+
+	Context("when listing containers fails", func() {
+		BeforeEach(func() {
+			fakeExecutorClient.ListContainersReturns(nil, errors.New("boom"))
+		})
+
+		It("returns the error", func() {
+			Expect(reportErr).To(MatchError(ContainSubstring("boom")))
+		})
+	})
+})
@@ -2,18 +2,32 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
+	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/executor"
 	"code.cloudfoundry.org/lager"
 )
 
+// CancelTaskPollInterval is how often a stopped task container is checked
+// for completion during the grace period.
+const CancelTaskPollInterval = 500 * time.Millisecond
+
+// CancelTaskHandler stops the task's container, giving it the grace period to
+// complete before deleting it. A grace_period query parameter, as sent by
+// rep.Client.CancelTaskWithGracePeriod, overrides the default for a single
+// request; a zero grace period deletes the container right away.
 type CancelTaskHandler struct {
 	executorClient executor.Client
+	clock          clock.Clock
+	gracePeriod    time.Duration
 }
 
-func NewCancelTaskHandler(executorClient executor.Client) *CancelTaskHandler {
+func NewCancelTaskHandler(executorClient executor.Client, clock clock.Clock, gracePeriod time.Duration) *CancelTaskHandler {
 	return &CancelTaskHandler{
 		executorClient: executorClient,
+		clock:          clock,
+		gracePeriod:    gracePeriod,
 	}
 }
 
@@ -24,9 +38,25 @@ func (h CancelTaskHandler) ServeHTTP(w http.ResponseWriter, r *http.Request, log
 		"instance-guid": taskGuid,
 	})
 
+	gracePeriod := h.gracePeriod
+	if value := r.FormValue("grace_period"); value != "" {
+		var err error
+		gracePeriod, err = time.ParseDuration(value)
+		if err != nil || gracePeriod < 0 {
+			logger.Info("invalid-grace-period", lager.Data{"grace-period": value})
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	logger = logger.WithData(lager.Data{"grace-period": gracePeriod.String()})
+
 	w.WriteHeader(http.StatusAccepted)
 
 	go func() {
+		if gracePeriod > 0 && !h.stopContainer(logger, taskGuid, gracePeriod) {
+			return
+		}
+
 		logger.Info("deleting-container")
 		err := h.executorClient.DeleteContainer(logger, taskGuid)
 		if err == executor.ErrContainerNotFound {
@@ -42,3 +72,46 @@ func (h CancelTaskHandler) ServeHTTP(w http.ResponseWriter, r *http.Request, log
 		logger.Info("succeeded-deleting-container")
 	}()
 }
+
+// stopContainer signals the container and waits for it to complete. It
+// returns false when the container is already gone.
+func (h CancelTaskHandler) stopContainer(logger lager.Logger, taskGuid string, gracePeriod time.Duration) bool {
+	logger.Info("stopping-container")
+	err := h.executorClient.StopContainer(logger, taskGuid)
+	if err == executor.ErrContainerNotFound {
+		logger.Info("container-not-found")
+		return false
+	}
+
+	if err != nil {
+		logger.Error("failed-stopping-container", err)
+		return true
+	}
+
+	logger.Info("succeeded-stopping-container")
+
+	timer := h.clock.NewTimer(gracePeriod)
+	defer timer.Stop()
+
+	ticker := h.clock.NewTicker(CancelTaskPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			container, err := h.executorClient.GetContainer(logger, taskGuid)
+			if err == executor.ErrContainerNotFound {
+				logger.Info("container-not-found")
+				return false
+			}
+
+			if err == nil && container.State == executor.StateCompleted {
+				logger.Info("container-completed-within-grace-period")
+				return true
+			}
+		case <-timer.C():
+			logger.Info("grace-period-expired")
+			return true
+		}
+	}
+}
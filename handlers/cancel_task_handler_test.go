@@ -0,0 +1,141 @@
+package handlers_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/executor"
+	executorfakes "code.cloudfoundry.org/executor/fakes"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/rep/handlers"
+	"github.com/onsi/gomega/gbytes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CancelTaskHandler", func() {
+	const gracePeriod = 10 * time.Second
+
+	var (
+		cancelTaskHandler *handlers.CancelTaskHandler
+		fakeClient        *executorfakes.FakeClient
+		fakeClock         *fakeclock.FakeClock
+		resp              *httptest.ResponseRecorder
+		req               *http.Request
+		logger            *lagertest.TestLogger
+		values            url.Values
+	)
+
+	BeforeEach(func() {
+		var err error
+		fakeClient = &executorfakes.FakeClient{}
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+
+		logger = lagertest.NewTestLogger("test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+
+		cancelTaskHandler = handlers.NewCancelTaskHandler(fakeClient, fakeClock, gracePeriod)
+
+		resp = httptest.NewRecorder()
+
+		req, err = http.NewRequest("POST", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		values = make(url.Values)
+		values.Set(":task_guid", "task-guid")
+
+		fakeClient.GetContainerReturns(executor.Container{State: executor.StateRunning}, nil)
+	})
+
+	JustBeforeEach(func() {
+		req.URL.RawQuery = values.Encode()
+		cancelTaskHandler.ServeHTTP(resp, req, logger)
+	})
+
+	It("responds with 202 Accepted", func() {
+		Expect(resp.Code).To(Equal(http.StatusAccepted))
+	})
+
+	It("stops the container before deleting it", func() {
+		Eventually(fakeClient.StopContainerCallCount).Should(Equal(1))
+		_, guid := fakeClient.StopContainerArgsForCall(0)
+		Expect(guid).To(Equal("task-guid"))
+		Consistently(fakeClient.DeleteContainerCallCount).Should(Equal(0))
+	})
+
+	Context("when the container completes within the grace period", func() {
+		BeforeEach(func() {
+			fakeClient.GetContainerReturns(executor.Container{State: executor.StateCompleted}, nil)
+		})
+
+		It("deletes the container without waiting for the grace period", func() {
+			Eventually(fakeClock.WatcherCount).Should(Equal(2))
+			fakeClock.Increment(handlers.CancelTaskPollInterval)
+
+			Eventually(fakeClient.DeleteContainerCallCount).Should(Equal(1))
+			_, guid := fakeClient.DeleteContainerArgsForCall(0)
+			Expect(guid).To(Equal("task-guid"))
+			Expect(logger).To(gbytes.Say("container-completed-within-grace-period"))
+		})
+	})
+
+	Context("when the grace period expires", func() {
+		It("deletes the container", func() {
+			Eventually(fakeClock.WatcherCount).Should(Equal(2))
+			fakeClock.Increment(gracePeriod)
+
+			Eventually(fakeClient.DeleteContainerCallCount).Should(Equal(1))
+			Expect(logger).To(gbytes.Say("grace-period-expired"))
+		})
+	})
+
+	Context("when stopping the container fails", func() {
+		BeforeEach(func() {
+			fakeClient.StopContainerReturns(errors.New("boom"))
+		})
+
+		It("deletes the container right away", func() {
+			Eventually(fakeClient.DeleteContainerCallCount).Should(Equal(1))
+			Expect(logger).To(gbytes.Say("failed-stopping-container"))
+		})
+	})
+
+	Context("when the container does not exist", func() {
+		BeforeEach(func() {
+			fakeClient.StopContainerReturns(executor.ErrContainerNotFound)
+		})
+
+		It("does not try to delete it", func() {
+			Eventually(logger).Should(gbytes.Say("container-not-found"))
+			Consistently(fakeClient.DeleteContainerCallCount).Should(Equal(0))
+		})
+	})
+
+	Context("when the request supplies a grace period", func() {
+		BeforeEach(func() {
+			values.Set("grace_period", "0s")
+		})
+
+		It("uses it instead of the default", func() {
+			Eventually(fakeClient.DeleteContainerCallCount).Should(Equal(1))
+			Expect(fakeClient.StopContainerCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the request supplies an invalid grace period", func() {
+		BeforeEach(func() {
+			values.Set("grace_period", "soon")
+		})
+
+		It("responds with 400 Bad Request", func() {
+			Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			Consistently(fakeClient.StopContainerCallCount).Should(Equal(0))
+		})
+	})
+})
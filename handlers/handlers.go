@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
+	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/executor"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/rep"
@@ -16,6 +18,12 @@ func New(
 	localMetricCollector MetricCollector,
 	executorClient executor.Client,
 	evacuatable evacuation_context.Evacuatable,
+	healthChecks HealthChecks,
+	reconciliationReporter ReconciliationReporter,
+	operationHistory OperationHistory,
+	bulkSyncer BulkSyncer,
+	cancelTaskGracePeriod time.Duration,
+	clock clock.Clock,
 	logger lager.Logger,
 	secure bool,
 ) rata.Handlers {
@@ -27,7 +35,7 @@ func New(
 		performHandler := &perform{rep: localCellClient}
 		resetHandler := &reset{rep: localCellClient}
 		stopLrpHandler := NewStopLRPInstanceHandler(executorClient)
-		cancelTaskHandler := NewCancelTaskHandler(executorClient)
+		cancelTaskHandler := NewCancelTaskHandler(executorClient, clock, cancelTaskGracePeriod)
 
 		handlers[rep.StateRoute] = logWrap(stateHandler.ServeHTTP, logger)
 		handlers[rep.ContainerMetricsRoute] = logWrap(containerMetricsHandler.ServeHTTP, logger)
@@ -39,9 +47,17 @@ func New(
 	} else {
 		pingHandler := NewPingHandler()
 		evacuationHandler := NewEvacuationHandler(evacuatable)
+		healthHandler := NewHealthHandler(healthChecks, clock, DefaultHealthCheckTimeout)
+		reconciliationHandler := NewReconciliationHandler(reconciliationReporter)
+		operationHistoryHandler := NewOperationHistoryHandler(operationHistory)
+		syncHandler := NewSyncHandler(bulkSyncer)
 
 		handlers[rep.PingRoute] = logWrap(pingHandler.ServeHTTP, logger)
 		handlers[rep.EvacuateRoute] = logWrap(evacuationHandler.ServeHTTP, logger)
+		handlers[rep.HealthRoute] = logWrap(healthHandler.ServeHTTP, logger)
+		handlers[rep.ReconciliationReportRoute] = logWrap(reconciliationHandler.ServeHTTP, logger)
+		handlers[rep.OperationHistoryRoute] = logWrap(operationHistoryHandler.ServeHTTP, logger)
+		handlers[rep.SyncRoute] = logWrap(syncHandler.ServeHTTP, logger)
 	}
 
 	return handlers
@@ -58,8 +74,8 @@ func NewLegacy(
 	evacuatable evacuation_context.Evacuatable,
 	logger lager.Logger,
 ) rata.Handlers {
-	insecureHandlers := New(localCellClient, localMetricCollector, executorClient, evacuatable, logger, false)
-	secureHandlers := New(localCellClient, localMetricCollector, executorClient, evacuatable, logger, true)
+	insecureHandlers := New(localCellClient, localMetricCollector, executorClient, evacuatable, nil, nil, nil, nil, 0, clock.NewClock(), logger, false)
+	secureHandlers := New(localCellClient, localMetricCollector, executorClient, evacuatable, nil, nil, nil, nil, 0, clock.NewClock(), logger, true)
 	for name, handler := range secureHandlers {
 		insecureHandlers[name] = handler
 	}
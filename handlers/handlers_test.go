@@ -1,6 +1,7 @@
 package handlers_test
 
 import (
+	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/rep"
 	"code.cloudfoundry.org/rep/evacuation/evacuation_context/fake_evacuation_context"
 	"code.cloudfoundry.org/rep/handlers"
@@ -19,7 +20,7 @@ var _ = Describe("New", func() {
 		BeforeEach(func() {
 			fakeExecutorClient := new(executorfakes.FakeClient)
 			fakeEvacuatable := new(fake_evacuation_context.FakeEvacuatable)
-			test_handlers = handlers.New(fakeLocalRep, fakeMetricCollector, fakeExecutorClient, fakeEvacuatable, logger, false)
+			test_handlers = handlers.New(fakeLocalRep, fakeMetricCollector, fakeExecutorClient, fakeEvacuatable, nil, nil, nil, nil, 0, clock.NewClock(), logger, false)
 		})
 
 		It("has no secure routes", func() {
@@ -39,7 +40,7 @@ var _ = Describe("New", func() {
 		BeforeEach(func() {
 			fakeExecutorClient := new(executorfakes.FakeClient)
 			fakeEvacuatable := new(fake_evacuation_context.FakeEvacuatable)
-			test_handlers = handlers.New(fakeLocalRep, fakeMetricCollector, fakeExecutorClient, fakeEvacuatable, logger, true)
+			test_handlers = handlers.New(fakeLocalRep, fakeMetricCollector, fakeExecutorClient, fakeEvacuatable, nil, nil, nil, nil, 0, clock.NewClock(), logger, true)
 		})
 
 		It("has all the secure routes", func() {
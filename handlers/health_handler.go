@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/rep"
+)
+
+type ComponentHealth struct {
+	Healthy     bool       `json:"healthy"`
+	Error       string     `json:"error,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+}
+
+type HealthReport struct {
+	Healthy    bool                       `json:"healthy"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+type HealthCheck interface {
+	Check(logger lager.Logger) ComponentHealth
+}
+
+type HealthCheckFunc func(logger lager.Logger) ComponentHealth
+
+func (f HealthCheckFunc) Check(logger lager.Logger) ComponentHealth {
+	return f(logger)
+}
+
+// HealthChecks maps a component name to the check reporting its health.
+type HealthChecks map[string]HealthCheck
+
+// DefaultHealthCheckTimeout bounds how long the health handler waits for the
+// checks.
+const DefaultHealthCheckTimeout = 5 * time.Second
+
+type HealthHandler struct {
+	checks  HealthChecks
+	clock   clock.Clock
+	timeout time.Duration
+}
+
+type healthCheckResult struct {
+	name   string
+	health ComponentHealth
+}
+
+// NewHealthHandler serves a route that reports the health of each of the
+// rep's dependencies, used by monitoring and the rep drain script. Checks that
+// have not finished within the timeout are reported as unhealthy.
+func NewHealthHandler(checks HealthChecks, clock clock.Clock, timeout time.Duration) *HealthHandler {
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+
+	return &HealthHandler{
+		checks:  checks,
+		clock:   clock,
+		timeout: timeout,
+	}
+}
+
+func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request, logger lager.Logger) {
+	logger = logger.Session("health-handler")
+
+	report := HealthReport{
+		Healthy:    true,
+		Components: make(map[string]ComponentHealth, len(h.checks)),
+	}
+
+	results := make(chan healthCheckResult, len(h.checks))
+	for name, check := range h.checks {
+		go func(name string, check HealthCheck) {
+			health := check.Check(logger.Session("check", lager.Data{"component": name}))
+			results <- healthCheckResult{name: name, health: health}
+		}(name, check)
+	}
+
+	timer := h.clock.NewTimer(h.timeout)
+	defer timer.Stop()
+
+collect:
+	for range h.checks {
+		select {
+		case result := <-results:
+			report.Components[result.name] = result.health
+		case <-timer.C():
+			for name := range h.checks {
+				if _, ok := report.Components[name]; !ok {
+					logger.Info("health-check-timed-out", lager.Data{"component": name, "timeout": h.timeout.String()})
+					report.Components[name] = componentHealth(ErrHealthCheckTimedOut)
+				}
+			}
+			break collect
+		}
+	}
+
+	for _, health := range report.Components {
+		if !health.Healthy {
+			report.Healthy = false
+		}
+	}
+
+	jsonBytes, err := json.Marshal(report)
+	if err != nil {
+		logger.Error("failed-to-marshal-health-report", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Healthy {
+		logger.Info("cell-not-healthy", lager.Data{"report": report})
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(jsonBytes)
+}
+
+type BBSPinger interface {
+	Ping(logger lager.Logger) bool
+}
+
+type PresenceStatus interface {
+	PresenceHeld() bool
+}
+
+type SyncStatus interface {
+	LastSuccessfulSync() time.Time
+}
+
+type EventStreamStatus interface {
+	EventStreamConnected() bool
+}
+
+type CanaryStatus interface {
+	LastResult() *rep.CanaryResult
+}
+
+var (
+	ErrGardenUnhealthy         = errors.New("garden healthcheck failed")
+	ErrBBSUnreachable          = errors.New("bbs is not reachable")
+	ErrPresenceNotHeld         = errors.New("cell presence is not held")
+	ErrNoSuccessfulSync        = errors.New("no successful bulk sync")
+	ErrBulkSyncStale           = errors.New("last successful bulk sync is too old")
+	ErrEventStreamDisconnected = errors.New("executor event stream is not connected")
+	ErrCanaryFailing           = errors.New("canary container is failing")
+	ErrHealthCheckTimedOut     = errors.New("health check timed out")
+)
+
+func ExecutorPingCheck(executorClient executor.Client) HealthCheck {
+	return HealthCheckFunc(func(logger lager.Logger) ComponentHealth {
+		return componentHealth(executorClient.Ping(logger))
+	})
+}
+
+func GardenHealthCheck(executorClient executor.Client) HealthCheck {
+	return HealthCheckFunc(func(logger lager.Logger) ComponentHealth {
+		if !executorClient.Healthy(logger) {
+			return componentHealth(ErrGardenUnhealthy)
+		}
+		return componentHealth(nil)
+	})
+}
+
+func BBSPingCheck(bbsClient BBSPinger) HealthCheck {
+	return HealthCheckFunc(func(logger lager.Logger) ComponentHealth {
+		if !bbsClient.Ping(logger) {
+			return componentHealth(ErrBBSUnreachable)
+		}
+		return componentHealth(nil)
+	})
+}
+
+func PresenceCheck(presence PresenceStatus) HealthCheck {
+	return HealthCheckFunc(func(logger lager.Logger) ComponentHealth {
+		if !presence.PresenceHeld() {
+			return componentHealth(ErrPresenceNotHeld)
+		}
+		return componentHealth(nil)
+	})
+}
+
+// BulkSyncCheck is healthy while the last successful bulk sync happened less
+// than maxAge ago.
+func BulkSyncCheck(syncStatus SyncStatus, clock clock.Clock, maxAge time.Duration) HealthCheck {
+	return HealthCheckFunc(func(logger lager.Logger) ComponentHealth {
+		lastSync := syncStatus.LastSuccessfulSync()
+		if lastSync.IsZero() {
+			return componentHealth(ErrNoSuccessfulSync)
+		}
+
+		var health ComponentHealth
+		if clock.Since(lastSync) > maxAge {
+			health = componentHealth(ErrBulkSyncStale)
+		} else {
+			health = componentHealth(nil)
+		}
+		health.LastSuccess = &lastSync
+		return health
+	})
+}
+
+func EventStreamCheck(eventStream EventStreamStatus) HealthCheck {
+	return HealthCheckFunc(func(logger lager.Logger) ComponentHealth {
+		if !eventStream.EventStreamConnected() {
+			return componentHealth(ErrEventStreamDisconnected)
+		}
+		return componentHealth(nil)
+	})
+}
+
+// CanaryCheck is healthy until the canary has failed enough times in a row to
+// mark the cell unhealthy.
+func CanaryCheck(canary CanaryStatus) HealthCheck {
+	return HealthCheckFunc(func(logger lager.Logger) ComponentHealth {
+		result := canary.LastResult()
+		if result != nil && !result.Healthy {
+			return componentHealth(ErrCanaryFailing)
+		}
+		return componentHealth(nil)
+	})
+}
+
+func componentHealth(err error) ComponentHealth {
+	if err != nil {
+		return ComponentHealth{Healthy: false, Error: err.Error()}
+	}
+	return ComponentHealth{Healthy: true}
+}
@@ -0,0 +1,221 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	executorfakes "code.cloudfoundry.org/executor/fakes"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/rep"
+	"code.cloudfoundry.org/rep/handlers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeSyncStatus struct {
+	lastSync time.Time
+}
+
+func (f fakeSyncStatus) LastSuccessfulSync() time.Time {
+	return f.lastSync
+}
+
+type fakeCanaryStatus struct {
+	result *rep.CanaryResult
+}
+
+func (f fakeCanaryStatus) LastResult() *rep.CanaryResult {
+	return f.result
+}
+
+var _ = Describe("HealthHandler", func() {
+	const timeout = 5 * time.Second
+
+	var (
+		logger    *lagertest.TestLogger
+		fakeClock *fakeclock.FakeClock
+		checks    handlers.HealthChecks
+
+		responseRecorder *httptest.ResponseRecorder
+		report           handlers.HealthReport
+	)
+
+	healthy := handlers.HealthCheckFunc(func(lager.Logger) handlers.ComponentHealth {
+		return handlers.ComponentHealth{Healthy: true}
+	})
+
+	unhealthy := handlers.HealthCheckFunc(func(lager.Logger) handlers.ComponentHealth {
+		return handlers.ComponentHealth{Healthy: false, Error: "broken"}
+	})
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		responseRecorder = httptest.NewRecorder()
+		checks = handlers.HealthChecks{
+			"executor": healthy,
+			"bbs":      healthy,
+		}
+	})
+
+	JustBeforeEach(func() {
+		request, err := http.NewRequest("GET", "/health", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		handlers.NewHealthHandler(checks, fakeClock, timeout).ServeHTTP(responseRecorder, request, logger)
+
+		report = handlers.HealthReport{}
+		Expect(json.Unmarshal(responseRecorder.Body.Bytes(), &report)).To(Succeed())
+	})
+
+	Context("when every component is healthy", func() {
+		It("responds with 200 and a healthy report", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+			Expect(report.Healthy).To(BeTrue())
+			Expect(report.Components).To(Equal(map[string]handlers.ComponentHealth{
+				"executor": {Healthy: true},
+				"bbs":      {Healthy: true},
+			}))
+		})
+	})
+
+	Context("when a component is unhealthy", func() {
+		BeforeEach(func() {
+			checks["bbs"] = unhealthy
+		})
+
+		It("responds with 503 and reports which component failed", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(report.Healthy).To(BeFalse())
+			Expect(report.Components["executor"]).To(Equal(handlers.ComponentHealth{Healthy: true}))
+			Expect(report.Components["bbs"]).To(Equal(handlers.ComponentHealth{Healthy: false, Error: "broken"}))
+		})
+	})
+
+	Context("when a check does not finish in time", func() {
+		var release chan struct{}
+
+		BeforeEach(func() {
+			release = make(chan struct{})
+			checks["garden"] = handlers.HealthCheckFunc(func(lager.Logger) handlers.ComponentHealth {
+				<-release
+				return handlers.ComponentHealth{Healthy: true}
+			})
+
+			go func() {
+				defer GinkgoRecover()
+				Eventually(fakeClock.WatcherCount).Should(Equal(1))
+				fakeClock.Increment(timeout)
+			}()
+		})
+
+		AfterEach(func() {
+			close(release)
+		})
+
+		It("responds with 503 and reports the check as timed out", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(report.Healthy).To(BeFalse())
+			Expect(report.Components["executor"]).To(Equal(handlers.ComponentHealth{Healthy: true}))
+			Expect(report.Components["garden"]).To(Equal(handlers.ComponentHealth{
+				Healthy: false,
+				Error:   handlers.ErrHealthCheckTimedOut.Error(),
+			}))
+		})
+	})
+
+	Context("when there are no checks", func() {
+		BeforeEach(func() {
+			checks = nil
+		})
+
+		It("reports healthy", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+			Expect(report.Healthy).To(BeTrue())
+			Expect(report.Components).To(BeEmpty())
+		})
+	})
+})
+
+var _ = Describe("Health checks", func() {
+	var logger *lagertest.TestLogger
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+	})
+
+	Describe("ExecutorPingCheck", func() {
+		It("reports the executor ping error", func() {
+			fakeExecutorClient := new(executorfakes.FakeClient)
+			Expect(handlers.ExecutorPingCheck(fakeExecutorClient).Check(logger).Healthy).To(BeTrue())
+
+			fakeExecutorClient.PingReturns(errors.New("no executor"))
+			Expect(handlers.ExecutorPingCheck(fakeExecutorClient).Check(logger)).To(Equal(handlers.ComponentHealth{
+				Healthy: false,
+				Error:   "no executor",
+			}))
+		})
+	})
+
+	Describe("GardenHealthCheck", func() {
+		It("reports the executor's garden health", func() {
+			fakeExecutorClient := new(executorfakes.FakeClient)
+			Expect(handlers.GardenHealthCheck(fakeExecutorClient).Check(logger).Healthy).To(BeFalse())
+
+			fakeExecutorClient.HealthyReturns(true)
+			Expect(handlers.GardenHealthCheck(fakeExecutorClient).Check(logger).Healthy).To(BeTrue())
+		})
+	})
+
+	Describe("BulkSyncCheck", func() {
+		var fakeClock *fakeclock.FakeClock
+
+		BeforeEach(func() {
+			fakeClock = fakeclock.NewFakeClock(time.Now())
+		})
+
+		Context("when no sync has succeeded", func() {
+			It("is unhealthy", func() {
+				health := handlers.BulkSyncCheck(fakeSyncStatus{}, fakeClock, time.Minute).Check(logger)
+				Expect(health.Healthy).To(BeFalse())
+				Expect(health.Error).To(Equal(handlers.ErrNoSuccessfulSync.Error()))
+			})
+		})
+
+		Context("when the last sync is recent", func() {
+			It("is healthy and reports the last sync time", func() {
+				lastSync := fakeClock.Now().Add(-30 * time.Second)
+				health := handlers.BulkSyncCheck(fakeSyncStatus{lastSync}, fakeClock, time.Minute).Check(logger)
+				Expect(health.Healthy).To(BeTrue())
+				Expect(*health.LastSuccess).To(Equal(lastSync))
+			})
+		})
+
+		Context("when the last sync is too old", func() {
+			It("is unhealthy and reports the last sync time", func() {
+				lastSync := fakeClock.Now().Add(-2 * time.Minute)
+				health := handlers.BulkSyncCheck(fakeSyncStatus{lastSync}, fakeClock, time.Minute).Check(logger)
+				Expect(health.Healthy).To(BeFalse())
+				Expect(health.Error).To(Equal(handlers.ErrBulkSyncStale.Error()))
+				Expect(*health.LastSuccess).To(Equal(lastSync))
+			})
+		})
+	})
+
+	Describe("CanaryCheck", func() {
+		It("is healthy until the canary reports the cell unhealthy", func() {
+			Expect(handlers.CanaryCheck(fakeCanaryStatus{}).Check(logger).Healthy).To(BeTrue())
+			Expect(handlers.CanaryCheck(fakeCanaryStatus{&rep.CanaryResult{Healthy: true}}).Check(logger).Healthy).To(BeTrue())
+
+			health := handlers.CanaryCheck(fakeCanaryStatus{&rep.CanaryResult{Healthy: false}}).Check(logger)
+			Expect(health.Healthy).To(BeFalse())
+			Expect(health.Error).To(Equal(handlers.ErrCanaryFailing.Error()))
+		})
+	})
+})
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/rep/history"
+)
+
+type OperationHistory interface {
+	All() []history.Entry
+	ByGuid(guid string) []history.Entry
+}
+
+type OperationHistoryHandler struct {
+	history OperationHistory
+}
+
+// Operation History Handler serves the most recently executed operations,
+// optionally only those for the container guid given in the guid parameter
+func NewOperationHistoryHandler(history OperationHistory) *OperationHistoryHandler {
+	return &OperationHistoryHandler{
+		history: history,
+	}
+}
+
+func (h *OperationHistoryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request, logger lager.Logger) {
+	logger = logger.Session("operation-history-handler")
+
+	if h.history == nil {
+		logger.Info("operation-history-unavailable")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var entries []history.Entry
+	if guid := r.URL.Query().Get("guid"); guid != "" {
+		entries = h.history.ByGuid(guid)
+	} else {
+		entries = h.history.All()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
@@ -0,0 +1,76 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/rep/handlers"
+	"code.cloudfoundry.org/rep/history"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OperationHistoryHandler", func() {
+	var (
+		logger           *lagertest.TestLogger
+		operationHistory handlers.OperationHistory
+		path             string
+		responseRecorder *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		path = "/operations"
+		responseRecorder = httptest.NewRecorder()
+
+		h := history.New(10, fakeclock.NewFakeClock(time.Now()))
+		for _, guid := range []string{"guid-1", "guid-2"} {
+			_, recording := h.Start(logger, "container", guid)
+			recording.Finish()
+		}
+		operationHistory = h
+	})
+
+	JustBeforeEach(func() {
+		request, err := http.NewRequest("GET", path, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		handlers.NewOperationHistoryHandler(operationHistory).ServeHTTP(responseRecorder, request, logger)
+	})
+
+	It("responds with every recorded operation", func() {
+		Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+		var entries []history.Entry
+		Expect(json.Unmarshal(responseRecorder.Body.Bytes(), &entries)).To(Succeed())
+		Expect(entries).To(HaveLen(2))
+	})
+
+	Context("when a guid is given", func() {
+		BeforeEach(func() {
+			path = "/operations?guid=guid-2"
+		})
+
+		It("responds with the operations for the guid", func() {
+			var entries []history.Entry
+			Expect(json.Unmarshal(responseRecorder.Body.Bytes(), &entries)).To(Succeed())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Guid).To(Equal("guid-2"))
+		})
+	})
+
+	Context("when there is no history", func() {
+		BeforeEach(func() {
+			operationHistory = nil
+		})
+
+		It("responds with 503", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusServiceUnavailable))
+		})
+	})
+})
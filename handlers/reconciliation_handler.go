@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/rep/generator"
+)
+
+type ReconciliationReporter interface {
+	ReconciliationReport(logger lager.Logger) (generator.ReconciliationReport, error)
+}
+
+type ReconciliationHandler struct {
+	reporter ReconciliationReporter
+}
+
+// Reconciliation Handler serves a route that reports what the next bulk sync
+// would act on, without acting on it
+func NewReconciliationHandler(reporter ReconciliationReporter) *ReconciliationHandler {
+	return &ReconciliationHandler{
+		reporter: reporter,
+	}
+}
+
+func (h *ReconciliationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request, logger lager.Logger) {
+	logger = logger.Session("reconciliation-handler")
+
+	if h.reporter == nil {
+		logger.Info("reconciliation-report-unavailable")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	report, err := h.reporter.ReconciliationReport(logger)
+	if err != nil {
+		logger.Error("failed-to-generate-reconciliation-report", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,80 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/rep/generator"
+	"code.cloudfoundry.org/rep/generator/fake_generator"
+	"code.cloudfoundry.org/rep/handlers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReconciliationHandler", func() {
+	var (
+		logger           *lagertest.TestLogger
+		fakeGenerator    *fake_generator.FakeGenerator
+		reporter         handlers.ReconciliationReporter
+		responseRecorder *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeGenerator = new(fake_generator.FakeGenerator)
+		reporter = fakeGenerator
+		responseRecorder = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		request, err := http.NewRequest("GET", "/reconciliation", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		handlers.NewReconciliationHandler(reporter).ServeHTTP(responseRecorder, request, logger)
+	})
+
+	Context("when the report is generated", func() {
+		var expectedReport generator.ReconciliationReport
+
+		BeforeEach(func() {
+			expectedReport = generator.ReconciliationReport{
+				ActualLRPsWithoutContainers: []generator.ReconciliationEntry{
+					{Guid: "some-guid", ProcessGuid: "some-process-guid", Action: generator.ActionRemoveActualLRP},
+				},
+			}
+			fakeGenerator.ReconciliationReportReturns(expectedReport, nil)
+		})
+
+		It("responds with the report", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+			var report generator.ReconciliationReport
+			Expect(json.Unmarshal(responseRecorder.Body.Bytes(), &report)).To(Succeed())
+			Expect(report).To(Equal(expectedReport))
+		})
+	})
+
+	Context("when generating the report fails", func() {
+		BeforeEach(func() {
+			fakeGenerator.ReconciliationReportReturns(generator.ReconciliationReport{}, errors.New("boom"))
+		})
+
+		It("responds with 500", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusInternalServerError))
+		})
+	})
+
+	Context("when there is no reporter", func() {
+		BeforeEach(func() {
+			reporter = nil
+		})
+
+		It("responds with 503", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusServiceUnavailable))
+		})
+	})
+})
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/rep/harmonizer"
+)
+
+type BulkSyncer interface {
+	SyncNow(cancel <-chan struct{}) (harmonizer.SyncSummary, bool)
+}
+
+type SyncHandler struct {
+	syncer BulkSyncer
+}
+
+// NewSyncHandler serves a route that makes the bulker sync immediately and
+// responds with how many operations the sync queued.
+func NewSyncHandler(syncer BulkSyncer) *SyncHandler {
+	return &SyncHandler{
+		syncer: syncer,
+	}
+}
+
+func (h *SyncHandler) ServeHTTP(w http.ResponseWriter, r *http.Request, logger lager.Logger) {
+	logger = logger.Session("sync-handler")
+
+	if h.syncer == nil {
+		logger.Info("bulk-sync-unavailable")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	logger.Info("triggering-sync")
+	summary, ok := h.syncer.SyncNow(r.Context().Done())
+	if !ok {
+		logger.Info("cancelled-waiting-for-sync")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	logger.Info("synced", lager.Data{"operations-queued": summary.OperationsQueued, "error": summary.Error})
+
+	status := http.StatusOK
+	if summary.Error != "" {
+		status = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(summary)
+}
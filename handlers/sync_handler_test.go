@@ -0,0 +1,111 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/durationjson"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/rep/handlers"
+	"code.cloudfoundry.org/rep/harmonizer"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeBulkSyncer struct {
+	summary harmonizer.SyncSummary
+	block   bool
+}
+
+func (s *fakeBulkSyncer) SyncNow(cancel <-chan struct{}) (harmonizer.SyncSummary, bool) {
+	if s.block {
+		<-cancel
+		return harmonizer.SyncSummary{}, false
+	}
+	return s.summary, true
+}
+
+var _ = Describe("SyncHandler", func() {
+	var (
+		logger           *lagertest.TestLogger
+		syncer           *fakeBulkSyncer
+		bulkSyncer       handlers.BulkSyncer
+		request          *http.Request
+		responseRecorder *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		syncer = &fakeBulkSyncer{
+			summary: harmonizer.SyncSummary{
+				OperationsQueued: 3,
+				StartedAt:        time.Unix(1000, 0).UTC(),
+				Duration:         durationjson.Duration(time.Second),
+			},
+		}
+		bulkSyncer = syncer
+		responseRecorder = httptest.NewRecorder()
+
+		var err error
+		request, err = http.NewRequest("POST", "/sync", nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		handlers.NewSyncHandler(bulkSyncer).ServeHTTP(responseRecorder, request, logger)
+	})
+
+	It("responds with a summary of the sync", func() {
+		Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+		var summary harmonizer.SyncSummary
+		Expect(json.Unmarshal(responseRecorder.Body.Bytes(), &summary)).To(Succeed())
+		Expect(summary).To(Equal(syncer.summary))
+	})
+
+	It("reports the duration as a string", func() {
+		Expect(responseRecorder.Body.String()).To(ContainSubstring(`"duration":"1s"`))
+	})
+
+	Context("when the sync fails", func() {
+		BeforeEach(func() {
+			syncer.summary.Error = "boom"
+		})
+
+		It("responds with 500 and the summary", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusInternalServerError))
+
+			var summary harmonizer.SyncSummary
+			Expect(json.Unmarshal(responseRecorder.Body.Bytes(), &summary)).To(Succeed())
+			Expect(summary.Error).To(Equal("boom"))
+		})
+	})
+
+	Context("when the request is cancelled before the sync finishes", func() {
+		BeforeEach(func() {
+			syncer.block = true
+
+			ctx, cancel := context.WithCancel(request.Context())
+			cancel()
+			request = request.WithContext(ctx)
+		})
+
+		It("responds with 503", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusServiceUnavailable))
+		})
+	})
+
+	Context("when there is no bulk syncer", func() {
+		BeforeEach(func() {
+			bulkSyncer = nil
+		})
+
+		It("responds with 503", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusServiceUnavailable))
+		})
+	})
+})
@@ -1,51 +1,137 @@
 package harmonizer
 
 import (
+	"math/rand"
 	"os"
+	"sync"
 	"time"
 
 	"code.cloudfoundry.org/clock"
 	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/durationjson"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/operationq"
 	"code.cloudfoundry.org/rep/evacuation/evacuation_context"
 	"code.cloudfoundry.org/rep/generator"
 )
 
-const repBulkSyncDuration = "RepBulkSyncDuration"
+const (
+	repBulkSyncDuration = "RepBulkSyncDuration"
+	repBulkSyncInterval = "RepBulkSyncInterval"
+
+	DefaultBusyThreshold = 50
+)
+
+// AdaptiveInterval lets the bulker adapt its polling interval between
+// MinInterval and MaxInterval. The interval is halved after a sync that
+// failed, generated at least BusyThreshold operations, or was triggered
+// because the event stream was interrupted, and grows by half after any
+// other sync. An unset bound defaults to the polling interval, and with
+// neither bound set the interval is fixed.
+//
+// Every wait is randomly lengthened or shortened by up to Jitter, a fraction
+// of the interval, so that cells do not sync against the BBS in lockstep.
+type AdaptiveInterval struct {
+	MinInterval   time.Duration
+	MaxInterval   time.Duration
+	BusyThreshold int
+	Jitter        float64
+}
+
+func (a AdaptiveInterval) enabled() bool {
+	return a.MinInterval > 0 || a.MaxInterval > 0
+}
+
+func (a AdaptiveInterval) clamp(interval time.Duration) time.Duration {
+	if interval < a.MinInterval {
+		return a.MinInterval
+	}
+	if interval > a.MaxInterval {
+		return a.MaxInterval
+	}
+	return interval
+}
 
 type Bulker struct {
 	logger lager.Logger
 
 	pollInterval           time.Duration
 	evacuationPollInterval time.Duration
+	adaptive               AdaptiveInterval
 	evacuationNotifier     evacuation_context.EvacuationNotifier
 	clock                  clock.Clock
 	generator              generator.Generator
 	queue                  operationq.Queue
 	metronClient           loggingclient.IngressClient
+	syncRequests           chan struct{}
+
+	lastSyncLock       sync.RWMutex
+	lastSuccessfulSync time.Time
+
+	requestLock            sync.Mutex
+	pendingRequest         *syncRequest
+	eventStreamInterrupted bool
+}
+
+// SyncSummary describes a bulk sync that was requested through SyncNow. The
+// duration is encoded as a string such as "1.5s".
+type SyncSummary struct {
+	OperationsQueued int                   `json:"operations_queued"`
+	StartedAt        time.Time             `json:"started_at"`
+	Duration         durationjson.Duration `json:"duration"`
+	Error            string                `json:"error,omitempty"`
+}
+
+type syncRequest struct {
+	done    chan struct{}
+	summary SyncSummary
 }
 
 func NewBulker(
 	logger lager.Logger,
 	pollInterval time.Duration,
 	evacuationPollInterval time.Duration,
+	adaptive AdaptiveInterval,
 	evacuationNotifier evacuation_context.EvacuationNotifier,
 	clock clock.Clock,
 	generator generator.Generator,
 	queue operationq.Queue,
 	metronClient loggingclient.IngressClient,
 ) *Bulker {
+	if adaptive.enabled() {
+		if adaptive.MinInterval <= 0 {
+			adaptive.MinInterval = pollInterval
+		}
+		if adaptive.MaxInterval <= 0 {
+			adaptive.MaxInterval = pollInterval
+		}
+		if adaptive.MinInterval > adaptive.MaxInterval {
+			adaptive.MinInterval = adaptive.MaxInterval
+		}
+		if adaptive.BusyThreshold <= 0 {
+			adaptive.BusyThreshold = DefaultBusyThreshold
+		}
+		pollInterval = adaptive.clamp(pollInterval)
+	}
+	if adaptive.Jitter < 0 {
+		adaptive.Jitter = 0
+	}
+	if adaptive.Jitter > 1 {
+		adaptive.Jitter = 1
+	}
+
 	return &Bulker{
 		logger: logger,
 
 		pollInterval:           pollInterval,
 		evacuationPollInterval: evacuationPollInterval,
+		adaptive:               adaptive,
 		evacuationNotifier:     evacuationNotifier,
 		clock:                  clock,
 		generator:              generator,
 		queue:                  queue,
 		metronClient:           metronClient,
+		syncRequests:           make(chan struct{}, 1),
 	}
 }
 
@@ -61,8 +147,9 @@ func (b *Bulker) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
 	defer logger.Info("finished")
 
 	interval := b.pollInterval
+	evacuating := false
 
-	timer := b.clock.NewTimer(interval)
+	timer := b.clock.NewTimer(b.jitter(interval))
 	defer timer.Stop()
 
 	for {
@@ -70,23 +157,96 @@ func (b *Bulker) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
 		case <-timer.C():
 
 		case <-evacuateNotify:
-			timer.Stop()
+			stopTimer(timer)
 			evacuateNotify = nil
 
 			logger.Info("notified-of-evacuation")
 			interval = b.evacuationPollInterval
+			evacuating = true
+
+		case <-b.syncRequests:
+			stopTimer(timer)
+			logger.Info("sync-triggered")
+
+			// A requested sync must act on every guid, and after the event
+			// stream was interrupted the state seen by the previous sync can
+			// no longer be trusted.
+			b.generator.ForceFullSync()
 
 		case signal := <-signals:
 			logger.Info("received-signal", lager.Data{"signal": signal.String()})
 			return nil
 		}
 
-		b.sync(logger)
-		timer.Reset(interval)
+		request, streamInterrupted := b.takeSyncRequest()
+		startTime := b.clock.Now()
+
+		operations, err := b.sync(logger)
+		if request != nil {
+			request.finish(startTime, b.clock.Since(startTime), operations, err)
+		}
+
+		if !evacuating {
+			interval = b.adapt(logger, interval, operations, err, streamInterrupted)
+		}
+		timer.Reset(b.jitter(interval))
+	}
+}
+
+// adapt returns the interval to wait before the next sync, given how the
+// last one went. The interval is fixed unless adapting is enabled.
+func (b *Bulker) adapt(logger lager.Logger, interval time.Duration, operations int, err error, streamInterrupted bool) time.Duration {
+	if !b.adaptive.enabled() {
+		return interval
+	}
+
+	next := interval + interval/2
+	if err != nil || streamInterrupted || operations >= b.adaptive.BusyThreshold {
+		next = interval / 2
+	}
+	next = b.adaptive.clamp(next)
+
+	if next != interval {
+		logger.Info("adapted-interval", lager.Data{
+			"from":                     interval.String(),
+			"to":                       next.String(),
+			"operations":               operations,
+			"failed":                   err != nil,
+			"event-stream-interrupted": streamInterrupted,
+		})
+	}
+
+	sendErr := b.metronClient.SendDuration(repBulkSyncInterval, next)
+	if sendErr != nil {
+		logger.Error("failed-to-send-rep-bulk-sync-interval-metric", sendErr)
+	}
+
+	return next
+}
+
+// stopTimer stops the timer and drains a tick it may already have sent, so
+// that resetting it does not cause an extra sync.
+func stopTimer(timer clock.Timer) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C():
+		default:
+		}
+	}
+}
+
+// jitter randomly lengthens or shortens the interval by up to the configured
+// fraction of it.
+func (b *Bulker) jitter(interval time.Duration) time.Duration {
+	if b.adaptive.Jitter == 0 {
+		return interval
 	}
+	return interval + time.Duration((2*rand.Float64()-1)*b.adaptive.Jitter*float64(interval))
 }
 
-func (b *Bulker) sync(logger lager.Logger) {
+// sync generates and queues a batch of operations, returning how many were
+// generated.
+func (b *Bulker) sync(logger lager.Logger) (int, error) {
 	logger = logger.Session("sync")
 
 	logger.Info("starting")
@@ -105,10 +265,91 @@ func (b *Bulker) sync(logger lager.Logger) {
 
 	if batchError != nil {
 		logger.Error("failed-to-generate-operations", batchError)
-		return
+		return 0, batchError
 	}
 
 	for _, operation := range ops {
 		b.queue.Push(operation)
 	}
+
+	b.lastSyncLock.Lock()
+	b.lastSuccessfulSync = endTime
+	b.lastSyncLock.Unlock()
+
+	return len(ops), nil
+}
+
+// TriggerSync makes the bulker sync as soon as possible instead of waiting
+// for the interval. The event consumer triggers a sync once the event stream
+// was interrupted, so a sync triggered this way also shortens an adaptive
+// interval.
+func (b *Bulker) TriggerSync() {
+	b.requestLock.Lock()
+	b.eventStreamInterrupted = true
+	b.requestLock.Unlock()
+
+	b.requestSync()
+}
+
+// requestSync wakes the bulker up. Requests made while one is pending are
+// coalesced.
+func (b *Bulker) requestSync() {
+	select {
+	case b.syncRequests <- struct{}{}:
+	default:
+	}
+}
+
+// SyncNow triggers a sync and waits for it to finish, returning how many
+// operations it queued. Callers that request a sync before it has started
+// share it. It returns false if cancel is closed first.
+func (b *Bulker) SyncNow(cancel <-chan struct{}) (SyncSummary, bool) {
+	b.requestLock.Lock()
+	if b.pendingRequest == nil {
+		b.pendingRequest = &syncRequest{done: make(chan struct{})}
+	}
+	request := b.pendingRequest
+	b.requestLock.Unlock()
+
+	b.requestSync()
+
+	select {
+	case <-request.done:
+		return request.summary, true
+	case <-cancel:
+		return SyncSummary{}, false
+	}
+}
+
+// takeSyncRequest returns the pending sync request, if any, so that requests
+// made from now on wait for the next sync. It also returns whether the sync
+// was triggered because the event stream was interrupted.
+func (b *Bulker) takeSyncRequest() (*syncRequest, bool) {
+	b.requestLock.Lock()
+	defer b.requestLock.Unlock()
+
+	request, streamInterrupted := b.pendingRequest, b.eventStreamInterrupted
+	b.pendingRequest = nil
+	b.eventStreamInterrupted = false
+	return request, streamInterrupted
+}
+
+func (r *syncRequest) finish(startedAt time.Time, duration time.Duration, operations int, err error) {
+	r.summary = SyncSummary{
+		OperationsQueued: operations,
+		StartedAt:        startedAt,
+		Duration:         durationjson.Duration(duration),
+	}
+	if err != nil {
+		r.summary.Error = err.Error()
+	}
+	close(r.done)
+}
+
+// LastSuccessfulSync returns when the last bulk sync that generated operations
+// without error finished, or the zero time if none has.
+func (b *Bulker) LastSuccessfulSync() time.Time {
+	b.lastSyncLock.RLock()
+	defer b.lastSyncLock.RUnlock()
+	return b.lastSuccessfulSync
 }
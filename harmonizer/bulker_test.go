@@ -7,6 +7,7 @@ import (
 
 	"code.cloudfoundry.org/clock/fakeclock"
 	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/durationjson"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
 	"code.cloudfoundry.org/operationq"
@@ -25,6 +26,7 @@ var _ = Describe("Bulker", func() {
 		logger                 *lagertest.TestLogger
 		pollInterval           time.Duration
 		evacuationPollInterval time.Duration
+		adaptive               harmonizer.AdaptiveInterval
 		fakeClock              *fakeclock.FakeClock
 		fakeGenerator          *fake_generator.FakeGenerator
 		fakeQueue              *fake_operationq.FakeQueue
@@ -40,26 +42,27 @@ var _ = Describe("Bulker", func() {
 		logger = lagertest.NewTestLogger("test")
 		pollInterval = 30 * time.Second
 		evacuationPollInterval = 10 * time.Second
+		adaptive = harmonizer.AdaptiveInterval{}
 		fakeClock = fakeclock.NewFakeClock(time.Now())
 		fakeGenerator = new(fake_generator.FakeGenerator)
 		fakeQueue = new(fake_operationq.FakeQueue)
 		fakeMetronClient = new(mfakes.FakeIngressClient)
 
 		evacuatable, _, evacuationNotifier = evacuation_context.New()
+	})
 
+	JustBeforeEach(func() {
 		bulker = harmonizer.NewBulker(
 			logger,
 			pollInterval,
 			evacuationPollInterval,
+			adaptive,
 			evacuationNotifier,
 			fakeClock,
 			fakeGenerator,
 			fakeQueue,
 			fakeMetronClient,
 		)
-	})
-
-	JustBeforeEach(func() {
 		process = ifrit.Invoke(bulker)
 		Eventually(fakeClock.WatcherCount).Should(Equal(1))
 	})
@@ -103,6 +106,11 @@ var _ = Describe("Bulker", func() {
 				Expect(name).To(Equal("RepBulkSyncDuration"))
 				Expect(value).To(BeNumerically("==", 10*time.Second))
 			})
+
+			It("records when the sync succeeded", func() {
+				Eventually(fakeQueue.PushCallCount).Should(Equal(expectedQueueLength))
+				Expect(bulker.LastSuccessfulSync()).To(Equal(fakeClock.Now()))
+			})
 		})
 
 		Context("when generating the batch operations fails", func() {
@@ -116,6 +124,11 @@ var _ = Describe("Bulker", func() {
 				Eventually(logger).Should(gbytes.Say("failed-to-generate-operations"))
 				Eventually(logger).Should(gbytes.Say("nope"))
 			})
+
+			It("does not record a successful sync", func() {
+				Eventually(logger).Should(gbytes.Say("failed-to-generate-operations"))
+				Expect(bulker.LastSuccessfulSync()).To(BeZero())
+			})
 		})
 	}
 
@@ -145,6 +158,133 @@ var _ = Describe("Bulker", func() {
 		})
 	})
 
+	Context("when a sync is triggered", func() {
+		JustBeforeEach(func() {
+			bulker.TriggerSync()
+		})
+
+		itPerformsBatchOperations(2)
+
+		It("syncs without waiting for the interval", func() {
+			Eventually(fakeGenerator.BatchOperationsCallCount).Should(Equal(1))
+			Consistently(fakeGenerator.BatchOperationsCallCount).Should(Equal(1))
+		})
+
+		It("forces a full sync", func() {
+			Eventually(fakeGenerator.BatchOperationsCallCount).Should(Equal(1))
+			Expect(fakeGenerator.ForceFullSyncCallCount()).To(Equal(1))
+		})
+
+		It("restarts the interval", func() {
+			Eventually(fakeGenerator.BatchOperationsCallCount).Should(Equal(1))
+			fakeClock.WaitForWatcherAndIncrement(pollInterval)
+			Eventually(fakeGenerator.BatchOperationsCallCount).Should(Equal(2))
+		})
+	})
+
+	Context("when a sync is requested", func() {
+		type result struct {
+			summary harmonizer.SyncSummary
+			ok      bool
+		}
+
+		syncNow := func(cancel <-chan struct{}) <-chan result {
+			results := make(chan result, 1)
+			go func() {
+				summary, ok := bulker.SyncNow(cancel)
+				results <- result{summary, ok}
+			}()
+			return results
+		}
+
+		var (
+			release     chan struct{}
+			syncStarted chan struct{}
+		)
+
+		BeforeEach(func() {
+			release = make(chan struct{})
+			syncStarted = make(chan struct{}, 2)
+
+			fakeGenerator.BatchOperationsStub = func(lager.Logger) (map[string]operationq.Operation, error) {
+				syncStarted <- struct{}{}
+				<-release
+				fakeClock.Increment(time.Second)
+				return map[string]operationq.Operation{
+					"guid1": new(fake_operationq.FakeOperation),
+					"guid2": new(fake_operationq.FakeOperation),
+				}, nil
+			}
+		})
+
+		It("syncs and reports how many operations were queued", func() {
+			startedAt := fakeClock.Now()
+			results := syncNow(nil)
+			close(release)
+
+			var r result
+			Eventually(results).Should(Receive(&r))
+			Expect(r.ok).To(BeTrue())
+			Expect(r.summary).To(Equal(harmonizer.SyncSummary{
+				OperationsQueued: 2,
+				StartedAt:        startedAt,
+				Duration:         durationjson.Duration(time.Second),
+			}))
+			Expect(fakeQueue.PushCallCount()).To(Equal(2))
+		})
+
+		Context("when generating the batch operations fails", func() {
+			BeforeEach(func() {
+				close(release)
+				fakeGenerator.BatchOperationsStub = nil
+				fakeGenerator.BatchOperationsReturns(nil, errors.New("nope"))
+			})
+
+			It("reports the error", func() {
+				var r result
+				Eventually(syncNow(nil)).Should(Receive(&r))
+				Expect(r.ok).To(BeTrue())
+				Expect(r.summary.OperationsQueued).To(BeZero())
+				Expect(r.summary.Error).To(Equal("nope"))
+			})
+		})
+
+		Context("while a sync is in progress", func() {
+			It("coalesces the requests into a single follow-up sync", func() {
+				first := syncNow(nil)
+				Eventually(syncStarted).Should(Receive())
+
+				second := syncNow(nil)
+				third := syncNow(nil)
+				Consistently(second).ShouldNot(Receive())
+
+				close(release)
+				Eventually(first).Should(Receive())
+
+				var secondResult, thirdResult result
+				Eventually(second).Should(Receive(&secondResult))
+				Eventually(third).Should(Receive(&thirdResult))
+				Expect(secondResult).To(Equal(thirdResult))
+				Expect(fakeGenerator.BatchOperationsCallCount()).To(Equal(2))
+			})
+		})
+
+		Context("when the caller gives up waiting", func() {
+			It("returns without a summary", func() {
+				cancel := make(chan struct{})
+				results := syncNow(cancel)
+				Eventually(syncStarted).Should(Receive())
+
+				close(cancel)
+				var r result
+				Eventually(results).Should(Receive(&r))
+				Expect(r.ok).To(BeFalse())
+
+				close(release)
+			})
+		})
+	})
+
 	Context("when evacuation starts", func() {
 		BeforeEach(func() {
 			evacuatable.Evacuate()
@@ -166,4 +306,106 @@ var _ = Describe("Bulker", func() {
 			})
 		})
 	})
+
+	Context("with an adaptive interval", func() {
+		var operations map[string]operationq.Operation
+
+		BeforeEach(func() {
+			adaptive = harmonizer.AdaptiveInterval{
+				MinInterval:   10 * time.Second,
+				MaxInterval:   time.Minute,
+				BusyThreshold: 2,
+			}
+			operations = map[string]operationq.Operation{"guid1": new(fake_operationq.FakeOperation)}
+			fakeGenerator.BatchOperationsStub = func(lager.Logger) (map[string]operationq.Operation, error) {
+				return operations, nil
+			}
+		})
+
+		syncs := fakeGenerator.BatchOperationsCallCount
+
+		expectNextSyncAfter := func(interval time.Duration) {
+			count := syncs()
+			fakeClock.WaitForWatcherAndIncrement(interval - time.Second)
+			Consistently(syncs).Should(Equal(count))
+			fakeClock.Increment(time.Second)
+			Eventually(syncs).Should(Equal(count + 1))
+		}
+
+		JustBeforeEach(func() {
+			fakeClock.WaitForWatcherAndIncrement(pollInterval)
+			Eventually(syncs).Should(Equal(1))
+		})
+
+		It("lengthens the interval while syncs generate few operations", func() {
+			expectNextSyncAfter(45 * time.Second)
+			expectNextSyncAfter(time.Minute)
+			expectNextSyncAfter(time.Minute)
+		})
+
+		It("reports the interval", func() {
+			Eventually(fakeMetronClient.SendDurationCallCount).Should(Equal(2))
+			name, value, _ := fakeMetronClient.SendDurationArgsForCall(1)
+			Expect(name).To(Equal("RepBulkSyncInterval"))
+			Expect(value).To(Equal(45 * time.Second))
+		})
+
+		Context("when syncs generate many operations", func() {
+			BeforeEach(func() {
+				operations["guid2"] = new(fake_operationq.FakeOperation)
+			})
+
+			It("shortens the interval down to the minimum", func() {
+				expectNextSyncAfter(15 * time.Second)
+				expectNextSyncAfter(10 * time.Second)
+				expectNextSyncAfter(10 * time.Second)
+			})
+		})
+
+		Context("when a sync fails", func() {
+			BeforeEach(func() {
+				fakeGenerator.BatchOperationsStub = nil
+				fakeGenerator.BatchOperationsReturns(nil, errors.New("nope"))
+			})
+
+			It("shortens the interval", func() {
+				expectNextSyncAfter(15 * time.Second)
+			})
+		})
+
+		Context("when a sync is triggered after the event stream was interrupted", func() {
+			It("shortens the interval", func() {
+				expectNextSyncAfter(45 * time.Second)
+
+				bulker.TriggerSync()
+				Eventually(syncs).Should(Equal(3))
+				expectNextSyncAfter(30 * time.Second)
+			})
+		})
+
+		Context("when a sync is requested", func() {
+			It("does not shorten the interval", func() {
+				expectNextSyncAfter(45 * time.Second)
+
+				_, ok := bulker.SyncNow(nil)
+				Expect(ok).To(BeTrue())
+				Expect(syncs()).To(Equal(3))
+				expectNextSyncAfter(time.Minute)
+			})
+		})
+	})
+
+	Context("with jitter", func() {
+		BeforeEach(func() {
+			adaptive = harmonizer.AdaptiveInterval{Jitter: 0.5}
+		})
+
+		It("syncs within the jittered interval", func() {
+			fakeClock.WaitForWatcherAndIncrement(pollInterval/2 - time.Millisecond)
+			Consistently(fakeGenerator.BatchOperationsCallCount).Should(BeZero())
+
+			fakeClock.Increment(pollInterval + time.Millisecond)
+			Eventually(fakeGenerator.BatchOperationsCallCount).Should(Equal(1))
+		})
+	})
 })
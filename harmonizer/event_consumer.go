@@ -2,29 +2,56 @@ package harmonizer
 
 import (
 	"os"
+	"sync/atomic"
+	"time"
 
+	"code.cloudfoundry.org/clock"
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
 	"code.cloudfoundry.org/executor"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/operationq"
 	"code.cloudfoundry.org/rep/generator"
 )
 
+const (
+	repEventStreamConnected  = "RepEventStreamConnected"
+	repEventStreamReconnects = "RepEventStreamReconnects"
+
+	ResubscribeInitialBackoff = time.Second
+	ResubscribeMaxBackoff     = 30 * time.Second
+)
+
+// SyncTrigger requests a bulk sync outside of the regular interval.
+type SyncTrigger interface {
+	TriggerSync()
+}
+
 type EventConsumer struct {
 	logger         lager.Logger
 	executorClient executor.Client
 	generator      generator.Generator
 	queue          operationq.Queue
+	clock          clock.Clock
+	metronClient   loggingclient.IngressClient
+	syncTrigger    SyncTrigger
+	connected      int32
 }
 
 func NewEventConsumer(
 	logger lager.Logger,
 	generator generator.Generator,
 	queue operationq.Queue,
+	clock clock.Clock,
+	metronClient loggingclient.IngressClient,
+	syncTrigger SyncTrigger,
 ) *EventConsumer {
 	return &EventConsumer{
-		logger:    logger,
-		generator: generator,
-		queue:     queue,
+		logger:       logger,
+		generator:    generator,
+		queue:        queue,
+		clock:        clock,
+		metronClient: metronClient,
+		syncTrigger:  syncTrigger,
 	}
 }
 
@@ -39,24 +66,90 @@ func (consumer *EventConsumer) Run(signals <-chan os.Signal, ready chan<- struct
 		return err
 	}
 
+	consumer.setConnected(logger, true)
+	defer consumer.setConnected(logger, false)
+
 	close(ready)
 	logger.Info("started")
 
 	for {
 		select {
 		case op, ok := <-stream:
-			if !ok {
-				logger.Info("event-stream-closed")
+			if ok {
+				consumer.queue.Push(op)
+				continue
+			}
+
+			logger.Info("event-stream-closed")
+			consumer.setConnected(logger, false)
+
+			stream = consumer.resubscribe(logger, signals)
+			if stream == nil {
 				return nil
 			}
 
-			consumer.queue.Push(op)
+			consumer.setConnected(logger, true)
+			consumer.syncTrigger.TriggerSync()
 
 		case signal := <-signals:
 			logger.Info("received-signal", lager.Data{"signal": signal.String()})
 			return nil
 		}
 	}
+}
+
+// resubscribe subscribes to the operation stream again, backing off between
+// failed attempts. It returns nil when signaled before succeeding.
+func (consumer *EventConsumer) resubscribe(logger lager.Logger, signals <-chan os.Signal) <-chan operationq.Operation {
+	logger = logger.Session("resubscribe")
+
+	backoff := ResubscribeInitialBackoff
+	for attempt := 1; ; attempt++ {
+		timer := consumer.clock.NewTimer(backoff)
+
+		select {
+		case <-timer.C():
+		case signal := <-signals:
+			timer.Stop()
+			logger.Info("received-signal", lager.Data{"signal": signal.String()})
+			return nil
+		}
+
+		stream, err := consumer.generator.OperationStream(consumer.logger)
+		if err == nil {
+			logger.Info("succeeded-resubscribing", lager.Data{"attempt": attempt})
+			err = consumer.metronClient.IncrementCounter(repEventStreamReconnects)
+			if err != nil {
+				logger.Error("failed-to-increment-reconnects-counter", err)
+			}
+			return stream
+		}
+
+		logger.Error("failed-resubscribing", err, lager.Data{"attempt": attempt, "backoff": backoff.String()})
+
+		backoff *= 2
+		if backoff > ResubscribeMaxBackoff {
+			backoff = ResubscribeMaxBackoff
+		}
+	}
+}
+
+func (consumer *EventConsumer) setConnected(logger lager.Logger, connected bool) {
+	value := 0
+	if connected {
+		value = 1
+	}
+
+	atomic.StoreInt32(&consumer.connected, int32(value))
+
+	err := consumer.metronClient.SendMetric(repEventStreamConnected, value)
+	if err != nil {
+		logger.Error("failed-to-send-event-stream-connected-metric", err)
+	}
+}
 
-	return nil
+// EventStreamConnected reports whether the consumer is currently receiving
+// operations from the executor event stream.
+func (consumer *EventConsumer) EventStreamConnected() bool {
+	return atomic.LoadInt32(&consumer.connected) == 1
 }
@@ -3,7 +3,11 @@ package harmonizer_test
 import (
 	"errors"
 	"os"
+	"sync/atomic"
+	"time"
 
+	"code.cloudfoundry.org/clock/fakeclock"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
 	"code.cloudfoundry.org/lager/lagertest"
 	"code.cloudfoundry.org/operationq"
 	"code.cloudfoundry.org/operationq/fake_operationq"
@@ -14,11 +18,26 @@ import (
 	"github.com/tedsuo/ifrit"
 )
 
+type fakeSyncTrigger struct {
+	triggered int32
+}
+
+func (t *fakeSyncTrigger) TriggerSync() {
+	atomic.AddInt32(&t.triggered, 1)
+}
+
+func (t *fakeSyncTrigger) TriggerCount() int {
+	return int(atomic.LoadInt32(&t.triggered))
+}
+
 var _ = Describe("EventConsumer", func() {
 	var (
-		logger        *lagertest.TestLogger
-		fakeGenerator *fake_generator.FakeGenerator
-		fakeQueue     *fake_operationq.FakeQueue
+		logger           *lagertest.TestLogger
+		fakeGenerator    *fake_generator.FakeGenerator
+		fakeQueue        *fake_operationq.FakeQueue
+		fakeClock        *fakeclock.FakeClock
+		fakeMetronClient *mfakes.FakeIngressClient
+		syncTrigger      *fakeSyncTrigger
 
 		consumer *harmonizer.EventConsumer
 		process  ifrit.Process
@@ -28,8 +47,11 @@ var _ = Describe("EventConsumer", func() {
 		logger = lagertest.NewTestLogger("test")
 		fakeGenerator = new(fake_generator.FakeGenerator)
 		fakeQueue = new(fake_operationq.FakeQueue)
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeMetronClient = new(mfakes.FakeIngressClient)
+		syncTrigger = &fakeSyncTrigger{}
 
-		consumer = harmonizer.NewEventConsumer(logger, fakeGenerator, fakeQueue)
+		consumer = harmonizer.NewEventConsumer(logger, fakeGenerator, fakeQueue, fakeClock, fakeMetronClient, syncTrigger)
 	})
 
 	JustBeforeEach(func() {
@@ -53,6 +75,14 @@ var _ = Describe("EventConsumer", func() {
 			fakeGenerator.OperationStreamReturns(operations, nil)
 		})
 
+		It("reports the event stream as connected", func() {
+			Eventually(consumer.EventStreamConnected).Should(BeTrue())
+
+			name, value, _ := fakeMetronClient.SendMetricArgsForCall(0)
+			Expect(name).To(Equal("RepEventStreamConnected"))
+			Expect(value).To(Equal(1))
+		})
+
 		Context("when an operation is received", func() {
 			var fakeOperation *fake_operationq.FakeOperation
 
@@ -69,10 +99,70 @@ var _ = Describe("EventConsumer", func() {
 		})
 
 		Context("when the operation stream terminates", func() {
-			It("exits happily", func() {
+			var resubscribedOperations chan operationq.Operation
+
+			BeforeEach(func() {
+				resubscribedOperations = make(chan operationq.Operation)
+				fakeGenerator.OperationStreamReturnsOnCall(1, nil, errors.New("executor unavailable"))
+				fakeGenerator.OperationStreamReturnsOnCall(2, resubscribedOperations, nil)
+			})
+
+			JustBeforeEach(func() {
+				Eventually(consumer.EventStreamConnected).Should(BeTrue())
 				close(receivedOperations)
+			})
+
+			It("reports the event stream as disconnected", func() {
+				Eventually(consumer.EventStreamConnected).Should(BeFalse())
+
+				name, value, _ := fakeMetronClient.SendMetricArgsForCall(1)
+				Expect(name).To(Equal("RepEventStreamConnected"))
+				Expect(value).To(Equal(0))
+			})
+
+			It("does not exit", func() {
+				Consistently(process.Wait()).ShouldNot(Receive())
+			})
+
+			It("resubscribes with backoff", func() {
+				fakeClock.WaitForWatcherAndIncrement(harmonizer.ResubscribeInitialBackoff)
+				Eventually(fakeGenerator.OperationStreamCallCount).Should(Equal(2))
+
+				fakeClock.WaitForWatcherAndIncrement(harmonizer.ResubscribeInitialBackoff)
+				Consistently(fakeGenerator.OperationStreamCallCount).Should(Equal(2))
+
+				fakeClock.Increment(harmonizer.ResubscribeInitialBackoff)
+				Eventually(fakeGenerator.OperationStreamCallCount).Should(Equal(3))
+				Eventually(consumer.EventStreamConnected).Should(BeTrue())
+				Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("RepEventStreamReconnects"))
+			})
+
+			Context("once it has resubscribed", func() {
+				JustBeforeEach(func() {
+					fakeClock.WaitForWatcherAndIncrement(harmonizer.ResubscribeInitialBackoff)
+					fakeClock.WaitForWatcherAndIncrement(2 * harmonizer.ResubscribeInitialBackoff)
+					Eventually(consumer.EventStreamConnected).Should(BeTrue())
+				})
+
+				It("triggers a bulk sync to catch up on missed events", func() {
+					Eventually(syncTrigger.TriggerCount).Should(Equal(1))
+				})
+
+				It("pushes operations from the new stream", func() {
+					fakeOperation := new(fake_operationq.FakeOperation)
+					resubscribedOperations <- fakeOperation
+
+					Eventually(fakeQueue.PushCallCount).Should(Equal(1))
+					Expect(fakeQueue.PushArgsForCall(0)).To(Equal(fakeOperation))
+				})
+			})
 
-				Eventually(process.Wait()).Should(Receive(BeNil()))
+			Context("when signaled while resubscribing", func() {
+				It("exits", func() {
+					Eventually(consumer.EventStreamConnected).Should(BeFalse())
+					process.Signal(os.Interrupt)
+					Eventually(process.Wait()).Should(Receive(BeNil()))
+				})
 			})
 		})
 	})
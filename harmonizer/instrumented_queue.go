@@ -0,0 +1,93 @@
+package harmonizer
+
+import (
+	"sync"
+
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/operationq"
+	"code.cloudfoundry.org/rep/generator"
+)
+
+const repOperationQueueDepth = "RepOperationQueueDepth"
+
+// InstrumentedQueue reports how many keys have an operation waiting to be
+// executed. Like the queues it wraps, a key is waiting once no matter how
+// many operations were pushed for it.
+type InstrumentedQueue struct {
+	logger       lager.Logger
+	queue        operationq.Queue
+	metronClient loggingclient.IngressClient
+
+	lock    sync.Mutex
+	pending map[string]struct{}
+}
+
+func NewInstrumentedQueue(logger lager.Logger, queue operationq.Queue, metronClient loggingclient.IngressClient) *InstrumentedQueue {
+	return &InstrumentedQueue{
+		logger:       logger.Session("instrumented-queue"),
+		queue:        queue,
+		metronClient: metronClient,
+		pending:      map[string]struct{}{},
+	}
+}
+
+func (q *InstrumentedQueue) Push(operation operationq.Operation) {
+	q.lock.Lock()
+	q.pending[operation.Key()] = struct{}{}
+	depth := len(q.pending)
+	q.lock.Unlock()
+
+	q.sendDepth(depth)
+
+	if prioritized, ok := operation.(generator.PrioritizedOperation); ok {
+		q.queue.Push(&instrumentedPrioritizedOperation{PrioritizedOperation: prioritized, queue: q})
+	} else {
+		q.queue.Push(&instrumentedOperation{Operation: operation, queue: q})
+	}
+}
+
+// Depth is the number of keys with an operation waiting to be executed.
+func (q *InstrumentedQueue) Depth() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.pending)
+}
+
+func (q *InstrumentedQueue) started(key string) {
+	q.lock.Lock()
+	delete(q.pending, key)
+	depth := len(q.pending)
+	q.lock.Unlock()
+
+	q.sendDepth(depth)
+}
+
+func (q *InstrumentedQueue) sendDepth(depth int) {
+	err := q.metronClient.SendMetric(repOperationQueueDepth, depth)
+	if err != nil {
+		q.logger.Error("failed-to-send-queue-depth-metric", err)
+	}
+}
+
+type instrumentedOperation struct {
+	operationq.Operation
+	queue *InstrumentedQueue
+}
+
+func (o *instrumentedOperation) Execute() {
+	o.queue.started(o.Key())
+	o.Operation.Execute()
+}
+
+// instrumentedPrioritizedOperation keeps the priority of the operation
+// visible to a priority queue.
+type instrumentedPrioritizedOperation struct {
+	generator.PrioritizedOperation
+	queue *InstrumentedQueue
+}
+
+func (o *instrumentedPrioritizedOperation) Execute() {
+	o.queue.started(o.Key())
+	o.PrioritizedOperation.Execute()
+}
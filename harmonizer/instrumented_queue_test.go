@@ -0,0 +1,65 @@
+package harmonizer_test
+
+import (
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/operationq/fake_operationq"
+	"code.cloudfoundry.org/rep/generator"
+	"code.cloudfoundry.org/rep/harmonizer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("InstrumentedQueue", func() {
+	var (
+		fakeQueue        *fake_operationq.FakeQueue
+		fakeMetronClient *mfakes.FakeIngressClient
+		queue            *harmonizer.InstrumentedQueue
+	)
+
+	newOperation := func(key string) *fake_operationq.FakeOperation {
+		operation := new(fake_operationq.FakeOperation)
+		operation.KeyReturns(key)
+		return operation
+	}
+
+	BeforeEach(func() {
+		fakeQueue = new(fake_operationq.FakeQueue)
+		fakeMetronClient = new(mfakes.FakeIngressClient)
+		queue = harmonizer.NewInstrumentedQueue(lagertest.NewTestLogger("test"), fakeQueue, fakeMetronClient)
+	})
+
+	It("counts each waiting key once", func() {
+		queue.Push(newOperation("guid-1"))
+		queue.Push(newOperation("guid-1"))
+		queue.Push(newOperation("guid-2"))
+
+		Expect(queue.Depth()).To(Equal(2))
+		Expect(fakeQueue.PushCallCount()).To(Equal(3))
+
+		name, value, _ := fakeMetronClient.SendMetricArgsForCall(2)
+		Expect(name).To(Equal("RepOperationQueueDepth"))
+		Expect(value).To(Equal(2))
+	})
+
+	It("stops counting a key once its operation executes", func() {
+		operation := newOperation("guid-1")
+		queue.Push(operation)
+
+		fakeQueue.PushArgsForCall(0).Execute()
+
+		Expect(operation.ExecuteCallCount()).To(Equal(1))
+		Expect(queue.Depth()).To(Equal(0))
+
+		_, value, _ := fakeMetronClient.SendMetricArgsForCall(1)
+		Expect(value).To(Equal(0))
+	})
+
+	It("keeps the priority of prioritized operations", func() {
+		queue.Push(&generator.ResidualTaskOperation{TaskGuid: "task-guid"})
+
+		prioritized, ok := fakeQueue.PushArgsForCall(0).(generator.PrioritizedOperation)
+		Expect(ok).To(BeTrue())
+		Expect(prioritized.Priority()).To(Equal(generator.PriorityResidual))
+	})
+})
@@ -0,0 +1,152 @@
+package harmonizer
+
+import (
+	"container/list"
+	"sync"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/operationq"
+	"code.cloudfoundry.org/rep/generator"
+)
+
+// PriorityQueueConfig bounds how many operations may execute at once, in
+// total and per priority class. A limit of zero is unlimited.
+type PriorityQueueConfig struct {
+	MaxConcurrency   int
+	ClassConcurrency map[generator.Priority]int
+}
+
+type queuedOperation struct {
+	operation  operationq.Operation
+	priority   generator.Priority
+	evacuation bool
+}
+
+// PriorityQueue is an operationq.Queue that executes operations in priority
+// order: evacuation operations first, then by the operation's priority class,
+// then in the order they were pushed. Operations that do not implement
+// generator.PrioritizedOperation are treated as residual.
+//
+// Like operationq.NewSlidingQueue(1), at most one operation per key executes
+// at a time and only the most recently pushed operation for a busy key is
+// kept.
+type PriorityQueue struct {
+	logger lager.Logger
+	config PriorityQueueConfig
+
+	lock             sync.Mutex
+	pending          map[string]*list.Element
+	queues           map[bool]map[generator.Priority]*list.List
+	executing        map[string]struct{}
+	executingCount   int
+	executingByClass map[generator.Priority]int
+}
+
+func NewPriorityQueue(logger lager.Logger, config PriorityQueueConfig) *PriorityQueue {
+	queues := map[bool]map[generator.Priority]*list.List{}
+	for _, evacuation := range []bool{true, false} {
+		queues[evacuation] = map[generator.Priority]*list.List{}
+		for _, priority := range generator.Priorities {
+			queues[evacuation][priority] = list.New()
+		}
+	}
+
+	return &PriorityQueue{
+		logger:           logger.Session("priority-queue"),
+		config:           config,
+		pending:          map[string]*list.Element{},
+		queues:           queues,
+		executing:        map[string]struct{}{},
+		executingByClass: map[generator.Priority]int{},
+	}
+}
+
+func (q *PriorityQueue) Push(operation operationq.Operation) {
+	queued := &queuedOperation{
+		operation: operation,
+		priority:  generator.PriorityResidual,
+	}
+	if prioritized, ok := operation.(generator.PrioritizedOperation); ok {
+		queued.priority = prioritized.Priority()
+		queued.evacuation = prioritized.Evacuation()
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	key := operation.Key()
+	if element, found := q.pending[key]; found {
+		existing := element.Value.(*queuedOperation)
+		if existing.priority == queued.priority && existing.evacuation == queued.evacuation {
+			element.Value = queued
+			return
+		}
+		q.queues[existing.evacuation][existing.priority].Remove(element)
+	}
+
+	q.pending[key] = q.queues[queued.evacuation][queued.priority].PushBack(queued)
+	q.dispatch()
+}
+
+// dispatch starts as many pending operations as the concurrency limits
+// allow. It must be called with the lock held.
+func (q *PriorityQueue) dispatch() {
+	for {
+		if q.config.MaxConcurrency > 0 && q.executingCount >= q.config.MaxConcurrency {
+			return
+		}
+
+		element, queue := q.next()
+		if element == nil {
+			return
+		}
+
+		queued := queue.Remove(element).(*queuedOperation)
+		key := queued.operation.Key()
+		delete(q.pending, key)
+
+		q.executing[key] = struct{}{}
+		q.executingCount++
+		q.executingByClass[queued.priority]++
+
+		go q.execute(key, queued)
+	}
+}
+
+func (q *PriorityQueue) next() (*list.Element, *list.List) {
+	for _, evacuation := range []bool{true, false} {
+		for _, priority := range generator.Priorities {
+			limit := q.config.ClassConcurrency[priority]
+			if limit > 0 && q.executingByClass[priority] >= limit {
+				continue
+			}
+
+			queue := q.queues[evacuation][priority]
+			for element := queue.Front(); element != nil; element = element.Next() {
+				key := element.Value.(*queuedOperation).operation.Key()
+				if _, busy := q.executing[key]; !busy {
+					return element, queue
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (q *PriorityQueue) execute(key string, queued *queuedOperation) {
+	q.logger.Debug("executing", lager.Data{
+		"key":        key,
+		"priority":   queued.priority.String(),
+		"evacuation": queued.evacuation,
+	})
+
+	queued.operation.Execute()
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	delete(q.executing, key)
+	q.executingCount--
+	q.executingByClass[queued.priority]--
+	q.dispatch()
+}
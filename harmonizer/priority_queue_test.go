@@ -0,0 +1,163 @@
+package harmonizer_test
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/operationq"
+	"code.cloudfoundry.org/rep/generator"
+	"code.cloudfoundry.org/rep/harmonizer"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type executionLog struct {
+	lock sync.Mutex
+	ids  []string
+}
+
+func (l *executionLog) add(id string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.ids = append(l.ids, id)
+}
+
+func (l *executionLog) executed() []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]string{}, l.ids...)
+}
+
+type testOperation struct {
+	id         string
+	key        string
+	priority   generator.Priority
+	evacuation bool
+	log        *executionLog
+	release    chan struct{}
+}
+
+func (o *testOperation) Key() string                  { return o.key }
+func (o *testOperation) Priority() generator.Priority { return o.priority }
+func (o *testOperation) Evacuation() bool             { return o.evacuation }
+
+func (o *testOperation) Execute() {
+	o.log.add(o.id)
+	if o.release != nil {
+		<-o.release
+	}
+}
+
+type unprioritizedOperation struct {
+	testOperation
+}
+
+var _ = Describe("PriorityQueue", func() {
+	var (
+		logger  *lagertest.TestLogger
+		config  harmonizer.PriorityQueueConfig
+		log     *executionLog
+		queue   operationq.Queue
+		blocker *testOperation
+	)
+
+	newOperation := func(id, key string, priority generator.Priority) *testOperation {
+		return &testOperation{id: id, key: key, priority: priority, log: log}
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		log = &executionLog{}
+		config = harmonizer.PriorityQueueConfig{MaxConcurrency: 1}
+	})
+
+	JustBeforeEach(func() {
+		queue = harmonizer.NewPriorityQueue(logger, config)
+
+		blocker = newOperation("blocker", "blocker", generator.PriorityCompleted)
+		blocker.release = make(chan struct{})
+		queue.Push(blocker)
+		Eventually(log.executed).Should(Equal([]string{"blocker"}))
+	})
+
+	It("executes operations by priority class once capacity frees up", func() {
+		queue.Push(newOperation("residual", "a", generator.PriorityResidual))
+		queue.Push(newOperation("reserved", "b", generator.PriorityReserved))
+		queue.Push(newOperation("running", "c", generator.PriorityRunning))
+		queue.Push(newOperation("completed", "d", generator.PriorityCompleted))
+		queue.Push(&unprioritizedOperation{*newOperation("unprioritized", "e", generator.PriorityCompleted)})
+
+		close(blocker.release)
+
+		Eventually(log.executed).Should(Equal([]string{
+			"blocker", "completed", "running", "reserved", "residual", "unprioritized",
+		}))
+	})
+
+	It("boosts evacuation operations ahead of every class", func() {
+		queue.Push(newOperation("completed", "a", generator.PriorityCompleted))
+		evacuation := newOperation("evacuation", "b", generator.PriorityResidual)
+		evacuation.evacuation = true
+		queue.Push(evacuation)
+
+		close(blocker.release)
+
+		Eventually(log.executed).Should(Equal([]string{"blocker", "evacuation", "completed"}))
+	})
+
+	It("executes operations of the same class in the order they were pushed", func() {
+		queue.Push(newOperation("first", "a", generator.PriorityRunning))
+		queue.Push(newOperation("second", "b", generator.PriorityRunning))
+
+		close(blocker.release)
+
+		Eventually(log.executed).Should(Equal([]string{"blocker", "first", "second"}))
+	})
+
+	It("keeps only the latest pending operation for a key", func() {
+		queue.Push(newOperation("stale", "a", generator.PriorityReserved))
+		queue.Push(newOperation("latest", "a", generator.PriorityRunning))
+
+		close(blocker.release)
+
+		Eventually(log.executed).Should(Equal([]string{"blocker", "latest"}))
+		Consistently(log.executed).Should(HaveLen(2))
+	})
+
+	Context("when there is no global concurrency limit", func() {
+		BeforeEach(func() {
+			config.MaxConcurrency = 0
+		})
+
+		It("does not execute an operation while another with the same key is executing", func() {
+			queue.Push(newOperation("other", "a", generator.PriorityResidual))
+			Eventually(log.executed).Should(ContainElement("other"))
+
+			queue.Push(newOperation("second", "blocker", generator.PriorityRunning))
+			Consistently(log.executed).ShouldNot(ContainElement("second"))
+
+			close(blocker.release)
+			Eventually(log.executed).Should(ContainElement("second"))
+		})
+	})
+
+	Context("when a class has a concurrency limit", func() {
+		BeforeEach(func() {
+			config = harmonizer.PriorityQueueConfig{
+				ClassConcurrency: map[generator.Priority]int{generator.PriorityCompleted: 1},
+			}
+		})
+
+		It("holds operations of that class while others run", func() {
+			queue.Push(newOperation("completed", "a", generator.PriorityCompleted))
+			queue.Push(newOperation("reserved", "b", generator.PriorityReserved))
+
+			Eventually(log.executed).Should(Equal([]string{"blocker", "reserved"}))
+			Consistently(log.executed).Should(HaveLen(2))
+
+			close(blocker.release)
+			Eventually(log.executed).Should(Equal([]string{"blocker", "reserved", "completed"}))
+		})
+	})
+})
@@ -0,0 +1,46 @@
+package history
+
+import (
+	"os"
+	"os/signal"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/tedsuo/ifrit"
+)
+
+type dumper struct {
+	logger  lager.Logger
+	history *History
+	signal  os.Signal
+}
+
+// NewDumper returns a runner that logs the whole history every time the
+// process receives the signal.
+func NewDumper(logger lager.Logger, history *History, sig os.Signal) ifrit.Runner {
+	return &dumper{
+		logger:  logger.Session("operation-history-dumper"),
+		history: history,
+		signal:  sig,
+	}
+}
+
+func (d *dumper) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	dumpSignals := make(chan os.Signal, 1)
+	signal.Notify(dumpSignals, d.signal)
+	defer signal.Stop(dumpSignals)
+
+	close(ready)
+
+	for {
+		select {
+		case <-dumpSignals:
+			d.dump()
+		case <-signals:
+			return nil
+		}
+	}
+}
+
+func (d *dumper) dump() {
+	d.logger.Info("operation-history", lager.Data{"entries": d.history.All()})
+}
@@ -0,0 +1,201 @@
+package history
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+)
+
+// Call is a BBS request made while executing an operation, or a BBS request
+// or container action that was only shadowed when the generator runs in
+// shadow mode.
+type Call struct {
+	Name     string        `json:"name"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Shadowed bool          `json:"shadowed,omitempty"`
+}
+
+// Entry describes a single executed operation: which processor sessions it
+// went through and the BBS requests it made.
+type Entry struct {
+	Operation string        `json:"operation"`
+	Guid      string        `json:"guid"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Path      []string      `json:"path"`
+	Calls     []Call        `json:"calls"`
+}
+
+// History keeps the most recent executed operations in a ring buffer. A nil
+// History records nothing, so callers do not need to check whether it is
+// enabled.
+type History struct {
+	clock clock.Clock
+
+	lock    sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+}
+
+func New(size int, clock clock.Clock) *History {
+	if size <= 0 {
+		return nil
+	}
+
+	return &History{
+		clock:   clock,
+		entries: make([]Entry, size),
+	}
+}
+
+// Start begins recording an operation. Sessions created from the returned
+// logger are recorded as the path taken, and BBS requests made with it are
+// recorded as calls when reported through RecordCall.
+func (h *History) Start(logger lager.Logger, operation, guid string) (lager.Logger, *Recording) {
+	if h == nil {
+		return logger, nil
+	}
+
+	recording := &Recording{
+		history: h,
+		entry: Entry{
+			Operation: operation,
+			Guid:      guid,
+			StartedAt: h.clock.Now(),
+		},
+	}
+
+	return &recordingLogger{Logger: logger, recording: recording}, recording
+}
+
+// ByGuid returns the recorded operations for the guid, oldest first.
+func (h *History) ByGuid(guid string) []Entry {
+	entries := []Entry{}
+	for _, entry := range h.All() {
+		if entry.Guid == guid {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// All returns every recorded operation, oldest first.
+func (h *History) All() []Entry {
+	if h == nil {
+		return []Entry{}
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	var entries []Entry
+	if h.full {
+		entries = append(entries, h.entries[h.next:]...)
+	}
+	entries = append(entries, h.entries[:h.next]...)
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedAt.Before(entries[j].StartedAt)
+	})
+
+	return entries
+}
+
+func (h *History) add(entry Entry) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.entries[h.next] = entry
+	h.next++
+	if h.next == len(h.entries) {
+		h.next = 0
+		h.full = true
+	}
+}
+
+// Recording collects an operation until Finish adds it to the history.
+type Recording struct {
+	history *History
+
+	lock  sync.Mutex
+	entry Entry
+}
+
+func (r *Recording) Finish() {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	entry := r.entry
+	entry.Duration = r.history.clock.Since(entry.StartedAt)
+	r.lock.Unlock()
+
+	r.history.add(entry)
+}
+
+func (r *Recording) session(task string) {
+	r.lock.Lock()
+	r.entry.Path = append(r.entry.Path, task)
+	r.lock.Unlock()
+}
+
+func (r *Recording) call(call Call) {
+	r.lock.Lock()
+	r.entry.Calls = append(r.entry.Calls, call)
+	r.lock.Unlock()
+}
+
+type recordingLogger struct {
+	lager.Logger
+	recording *Recording
+}
+
+func (l *recordingLogger) Session(task string, data ...lager.Data) lager.Logger {
+	l.recording.session(task)
+	return &recordingLogger{Logger: l.Logger.Session(task, data...), recording: l.recording}
+}
+
+func (l *recordingLogger) WithData(data lager.Data) lager.Logger {
+	return &recordingLogger{Logger: l.Logger.WithData(data), recording: l.recording}
+}
+
+// Unwrap returns the logger to hand to a wrapped client, so that sessions it
+// creates are not recorded as part of the path.
+func Unwrap(logger lager.Logger) lager.Logger {
+	if l, ok := logger.(*recordingLogger); ok {
+		return l.Logger
+	}
+	return logger
+}
+
+// RecordCall adds a BBS request made with the logger to the operation the
+// logger is recording, if any.
+func RecordCall(logger lager.Logger, name string, duration time.Duration, err error) {
+	l, ok := logger.(*recordingLogger)
+	if !ok {
+		return
+	}
+
+	call := Call{Name: name, Duration: duration}
+	if err != nil {
+		call.Error = err.Error()
+	}
+	l.recording.call(call)
+}
+
+// RecordShadowedCall adds a BBS request or container action that was skipped
+// in shadow mode to the operation the logger is recording, if any.
+func RecordShadowedCall(logger lager.Logger, name string) {
+	l, ok := logger.(*recordingLogger)
+	if !ok {
+		return
+	}
+
+	l.recording.call(Call{Name: name, Shadowed: true})
+}
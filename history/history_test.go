@@ -0,0 +1,109 @@
+package history_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/rep/history"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("History", func() {
+	var (
+		logger    *lagertest.TestLogger
+		fakeClock *fakeclock.FakeClock
+		h         *history.History
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		h = history.New(2, fakeClock)
+	})
+
+	It("records the path and the BBS calls of an operation", func() {
+		opLogger, recording := h.Start(logger, "container", "some-guid")
+		opLogger = opLogger.Session("task-processor")
+		fakeClock.Increment(time.Second)
+		history.RecordCall(opLogger, "StartTask", time.Second, errors.New("boom"))
+		history.RecordCall(opLogger.Session("process-completed-container"), "RemoveActualLRP", 0, nil)
+		recording.Finish()
+
+		entries := h.ByGuid("some-guid")
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Operation).To(Equal("container"))
+		Expect(entries[0].Duration).To(Equal(time.Second))
+		Expect(entries[0].Path).To(Equal([]string{"task-processor", "process-completed-container"}))
+		Expect(entries[0].Calls).To(Equal([]history.Call{
+			{Name: "StartTask", Error: "boom", Duration: time.Second},
+			{Name: "RemoveActualLRP"},
+		}))
+	})
+
+	It("does not record sessions created by wrapped clients", func() {
+		opLogger, recording := h.Start(logger, "container", "some-guid")
+		opLogger = opLogger.Session("task-processor")
+		innerLogger := history.Unwrap(opLogger).Session("do-request")
+		innerLogger.Session("request")
+		history.RecordCall(opLogger, "StartTask", 0, nil)
+		recording.Finish()
+
+		entries := h.ByGuid("some-guid")
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Path).To(Equal([]string{"task-processor"}))
+		Expect(entries[0].Calls).To(Equal([]history.Call{{Name: "StartTask"}}))
+	})
+
+	It("leaves loggers without a recording as they are", func() {
+		Expect(history.Unwrap(logger)).To(Equal(logger))
+	})
+
+	It("records shadowed calls", func() {
+		opLogger, recording := h.Start(logger, "container", "some-guid")
+		history.RecordShadowedCall(opLogger, "RunContainer")
+		recording.Finish()
+
+		entries := h.ByGuid("some-guid")
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Calls).To(Equal([]history.Call{
+			{Name: "RunContainer", Shadowed: true},
+		}))
+	})
+
+	It("ignores calls made without a recording", func() {
+		history.RecordCall(logger, "StartTask", time.Second, nil)
+		Expect(h.All()).To(BeEmpty())
+	})
+
+	It("only keeps the most recent operations", func() {
+		for _, guid := range []string{"guid-1", "guid-2", "guid-3"} {
+			_, recording := h.Start(logger, "container", guid)
+			recording.Finish()
+			fakeClock.Increment(time.Second)
+		}
+
+		entries := h.All()
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Guid).To(Equal("guid-2"))
+		Expect(entries[1].Guid).To(Equal("guid-3"))
+		Expect(h.ByGuid("guid-1")).To(BeEmpty())
+	})
+
+	Context("when the history is disabled", func() {
+		BeforeEach(func() {
+			h = history.New(0, fakeClock)
+		})
+
+		It("records nothing", func() {
+			opLogger, recording := h.Start(logger, "container", "some-guid")
+			Expect(opLogger).To(BeIdenticalTo(logger))
+			recording.Finish()
+
+			Expect(h.All()).To(BeEmpty())
+		})
+	})
+})
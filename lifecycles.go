@@ -0,0 +1,178 @@
+package rep
+
+import (
+	"sort"
+	"sync"
+
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/executor/containermetrics"
+	"code.cloudfoundry.org/lager"
+)
+
+// ContainerProcessor harmonizes a container of a lifecycle with the BBS.
+type ContainerProcessor interface {
+	Process(logger lager.Logger, container executor.Container)
+}
+
+type ContainerProcessorFunc func(logger lager.Logger, container executor.Container)
+
+func (f ContainerProcessorFunc) Process(logger lager.Logger, container executor.Container) {
+	f(logger, container)
+}
+
+// CellStateReporter adds a container of a lifecycle to the cell state
+// reported to the auctioneer.
+type CellStateReporter interface {
+	ReportContainer(logger lager.Logger, container executor.Container, state *CellState)
+}
+
+type CellStateReporterFunc func(logger lager.Logger, container executor.Container, state *CellState)
+
+func (f CellStateReporterFunc) ReportContainer(logger lager.Logger, container executor.Container, state *CellState) {
+	f(logger, container, state)
+}
+
+// ContainerMetricsReporter adds the metrics of a container of a lifecycle to
+// the collection reported for the cell.
+type ContainerMetricsReporter interface {
+	ReportMetrics(logger lager.Logger, container executor.Container, metrics containermetrics.CachedContainerMetrics, collection *ContainerMetricsCollection)
+}
+
+type ContainerMetricsReporterFunc func(logger lager.Logger, container executor.Container, metrics containermetrics.CachedContainerMetrics, collection *ContainerMetricsCollection)
+
+func (f ContainerMetricsReporterFunc) ReportMetrics(logger lager.Logger, container executor.Container, metrics containermetrics.CachedContainerMetrics, collection *ContainerMetricsCollection) {
+	f(logger, container, metrics, collection)
+}
+
+// LifecycleRegistry maps the lifecycle tag of a container to how the rep
+// processes it and reports it in the cell state, so that new kinds of
+// workload can be added without changing the generator or the cell rep.
+// Containers whose lifecycle has no processor are not harmonized, and those
+// without a reporter are left out of the cell state and container metrics.
+//
+// A lifecycle is cell managed when the rep itself runs its containers, which
+// then have no BBS record and are not waited for when the cell evacuates. It
+// is evacuated when its containers move to other cells while the cell
+// evacuates.
+type LifecycleRegistry struct {
+	lock             sync.RWMutex
+	processors       map[string]ContainerProcessor
+	reporters        map[string]CellStateReporter
+	metricsReporters map[string]ContainerMetricsReporter
+	cellManaged      map[string]struct{}
+	evacuated        map[string]struct{}
+}
+
+func NewLifecycleRegistry() *LifecycleRegistry {
+	return &LifecycleRegistry{
+		processors:       map[string]ContainerProcessor{},
+		reporters:        map[string]CellStateReporter{},
+		metricsReporters: map[string]ContainerMetricsReporter{},
+		cellManaged:      map[string]struct{}{},
+		evacuated:        map[string]struct{}{},
+	}
+}
+
+func (r *LifecycleRegistry) RegisterProcessor(lifecycle string, processor ContainerProcessor) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.processors[lifecycle] = processor
+}
+
+func (r *LifecycleRegistry) RegisterReporter(lifecycle string, reporter CellStateReporter) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.reporters[lifecycle] = reporter
+}
+
+func (r *LifecycleRegistry) RegisterMetricsReporter(lifecycle string, reporter ContainerMetricsReporter) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.metricsReporters[lifecycle] = reporter
+}
+
+func (r *LifecycleRegistry) RegisterCellManaged(lifecycle string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.cellManaged[lifecycle] = struct{}{}
+}
+
+func (r *LifecycleRegistry) RegisterEvacuated(lifecycle string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.evacuated[lifecycle] = struct{}{}
+}
+
+func (r *LifecycleRegistry) Processor(lifecycle string) (ContainerProcessor, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	processor, ok := r.processors[lifecycle]
+	return processor, ok
+}
+
+func (r *LifecycleRegistry) Reporter(lifecycle string) (CellStateReporter, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	reporter, ok := r.reporters[lifecycle]
+	return reporter, ok
+}
+
+func (r *LifecycleRegistry) MetricsReporter(lifecycle string) (ContainerMetricsReporter, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	reporter, ok := r.metricsReporters[lifecycle]
+	return reporter, ok
+}
+
+func (r *LifecycleRegistry) CellManaged(lifecycle string) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	_, ok := r.cellManaged[lifecycle]
+	return ok
+}
+
+func (r *LifecycleRegistry) Evacuated(lifecycle string) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	_, ok := r.evacuated[lifecycle]
+	return ok
+}
+
+// Known is true for lifecycles with a processor or a reporter.
+func (r *LifecycleRegistry) Known(lifecycle string) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if _, ok := r.processors[lifecycle]; ok {
+		return true
+	}
+	if _, ok := r.reporters[lifecycle]; ok {
+		return true
+	}
+	_, ok := r.metricsReporters[lifecycle]
+	return ok
+}
+
+// Lifecycles returns every lifecycle with a processor or a reporter, sorted.
+func (r *LifecycleRegistry) Lifecycles() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	seen := map[string]struct{}{}
+	for lifecycle := range r.processors {
+		seen[lifecycle] = struct{}{}
+	}
+	for lifecycle := range r.reporters {
+		seen[lifecycle] = struct{}{}
+	}
+	for lifecycle := range r.metricsReporters {
+		seen[lifecycle] = struct{}{}
+	}
+
+	lifecycles := make([]string, 0, len(seen))
+	for lifecycle := range seen {
+		lifecycles = append(lifecycles, lifecycle)
+	}
+	sort.Strings(lifecycles)
+	return lifecycles
+}
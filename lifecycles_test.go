@@ -0,0 +1,99 @@
+package rep_test
+
+import (
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/executor/containermetrics"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/rep"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LifecycleRegistry", func() {
+	var (
+		registry *rep.LifecycleRegistry
+		logger   *lagertest.TestLogger
+	)
+
+	BeforeEach(func() {
+		registry = rep.NewLifecycleRegistry()
+		logger = lagertest.NewTestLogger("test")
+	})
+
+	It("returns the processor registered for a lifecycle", func() {
+		var processed []string
+		registry.RegisterProcessor("daemon", rep.ContainerProcessorFunc(func(_ lager.Logger, container executor.Container) {
+			processed = append(processed, container.Guid)
+		}))
+
+		processor, ok := registry.Processor("daemon")
+		Expect(ok).To(BeTrue())
+		processor.Process(logger, executor.Container{Guid: "some-guid"})
+		Expect(processed).To(ConsistOf("some-guid"))
+
+		_, ok = registry.Processor("unknown")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("returns the reporter registered for a lifecycle", func() {
+		registry.RegisterReporter("daemon", rep.CellStateReporterFunc(func(_ lager.Logger, _ executor.Container, state *rep.CellState) {
+			state.StartingContainerCount++
+		}))
+
+		reporter, ok := registry.Reporter("daemon")
+		Expect(ok).To(BeTrue())
+		state := rep.CellState{}
+		reporter.ReportContainer(logger, executor.Container{}, &state)
+		Expect(state.StartingContainerCount).To(Equal(1))
+
+		_, ok = registry.Reporter("unknown")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("returns the metrics reporter registered for a lifecycle", func() {
+		registry.RegisterMetricsReporter("task", rep.ContainerMetricsReporterFunc(func(_ lager.Logger, container executor.Container, metrics containermetrics.CachedContainerMetrics, collection *rep.ContainerMetricsCollection) {
+			collection.Tasks = append(collection.Tasks, rep.TaskMetric{TaskGUID: container.Guid, CachedContainerMetrics: metrics})
+		}))
+
+		reporter, ok := registry.MetricsReporter("task")
+		Expect(ok).To(BeTrue())
+		collection := rep.ContainerMetricsCollection{}
+		reporter.ReportMetrics(logger, executor.Container{Guid: "some-guid"}, containermetrics.CachedContainerMetrics{CPUUsageFraction: 0.5}, &collection)
+		Expect(collection.Tasks).To(ConsistOf(rep.TaskMetric{TaskGUID: "some-guid", CachedContainerMetrics: containermetrics.CachedContainerMetrics{CPUUsageFraction: 0.5}}))
+
+		_, ok = registry.MetricsReporter("unknown")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("knows lifecycles with a processor, reporter or metrics reporter", func() {
+		registry.RegisterProcessor("lrp", rep.ContainerProcessorFunc(func(lager.Logger, executor.Container) {}))
+		registry.RegisterReporter("daemon", rep.CellStateReporterFunc(func(lager.Logger, executor.Container, *rep.CellState) {}))
+		registry.RegisterMetricsReporter("task", rep.ContainerMetricsReporterFunc(func(lager.Logger, executor.Container, containermetrics.CachedContainerMetrics, *rep.ContainerMetricsCollection) {
+		}))
+
+		Expect(registry.Known("lrp")).To(BeTrue())
+		Expect(registry.Known("daemon")).To(BeTrue())
+		Expect(registry.Known("task")).To(BeTrue())
+		Expect(registry.Known("unknown")).To(BeFalse())
+	})
+
+	It("tracks cell managed and evacuated lifecycles", func() {
+		registry.RegisterCellManaged("daemon")
+		registry.RegisterEvacuated("lrp")
+
+		Expect(registry.CellManaged("daemon")).To(BeTrue())
+		Expect(registry.CellManaged("lrp")).To(BeFalse())
+		Expect(registry.Evacuated("lrp")).To(BeTrue())
+		Expect(registry.Evacuated("daemon")).To(BeFalse())
+	})
+
+	It("lists every registered lifecycle once, sorted", func() {
+		registry.RegisterProcessor("task", rep.ContainerProcessorFunc(func(lager.Logger, executor.Container) {}))
+		registry.RegisterReporter("task", rep.CellStateReporterFunc(func(lager.Logger, executor.Container, *rep.CellState) {}))
+		registry.RegisterReporter("daemon", rep.CellStateReporterFunc(func(lager.Logger, executor.Container, *rep.CellState) {}))
+
+		Expect(registry.Lifecycles()).To(Equal([]string{"daemon", "task"}))
+	})
+})
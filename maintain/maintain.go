@@ -3,6 +3,7 @@ package maintain
 import (
 	"errors"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"code.cloudfoundry.org/bbs/models"
@@ -19,6 +20,7 @@ type Maintainer struct {
 	logger         lager.Logger
 	lockTTL        time.Duration
 	clock          clock.Clock
+	presenceHeld   int32
 }
 
 type Config struct {
@@ -134,6 +136,9 @@ func (m *Maintainer) heartbeat(sigChan <-chan os.Signal, ready chan<- struct{},
 		return nil
 	}
 
+	atomic.StoreInt32(&m.presenceHeld, 1)
+	defer atomic.StoreInt32(&m.presenceHeld, 0)
+
 	if ready != nil {
 		close(ready)
 	}
@@ -170,3 +175,9 @@ func (m *Maintainer) heartbeat(sigChan <-chan os.Signal, ready chan<- struct{},
 		}
 	}
 }
+
+// PresenceHeld reports whether the maintainer is currently heartbeating the
+// cell presence.
+func (m *Maintainer) PresenceHeld() bool {
+	return atomic.LoadInt32(&m.presenceHeld) == 1
+}
@@ -29,7 +29,7 @@ var _ = Describe("Maintain Presence", func() {
 		serviceClient   *maintainfakes.FakeCellPresenceClient
 		logger          *lagertest.TestLogger
 
-		maintainer        ifrit.Runner
+		maintainer        *maintain.Maintainer
 		maintainProcess   ifrit.Process
 		heartbeaterErrors chan error
 		observedSignals   chan os.Signal
@@ -146,6 +146,10 @@ var _ = Describe("Maintain Presence", func() {
 				maintainProcess = ifrit.Background(maintainer)
 			})
 
+			It("does not report the presence as held", func() {
+				Consistently(maintainer.PresenceHeld).Should(BeFalse())
+			})
+
 			It("exits when signaled", func() {
 				maintainProcess.Signal(os.Interrupt)
 				var err error
@@ -202,6 +206,10 @@ var _ = Describe("Maintain Presence", func() {
 				Eventually(fakeHeartbeater.RunCallCount).Should(Equal(1))
 			})
 
+			It("reports the presence as held", func() {
+				Expect(maintainer.PresenceHeld()).To(BeTrue())
+			})
+
 			It("continues pings the executor on an interval", func() {
 				for i := 2; i < 6; i++ {
 					pingErrors <- nil
@@ -221,6 +229,10 @@ var _ = Describe("Maintain Presence", func() {
 					Eventually(observedSignals).Should(Receive(Equal(os.Kill)))
 				})
 
+				It("no longer reports the presence as held", func() {
+					Eventually(maintainer.PresenceHeld).Should(BeFalse())
+				})
+
 				It("continues pinging the executor", func() {
 					clock.Increment(1 * time.Second)
 					for i := 4; i < 8; i++ {
@@ -0,0 +1,60 @@
+package maintain
+
+import (
+	"os"
+	"sync/atomic"
+
+	"github.com/tedsuo/ifrit"
+)
+
+// PresenceTracker wraps a presence runner and reports the presence as held
+// from the moment the runner becomes ready until it exits.
+type PresenceTracker struct {
+	runner ifrit.Runner
+	held   int32
+}
+
+func NewPresenceTracker(runner ifrit.Runner) *PresenceTracker {
+	return &PresenceTracker{
+		runner: runner,
+	}
+}
+
+func (t *PresenceTracker) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	process := ifrit.Background(t.runner)
+	defer atomic.StoreInt32(&t.held, 0)
+
+	for {
+		select {
+		case <-process.Ready():
+			atomic.StoreInt32(&t.held, 1)
+			close(ready)
+			return t.wait(process, signals)
+		case err := <-process.Wait():
+			return err
+		case signal := <-signals:
+			process.Signal(signal)
+		}
+	}
+}
+
+func (t *PresenceTracker) wait(process ifrit.Process, signals <-chan os.Signal) error {
+	for {
+		select {
+		case err := <-process.Wait():
+			return err
+		case signal := <-signals:
+			process.Signal(signal)
+		}
+	}
+}
+
+func (t *PresenceTracker) PresenceHeld() bool {
+	return atomic.LoadInt32(&t.held) == 1
+}
+
+// PresenceRunner maintains the cell presence and reports whether it is held.
+type PresenceRunner interface {
+	ifrit.Runner
+	PresenceHeld() bool
+}
@@ -0,0 +1,87 @@
+package maintain_test
+
+import (
+	"errors"
+	"os"
+
+	"code.cloudfoundry.org/rep/maintain"
+	"code.cloudfoundry.org/rep/maintain/maintainfakes"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PresenceTracker", func() {
+	var (
+		fakeRunner  *maintainfakes.FakeRunner
+		becomeReady chan struct{}
+		runErrors   chan error
+
+		tracker *maintain.PresenceTracker
+		process ifrit.Process
+	)
+
+	BeforeEach(func() {
+		becomeReady = make(chan struct{})
+		runErrors = make(chan error, 1)
+
+		fakeRunner = &maintainfakes.FakeRunner{
+			RunStub: func(signals <-chan os.Signal, ready chan<- struct{}) error {
+				select {
+				case <-becomeReady:
+					close(ready)
+				case err := <-runErrors:
+					return err
+				case <-signals:
+					return nil
+				}
+
+				select {
+				case err := <-runErrors:
+					return err
+				case <-signals:
+					return nil
+				}
+			},
+		}
+
+		tracker = maintain.NewPresenceTracker(fakeRunner)
+		process = ifrit.Background(tracker)
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive())
+	})
+
+	Context("before the presence runner is ready", func() {
+		It("does not report the presence as held", func() {
+			Consistently(tracker.PresenceHeld).Should(BeFalse())
+			Expect(process.Ready()).NotTo(BeClosed())
+		})
+	})
+
+	Context("when the presence runner becomes ready", func() {
+		BeforeEach(func() {
+			close(becomeReady)
+		})
+
+		It("becomes ready and reports the presence as held", func() {
+			Eventually(process.Ready()).Should(BeClosed())
+			Expect(tracker.PresenceHeld()).To(BeTrue())
+		})
+
+		Context("and then exits", func() {
+			BeforeEach(func() {
+				Eventually(process.Ready()).Should(BeClosed())
+				runErrors <- errors.New("lost lock")
+			})
+
+			It("exits with the runner's error and no longer reports the presence as held", func() {
+				Eventually(process.Wait()).Should(Receive(MatchError("lost lock")))
+				Expect(tracker.PresenceHeld()).To(BeFalse())
+			})
+		})
+	})
+})
@@ -0,0 +1,208 @@
+package ratelimit
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock"
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/lager"
+)
+
+const (
+	bbsReadThrottleWaitMetric  = "RepBBSReadThrottleWait"
+	bbsWriteThrottleWaitMetric = "RepBBSWriteThrottleWait"
+)
+
+// ErrCanceled is returned for requests that were still waiting for their
+// budget when the client was canceled. Canceled evacuation requests keep the
+// container, as failed ones do.
+var ErrCanceled = errors.New("bbs request canceled while waiting for its rate limit")
+
+type bbsClient struct {
+	bbs.InternalClient
+	reads        *Bucket
+	writes       *Bucket
+	metronClient loggingclient.IngressClient
+	cancel       <-chan struct{}
+}
+
+// NewBBSClient wraps the client so that the requests the rep makes to the BBS
+// are limited to the budgets: reads of LRPs and tasks to one, and requests
+// that change them to the other. Requests that had to wait for the budget
+// report how long they waited. Closing cancel fails the requests that are
+// waiting with ErrCanceled, so that they do not hold up the rep shutting down.
+// The client is returned as is when neither budget limits anything.
+func NewBBSClient(
+	client bbs.InternalClient,
+	reads Budget,
+	writes Budget,
+	clock clock.Clock,
+	metronClient loggingclient.IngressClient,
+	cancel <-chan struct{},
+) bbs.InternalClient {
+	if reads.unlimited() && writes.unlimited() {
+		return client
+	}
+
+	return &bbsClient{
+		InternalClient: client,
+		reads:          NewBucket(reads, clock),
+		writes:         NewBucket(writes, clock),
+		metronClient:   metronClient,
+		cancel:         cancel,
+	}
+}
+
+// Uncancelable returns a client sharing the budgets of a client returned by
+// NewBBSClient whose requests are not canceled, for requests that are made
+// while the rep shuts down. Any other client is returned as is.
+func Uncancelable(client bbs.InternalClient) bbs.InternalClient {
+	limited, ok := client.(*bbsClient)
+	if !ok {
+		return client
+	}
+
+	uncancelable := *limited
+	uncancelable.cancel = nil
+	return &uncancelable
+}
+
+func (c *bbsClient) read(logger lager.Logger, name string) error {
+	return c.throttle(logger, c.reads, name, bbsReadThrottleWaitMetric)
+}
+
+func (c *bbsClient) write(logger lager.Logger, name string) error {
+	return c.throttle(logger, c.writes, name, bbsWriteThrottleWaitMetric)
+}
+
+func (c *bbsClient) throttle(logger lager.Logger, bucket *Bucket, name, metric string) error {
+	waited, ok := bucket.Wait(c.cancel)
+	if !ok {
+		logger.Info("canceled-throttled-bbs-call", lager.Data{"call": name, "waited": waited.String()})
+		return ErrCanceled
+	}
+	if waited == 0 {
+		return nil
+	}
+
+	logger.Debug("throttled-bbs-call", lager.Data{"call": name, "waited": waited.String()})
+	err := c.metronClient.SendDuration(metric, waited)
+	if err != nil {
+		logger.Error("failed-to-send-throttle-wait-metric", err)
+	}
+	return nil
+}
+
+func (c *bbsClient) ActualLRPGroups(logger lager.Logger, filter models.ActualLRPFilter) ([]*models.ActualLRPGroup, error) {
+	if err := c.read(logger, "ActualLRPGroups"); err != nil {
+		return nil, err
+	}
+	return c.InternalClient.ActualLRPGroups(logger, filter)
+}
+
+func (c *bbsClient) TasksByCellID(logger lager.Logger, cellID string) ([]*models.Task, error) {
+	if err := c.read(logger, "TasksByCellID"); err != nil {
+		return nil, err
+	}
+	return c.InternalClient.TasksByCellID(logger, cellID)
+}
+
+func (c *bbsClient) DesiredLRPByProcessGuid(logger lager.Logger, processGuid string) (*models.DesiredLRP, error) {
+	if err := c.read(logger, "DesiredLRPByProcessGuid"); err != nil {
+		return nil, err
+	}
+	return c.InternalClient.DesiredLRPByProcessGuid(logger, processGuid)
+}
+
+func (c *bbsClient) TaskByGuid(logger lager.Logger, taskGuid string) (*models.Task, error) {
+	if err := c.read(logger, "TaskByGuid"); err != nil {
+		return nil, err
+	}
+	return c.InternalClient.TaskByGuid(logger, taskGuid)
+}
+
+func (c *bbsClient) ClaimActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey) error {
+	if err := c.write(logger, "ClaimActualLRP"); err != nil {
+		return err
+	}
+	return c.InternalClient.ClaimActualLRP(logger, key, instanceKey)
+}
+
+func (c *bbsClient) StartActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey, netInfo *models.ActualLRPNetInfo) error {
+	if err := c.write(logger, "StartActualLRP"); err != nil {
+		return err
+	}
+	return c.InternalClient.StartActualLRP(logger, key, instanceKey, netInfo)
+}
+
+func (c *bbsClient) CrashActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey, errorMessage string) error {
+	if err := c.write(logger, "CrashActualLRP"); err != nil {
+		return err
+	}
+	return c.InternalClient.CrashActualLRP(logger, key, instanceKey, errorMessage)
+}
+
+func (c *bbsClient) RemoveActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey) error {
+	if err := c.write(logger, "RemoveActualLRP"); err != nil {
+		return err
+	}
+	return c.InternalClient.RemoveActualLRP(logger, key, instanceKey)
+}
+
+func (c *bbsClient) EvacuateClaimedActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey) (bool, error) {
+	if err := c.write(logger, "EvacuateClaimedActualLRP"); err != nil {
+		return true, err
+	}
+	return c.InternalClient.EvacuateClaimedActualLRP(logger, key, instanceKey)
+}
+
+func (c *bbsClient) EvacuateRunningActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey, netInfo *models.ActualLRPNetInfo, ttl uint64) (bool, error) {
+	if err := c.write(logger, "EvacuateRunningActualLRP"); err != nil {
+		return true, err
+	}
+	return c.InternalClient.EvacuateRunningActualLRP(logger, key, instanceKey, netInfo, ttl)
+}
+
+func (c *bbsClient) EvacuateStoppedActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey) (bool, error) {
+	if err := c.write(logger, "EvacuateStoppedActualLRP"); err != nil {
+		return true, err
+	}
+	return c.InternalClient.EvacuateStoppedActualLRP(logger, key, instanceKey)
+}
+
+func (c *bbsClient) EvacuateCrashedActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey, errorMessage string) (bool, error) {
+	if err := c.write(logger, "EvacuateCrashedActualLRP"); err != nil {
+		return true, err
+	}
+	return c.InternalClient.EvacuateCrashedActualLRP(logger, key, instanceKey, errorMessage)
+}
+
+func (c *bbsClient) RemoveEvacuatingActualLRP(logger lager.Logger, key *models.ActualLRPKey, instanceKey *models.ActualLRPInstanceKey) error {
+	if err := c.write(logger, "RemoveEvacuatingActualLRP"); err != nil {
+		return err
+	}
+	return c.InternalClient.RemoveEvacuatingActualLRP(logger, key, instanceKey)
+}
+
+func (c *bbsClient) StartTask(logger lager.Logger, taskGuid, cellID string) (bool, error) {
+	if err := c.write(logger, "StartTask"); err != nil {
+		return false, err
+	}
+	return c.InternalClient.StartTask(logger, taskGuid, cellID)
+}
+
+func (c *bbsClient) CompleteTask(logger lager.Logger, taskGuid, cellID string, failed bool, failureReason, result string) error {
+	if err := c.write(logger, "CompleteTask"); err != nil {
+		return err
+	}
+	return c.InternalClient.CompleteTask(logger, taskGuid, cellID, failed, failureReason, result)
+}
+
+func (c *bbsClient) RejectTask(logger lager.Logger, taskGuid, failureReason string) error {
+	if err := c.write(logger, "RejectTask"); err != nil {
+		return err
+	}
+	return c.InternalClient.RejectTask(logger, taskGuid, failureReason)
+}
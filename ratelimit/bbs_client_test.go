@@ -0,0 +1,146 @@
+package ratelimit_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/fake_bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/rep/ratelimit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BBSClient", func() {
+	var (
+		logger           *lagertest.TestLogger
+		fakeClock        *fakeclock.FakeClock
+		fakeBBS          *fake_bbs.FakeInternalClient
+		fakeMetronClient *mfakes.FakeIngressClient
+		reads, writes    ratelimit.Budget
+		cancel           chan struct{}
+		client           bbs.InternalClient
+
+		lrpKey      models.ActualLRPKey
+		instanceKey models.ActualLRPInstanceKey
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeBBS = new(fake_bbs.FakeInternalClient)
+		fakeMetronClient = new(mfakes.FakeIngressClient)
+		reads = ratelimit.Budget{RequestsPerSecond: 1, Burst: 1}
+		writes = ratelimit.Budget{RequestsPerSecond: 1, Burst: 1}
+		cancel = make(chan struct{})
+
+		lrpKey = models.NewActualLRPKey("process-guid", 0, "domain")
+		instanceKey = models.NewActualLRPInstanceKey("instance-guid", "cell-id")
+	})
+
+	JustBeforeEach(func() {
+		client = ratelimit.NewBBSClient(fakeBBS, reads, writes, fakeClock, fakeMetronClient, cancel)
+	})
+
+	It("limits reads and writes separately", func() {
+		_, err := client.DesiredLRPByProcessGuid(logger, "process-guid")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.ClaimActualLRP(logger, &lrpKey, &instanceKey)).To(Succeed())
+
+		Expect(fakeBBS.DesiredLRPByProcessGuidCallCount()).To(Equal(1))
+		Expect(fakeBBS.ClaimActualLRPCallCount()).To(Equal(1))
+		Expect(fakeClock.WatcherCount()).To(BeZero())
+	})
+
+	It("makes requests over the budget wait and reports how long they waited", func() {
+		Expect(client.RejectTask(logger, "task-guid", "no room")).To(Succeed())
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			_, err := client.StartTask(logger, "task-guid", "cell-id")
+			Expect(err).NotTo(HaveOccurred())
+			close(done)
+		}()
+
+		Eventually(fakeClock.WatcherCount).Should(Equal(1))
+		Expect(fakeBBS.StartTaskCallCount()).To(BeZero())
+
+		fakeClock.Increment(time.Second)
+		Eventually(done).Should(BeClosed())
+		Expect(fakeBBS.StartTaskCallCount()).To(Equal(1))
+
+		Expect(fakeMetronClient.SendDurationCallCount()).To(Equal(1))
+		name, value, _ := fakeMetronClient.SendDurationArgsForCall(0)
+		Expect(name).To(Equal("RepBBSWriteThrottleWait"))
+		Expect(value).To(Equal(time.Second))
+	})
+
+	It("fails requests waiting for their budget once canceled", func() {
+		Expect(client.RejectTask(logger, "task-guid", "no room")).To(Succeed())
+
+		errs := make(chan error, 1)
+		go func() {
+			errs <- client.CompleteTask(logger, "task-guid", "cell-id", false, "", "")
+		}()
+		Eventually(fakeClock.WatcherCount).Should(Equal(1))
+
+		close(cancel)
+		Eventually(errs).Should(Receive(Equal(ratelimit.ErrCanceled)))
+		Expect(fakeBBS.CompleteTaskCallCount()).To(BeZero())
+	})
+
+	It("keeps the container of canceled evacuation requests", func() {
+		_, err := client.EvacuateClaimedActualLRP(logger, &lrpKey, &instanceKey)
+		Expect(err).NotTo(HaveOccurred())
+
+		close(cancel)
+		keepContainer, err := client.EvacuateRunningActualLRP(logger, &lrpKey, &instanceKey, &models.ActualLRPNetInfo{}, 0)
+		Expect(err).To(Equal(ratelimit.ErrCanceled))
+		Expect(keepContainer).To(BeTrue())
+		Expect(fakeBBS.EvacuateRunningActualLRPCallCount()).To(BeZero())
+	})
+
+	Describe("Uncancelable", func() {
+		It("shares the budgets but does not cancel requests", func() {
+			Expect(client.RejectTask(logger, "task-guid", "no room")).To(Succeed())
+			close(cancel)
+
+			errs := make(chan error, 1)
+			go func() {
+				errs <- ratelimit.Uncancelable(client).CompleteTask(logger, "task-guid", "cell-id", false, "", "")
+			}()
+			Eventually(fakeClock.WatcherCount).Should(Equal(1))
+			Consistently(errs).ShouldNot(Receive())
+
+			fakeClock.Increment(time.Second)
+			Eventually(errs).Should(Receive(BeNil()))
+			Expect(fakeBBS.CompleteTaskCallCount()).To(Equal(1))
+		})
+	})
+
+	It("does not report requests that did not wait", func() {
+		_, err := client.TaskByGuid(logger, "task-guid")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeMetronClient.SendDurationCallCount()).To(BeZero())
+	})
+
+	Context("when neither budget has a rate", func() {
+		BeforeEach(func() {
+			reads = ratelimit.Budget{}
+			writes = ratelimit.Budget{}
+		})
+
+		It("returns the client unwrapped", func() {
+			Expect(client).To(BeIdenticalTo(fakeBBS))
+		})
+
+		It("keeps the client unwrapped when made uncancelable", func() {
+			Expect(ratelimit.Uncancelable(client)).To(BeIdenticalTo(fakeBBS))
+		})
+	})
+})
@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+// Budget is the rate at which requests may be made, and how many may be made
+// at once after a quiet period. A budget without a rate is unlimited. The
+// burst defaults to one second's worth of requests.
+type Budget struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+func (b Budget) unlimited() bool {
+	return b.RequestsPerSecond <= 0
+}
+
+// Bucket is a token bucket that refills at the rate of its budget. Requests
+// take a token each, and wait in the order they arrived once the bucket is
+// empty.
+type Bucket struct {
+	clock clock.Clock
+	rate  float64
+	burst float64
+
+	lock   sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func NewBucket(budget Budget, clock clock.Clock) *Bucket {
+	burst := float64(budget.Burst)
+	if burst <= 0 {
+		burst = math.Max(1, math.Ceil(budget.RequestsPerSecond))
+	}
+
+	return &Bucket{
+		clock:  clock,
+		rate:   budget.RequestsPerSecond,
+		burst:  burst,
+		tokens: burst,
+		last:   clock.Now(),
+	}
+}
+
+// Wait takes a token, waiting for one to become available if the bucket is
+// empty, and returns how long it waited. It gives the token back and returns
+// false if cancel is closed before the token is available.
+func (b *Bucket) Wait(cancel <-chan struct{}) (time.Duration, bool) {
+	wait := b.reserve()
+	if wait <= 0 {
+		return 0, true
+	}
+
+	started := b.clock.Now()
+	timer := b.clock.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return wait, true
+	case <-cancel:
+		b.refund()
+		return b.clock.Since(started), false
+	}
+}
+
+// reserve takes a token, which may leave the bucket in debt, and returns how
+// long the caller has to wait until the token it took has been refilled.
+func (b *Bucket) reserve() time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := b.clock.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// refund gives back a token that was reserved but not used.
+func (b *Bucket) refund() {
+	b.lock.Lock()
+	b.tokens = math.Min(b.burst, b.tokens+1)
+	b.lock.Unlock()
+}
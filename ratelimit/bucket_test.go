@@ -0,0 +1,115 @@
+package ratelimit_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/rep/ratelimit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bucket", func() {
+	var (
+		fakeClock *fakeclock.FakeClock
+		budget    ratelimit.Budget
+		bucket    *ratelimit.Bucket
+		cancel    chan struct{}
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		budget = ratelimit.Budget{RequestsPerSecond: 2, Burst: 2}
+		cancel = make(chan struct{})
+	})
+
+	JustBeforeEach(func() {
+		bucket = ratelimit.NewBucket(budget, fakeClock)
+	})
+
+	waitNow := func() time.Duration {
+		waited, ok := bucket.Wait(cancel)
+		Expect(ok).To(BeTrue())
+		return waited
+	}
+
+	wait := func() <-chan time.Duration {
+		waited := make(chan time.Duration, 1)
+		go func() {
+			defer GinkgoRecover()
+			waited <- waitNow()
+		}()
+		return waited
+	}
+
+	It("lets a burst of requests through without waiting", func() {
+		Expect(waitNow()).To(BeZero())
+		Expect(waitNow()).To(BeZero())
+	})
+
+	Context("when the bucket is empty", func() {
+		JustBeforeEach(func() {
+			waitNow()
+			waitNow()
+		})
+
+		It("makes requests wait their turn for a refill", func() {
+			first := wait()
+			Eventually(fakeClock.WatcherCount).Should(Equal(1))
+			second := wait()
+			Eventually(fakeClock.WatcherCount).Should(Equal(2))
+
+			fakeClock.Increment(500 * time.Millisecond)
+			Eventually(first).Should(Receive(Equal(500 * time.Millisecond)))
+			Consistently(second).ShouldNot(Receive())
+
+			fakeClock.Increment(500 * time.Millisecond)
+			Eventually(second).Should(Receive(Equal(time.Second)))
+		})
+
+		It("gives the token back when a waiting request is canceled", func() {
+			canceled := make(chan bool, 1)
+			go func() {
+				_, ok := bucket.Wait(cancel)
+				canceled <- !ok
+			}()
+			Eventually(fakeClock.WatcherCount).Should(Equal(1))
+
+			close(cancel)
+			Eventually(canceled).Should(Receive(BeTrue()))
+			Expect(fakeClock.WatcherCount()).To(BeZero())
+
+			cancel = make(chan struct{})
+			waited := wait()
+			Eventually(fakeClock.WatcherCount).Should(Equal(1))
+			fakeClock.Increment(500 * time.Millisecond)
+			Eventually(waited).Should(Receive(Equal(500 * time.Millisecond)))
+		})
+
+		It("refills over time up to the burst", func() {
+			fakeClock.Increment(time.Minute)
+			Expect(waitNow()).To(BeZero())
+			Expect(waitNow()).To(BeZero())
+
+			waited := wait()
+			Eventually(fakeClock.WatcherCount).Should(Equal(1))
+			Consistently(waited).ShouldNot(Receive())
+
+			fakeClock.Increment(500 * time.Millisecond)
+			Eventually(waited).Should(Receive(Equal(500 * time.Millisecond)))
+		})
+	})
+
+	Context("when the budget has no rate", func() {
+		BeforeEach(func() {
+			budget = ratelimit.Budget{}
+		})
+
+		It("never waits", func() {
+			for i := 0; i < 100; i++ {
+				Expect(waitNow()).To(BeZero())
+			}
+		})
+	})
+})
@@ -0,0 +1 @@
+package reaper // import "code.cloudfoundry.org/rep/reaper"
@@ -0,0 +1,202 @@
+package reaper
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/rep"
+)
+
+const (
+	orphanedContainersMetric       = "RepOrphanedContainers"
+	orphanedContainersReapedMetric = "RepOrphanedContainersReaped"
+
+	DefaultInterval    = time.Minute
+	DefaultGracePeriod = 10 * time.Minute
+
+	ReasonMissingLifecycle = "missing lifecycle tag"
+	ReasonUnknownLifecycle = "unknown lifecycle"
+	ReasonInvalidLRPTags   = "invalid lrp tags"
+)
+
+type Config struct {
+	Interval    time.Duration
+	GracePeriod time.Duration
+	DryRun      bool
+}
+
+// Reaper stops and deletes containers the rep cannot attribute to a registered
+// lifecycle. Such containers are never processed by the harmonizer but still
+// consume the cell's capacity. A container is only reaped once it has been
+// orphaned for the grace period, and in dry-run mode it is only logged.
+type Reaper struct {
+	logger         lager.Logger
+	config         Config
+	cellID         string
+	lifecycles     *rep.LifecycleRegistry
+	executorClient executor.Client
+	metronClient   loggingclient.IngressClient
+	clock          clock.Clock
+
+	orphanedSince map[string]time.Time
+}
+
+func New(
+	logger lager.Logger,
+	config Config,
+	cellID string,
+	lifecycles *rep.LifecycleRegistry,
+	executorClient executor.Client,
+	metronClient loggingclient.IngressClient,
+	clock clock.Clock,
+) *Reaper {
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.GracePeriod <= 0 {
+		config.GracePeriod = DefaultGracePeriod
+	}
+
+	return &Reaper{
+		logger:         logger.Session("orphan-reaper"),
+		config:         config,
+		cellID:         cellID,
+		lifecycles:     lifecycles,
+		executorClient: executorClient,
+		metronClient:   metronClient,
+		clock:          clock,
+		orphanedSince:  map[string]time.Time{},
+	}
+}
+
+func (r *Reaper) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	logger := r.logger
+	logger.Info("starting", lager.Data{
+		"interval":     r.config.Interval.String(),
+		"grace-period": r.config.GracePeriod.String(),
+		"dry-run":      r.config.DryRun,
+	})
+	defer logger.Info("finished")
+
+	close(ready)
+
+	ticker := r.clock.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			r.reap(logger)
+		case signal := <-signals:
+			logger.Info("received-signal", lager.Data{"signal": signal.String()})
+			return nil
+		}
+	}
+}
+
+func (r *Reaper) reap(logger lager.Logger) {
+	logger = logger.Session("reap")
+
+	containers, err := r.executorClient.ListContainers(logger)
+	if err != nil {
+		logger.Error("failed-to-list-containers", err)
+		return
+	}
+
+	now := r.clock.Now()
+	orphanedSince := map[string]time.Time{}
+
+	for _, container := range containers {
+		reason := OrphanReason(container, r.cellID, r.lifecycles)
+		if reason == "" {
+			continue
+		}
+
+		since, ok := r.orphanedSince[container.Guid]
+		if !ok {
+			since = now
+			logger.Info("found-orphaned-container", lager.Data{"container-guid": container.Guid, "reason": reason})
+		}
+
+		if now.Sub(since) < r.config.GracePeriod {
+			orphanedSince[container.Guid] = since
+			continue
+		}
+
+		containerLogger := logger.WithData(lager.Data{
+			"container-guid":  container.Guid,
+			"container-state": container.State,
+			"reason":          reason,
+			"orphaned-since":  since,
+		})
+
+		if r.config.DryRun {
+			containerLogger.Info("would-reap-orphaned-container")
+			orphanedSince[container.Guid] = since
+			continue
+		}
+
+		if r.destroy(containerLogger, container.Guid) {
+			err := r.metronClient.IncrementCounter(orphanedContainersReapedMetric)
+			if err != nil {
+				logger.Error("failed-to-increment-reaped-counter", err)
+			}
+		} else {
+			orphanedSince[container.Guid] = since
+		}
+	}
+
+	r.orphanedSince = orphanedSince
+
+	err = r.metronClient.SendMetric(orphanedContainersMetric, len(orphanedSince))
+	if err != nil {
+		logger.Error("failed-to-send-orphaned-containers-metric", err)
+	}
+}
+
+func (r *Reaper) destroy(logger lager.Logger, guid string) bool {
+	logger.Info("reaping-orphaned-container")
+
+	err := r.executorClient.StopContainer(logger, guid)
+	if err != nil && err != executor.ErrContainerNotFound {
+		logger.Info("failed-to-stop-container", lager.Data{"error": err.Error()})
+	}
+
+	err = r.executorClient.DeleteContainer(logger, guid)
+	if err != nil && err != executor.ErrContainerNotFound {
+		logger.Error("failed-to-delete-container", err)
+		return false
+	}
+
+	logger.Info("reaped-orphaned-container")
+	return true
+}
+
+// OrphanReason explains why the container cannot be attributed to a
+// registered lifecycle, or is empty when it can. LRP containers must also
+// carry complete LRP tags.
+func OrphanReason(container executor.Container, cellID string, lifecycles *rep.LifecycleRegistry) string {
+	lifecycle, ok := container.Tags[rep.LifecycleTag]
+	if !ok {
+		return ReasonMissingLifecycle
+	}
+
+	if !lifecycles.Known(lifecycle) {
+		return ReasonUnknownLifecycle
+	}
+
+	if lifecycle == rep.LRPLifecycle {
+		if _, err := rep.ActualLRPKeyFromTags(container.Tags); err != nil {
+			return ReasonInvalidLRPTags
+		}
+		if _, err := rep.ActualLRPInstanceKeyFromContainer(container, cellID); err != nil {
+			return ReasonInvalidLRPTags
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,243 @@
+package reaper_test
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/executor"
+	fake_client "code.cloudfoundry.org/executor/fakes"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/rep"
+	"code.cloudfoundry.org/rep/reaper"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reaper", func() {
+	const (
+		cellID      = "cell-id"
+		interval    = time.Minute
+		gracePeriod = 3 * time.Minute
+	)
+
+	var (
+		logger           *lagertest.TestLogger
+		fakeClock        *fakeclock.FakeClock
+		executorClient   *fake_client.FakeClient
+		fakeMetronClient *mfakes.FakeIngressClient
+		lifecycles       *rep.LifecycleRegistry
+		dryRun           bool
+
+		orphan   executor.Container
+		lrp      executor.Container
+		task     executor.Container
+		process  ifrit.Process
+		runCount int
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		executorClient = new(fake_client.FakeClient)
+		fakeMetronClient = new(mfakes.FakeIngressClient)
+		lifecycles = newLifecycleRegistry()
+		dryRun = false
+		runCount = 0
+
+		orphan = executor.Container{Guid: "orphan-guid", State: executor.StateRunning}
+		lrp = executor.Container{
+			Guid:  "lrp-guid",
+			State: executor.StateRunning,
+			Tags: executor.Tags{
+				rep.LifecycleTag:    rep.LRPLifecycle,
+				rep.DomainTag:       "domain",
+				rep.ProcessGuidTag:  "process-guid",
+				rep.ProcessIndexTag: "0",
+				rep.InstanceGuidTag: "instance-guid",
+			},
+		}
+		task = executor.Container{
+			Guid:  "task-guid",
+			State: executor.StateRunning,
+			Tags:  executor.Tags{rep.LifecycleTag: rep.TaskLifecycle, rep.DomainTag: "domain"},
+		}
+
+		executorClient.ListContainersReturns([]executor.Container{orphan, lrp, task}, nil)
+	})
+
+	JustBeforeEach(func() {
+		orphanReaper := reaper.New(
+			logger,
+			reaper.Config{Interval: interval, GracePeriod: gracePeriod, DryRun: dryRun},
+			cellID,
+			lifecycles,
+			executorClient,
+			fakeMetronClient,
+			fakeClock,
+		)
+		process = ifrit.Invoke(orphanReaper)
+		Eventually(fakeClock.WatcherCount).Should(Equal(1))
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive())
+	})
+
+	tick := func() {
+		runCount++
+		fakeClock.Increment(interval)
+		Eventually(fakeMetronClient.SendMetricCallCount).Should(Equal(runCount))
+	}
+
+	It("emits the number of orphaned containers", func() {
+		tick()
+		name, value, _ := fakeMetronClient.SendMetricArgsForCall(0)
+		Expect(name).To(Equal("RepOrphanedContainers"))
+		Expect(value).To(Equal(1))
+	})
+
+	Context("before the grace period has elapsed", func() {
+		It("does not reap the orphaned container", func() {
+			tick()
+			tick()
+			Expect(executorClient.StopContainerCallCount()).To(Equal(0))
+			Expect(executorClient.DeleteContainerCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("once the grace period has elapsed", func() {
+		It("stops and deletes only the orphaned container", func() {
+			for i := 0; i < 4; i++ {
+				tick()
+			}
+
+			Expect(executorClient.StopContainerCallCount()).To(Equal(1))
+			_, guid := executorClient.StopContainerArgsForCall(0)
+			Expect(guid).To(Equal("orphan-guid"))
+
+			Expect(executorClient.DeleteContainerCallCount()).To(Equal(1))
+			_, guid = executorClient.DeleteContainerArgsForCall(0)
+			Expect(guid).To(Equal("orphan-guid"))
+
+			Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(1))
+			Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("RepOrphanedContainersReaped"))
+		})
+
+		Context("when stopping the container fails", func() {
+			BeforeEach(func() {
+				executorClient.StopContainerReturns(errors.New("boom"))
+			})
+
+			It("still deletes it", func() {
+				for i := 0; i < 4; i++ {
+					tick()
+				}
+				Expect(executorClient.DeleteContainerCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when deleting the container fails", func() {
+			BeforeEach(func() {
+				executorClient.DeleteContainerReturns(errors.New("boom"))
+			})
+
+			It("tries again on the next run", func() {
+				for i := 0; i < 5; i++ {
+					tick()
+				}
+				Expect(executorClient.DeleteContainerCallCount()).To(Equal(2))
+				Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("in dry-run mode", func() {
+			BeforeEach(func() {
+				dryRun = true
+			})
+
+			It("only logs the container", func() {
+				for i := 0; i < 4; i++ {
+					tick()
+				}
+				Expect(logger).To(gbytes.Say("would-reap-orphaned-container"))
+				Expect(executorClient.StopContainerCallCount()).To(Equal(0))
+				Expect(executorClient.DeleteContainerCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Context("when an orphaned container disappears and comes back", func() {
+		It("restarts its grace period", func() {
+			tick()
+			tick()
+
+			executorClient.ListContainersReturns([]executor.Container{lrp, task}, nil)
+			tick()
+
+			executorClient.ListContainersReturns([]executor.Container{orphan, lrp, task}, nil)
+			tick()
+			tick()
+			Expect(executorClient.DeleteContainerCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when listing containers fails", func() {
+		BeforeEach(func() {
+			executorClient.ListContainersReturns(nil, errors.New("boom"))
+		})
+
+		It("does not emit a metric", func() {
+			fakeClock.Increment(interval)
+			Eventually(executorClient.ListContainersCallCount).Should(Equal(1))
+			Consistently(fakeMetronClient.SendMetricCallCount).Should(Equal(0))
+		})
+	})
+})
+
+var _ = Describe("OrphanReason", func() {
+	var lifecycles *rep.LifecycleRegistry
+
+	BeforeEach(func() {
+		lifecycles = newLifecycleRegistry()
+	})
+
+	It("is empty for registered lifecycles", func() {
+		Expect(reaper.OrphanReason(executor.Container{Tags: executor.Tags{rep.LifecycleTag: rep.TaskLifecycle}}, "cell-id", lifecycles)).To(BeEmpty())
+		Expect(reaper.OrphanReason(executor.Container{Tags: executor.Tags{rep.LifecycleTag: rep.CanaryLifecycle}}, "cell-id", lifecycles)).To(BeEmpty())
+		Expect(reaper.OrphanReason(executor.Container{Tags: executor.Tags{rep.LifecycleTag: rep.DaemonLifecycle}}, "cell-id", lifecycles)).To(BeEmpty())
+	})
+
+	It("is empty for lifecycles registered by extensions", func() {
+		lifecycles.RegisterProcessor("banana", rep.ContainerProcessorFunc(func(lager.Logger, executor.Container) {}))
+		Expect(reaper.OrphanReason(executor.Container{Tags: executor.Tags{rep.LifecycleTag: "banana"}}, "cell-id", lifecycles)).To(BeEmpty())
+	})
+
+	It("reports a missing lifecycle tag", func() {
+		Expect(reaper.OrphanReason(executor.Container{}, "cell-id", lifecycles)).To(Equal(reaper.ReasonMissingLifecycle))
+	})
+
+	It("reports an unknown lifecycle", func() {
+		Expect(reaper.OrphanReason(executor.Container{Tags: executor.Tags{rep.LifecycleTag: "banana"}}, "cell-id", lifecycles)).To(Equal(reaper.ReasonUnknownLifecycle))
+	})
+
+	It("reports LRP containers with incomplete tags", func() {
+		Expect(reaper.OrphanReason(executor.Container{Tags: executor.Tags{rep.LifecycleTag: rep.LRPLifecycle}}, "cell-id", lifecycles)).To(Equal(reaper.ReasonInvalidLRPTags))
+	})
+})
+
+func newLifecycleRegistry() *rep.LifecycleRegistry {
+	lifecycles := rep.NewLifecycleRegistry()
+	noop := rep.ContainerProcessorFunc(func(lager.Logger, executor.Container) {})
+	for _, lifecycle := range []string{rep.LRPLifecycle, rep.TaskLifecycle, rep.CanaryLifecycle, rep.DaemonLifecycle} {
+		lifecycles.RegisterProcessor(lifecycle, noop)
+	}
+	return lifecycles
+}
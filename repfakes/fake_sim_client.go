@@ -64,6 +64,19 @@ type FakeSimClient struct {
 	cancelTaskReturnsOnCall map[int]struct {
 		result1 error
 	}
+	CancelTaskWithGracePeriodStub        func(logger lager.Logger, taskGuid string, gracePeriod time.Duration) error
+	cancelTaskWithGracePeriodMutex       sync.RWMutex
+	cancelTaskWithGracePeriodArgsForCall []struct {
+		logger      lager.Logger
+		taskGuid    string
+		gracePeriod time.Duration
+	}
+	cancelTaskWithGracePeriodReturns struct {
+		result1 error
+	}
+	cancelTaskWithGracePeriodReturnsOnCall map[int]struct {
+		result1 error
+	}
 	SetStateClientStub        func(stateClient *http.Client)
 	setStateClientMutex       sync.RWMutex
 	setStateClientArgsForCall []struct {
@@ -293,6 +306,56 @@ func (fake *FakeSimClient) CancelTaskReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeSimClient) CancelTaskWithGracePeriod(logger lager.Logger, taskGuid string, gracePeriod time.Duration) error {
+	fake.cancelTaskWithGracePeriodMutex.Lock()
+	ret, specificReturn := fake.cancelTaskWithGracePeriodReturnsOnCall[len(fake.cancelTaskWithGracePeriodArgsForCall)]
+	fake.cancelTaskWithGracePeriodArgsForCall = append(fake.cancelTaskWithGracePeriodArgsForCall, struct {
+		logger      lager.Logger
+		taskGuid    string
+		gracePeriod time.Duration
+	}{logger, taskGuid, gracePeriod})
+	fake.recordInvocation("CancelTaskWithGracePeriod", []interface{}{logger, taskGuid, gracePeriod})
+	fake.cancelTaskWithGracePeriodMutex.Unlock()
+	if fake.CancelTaskWithGracePeriodStub != nil {
+		return fake.CancelTaskWithGracePeriodStub(logger, taskGuid, gracePeriod)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.cancelTaskWithGracePeriodReturns.result1
+}
+
+func (fake *FakeSimClient) CancelTaskWithGracePeriodCallCount() int {
+	fake.cancelTaskWithGracePeriodMutex.RLock()
+	defer fake.cancelTaskWithGracePeriodMutex.RUnlock()
+	return len(fake.cancelTaskWithGracePeriodArgsForCall)
+}
+
+func (fake *FakeSimClient) CancelTaskWithGracePeriodArgsForCall(i int) (lager.Logger, string, time.Duration) {
+	fake.cancelTaskWithGracePeriodMutex.RLock()
+	defer fake.cancelTaskWithGracePeriodMutex.RUnlock()
+	return fake.cancelTaskWithGracePeriodArgsForCall[i].logger, fake.cancelTaskWithGracePeriodArgsForCall[i].taskGuid, fake.cancelTaskWithGracePeriodArgsForCall[i].gracePeriod
+}
+
+func (fake *FakeSimClient) CancelTaskWithGracePeriodReturns(result1 error) {
+	fake.CancelTaskWithGracePeriodStub = nil
+	fake.cancelTaskWithGracePeriodReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeSimClient) CancelTaskWithGracePeriodReturnsOnCall(i int, result1 error) {
+	fake.CancelTaskWithGracePeriodStub = nil
+	if fake.cancelTaskWithGracePeriodReturnsOnCall == nil {
+		fake.cancelTaskWithGracePeriodReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.cancelTaskWithGracePeriodReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeSimClient) SetStateClient(stateClient *http.Client) {
 	fake.setStateClientMutex.Lock()
 	fake.setStateClientArgsForCall = append(fake.setStateClientArgsForCall, struct {
@@ -408,6 +471,8 @@ func (fake *FakeSimClient) Invocations() map[string][][]interface{} {
 	defer fake.stopLRPInstanceMutex.RUnlock()
 	fake.cancelTaskMutex.RLock()
 	defer fake.cancelTaskMutex.RUnlock()
+	fake.cancelTaskWithGracePeriodMutex.RLock()
+	defer fake.cancelTaskWithGracePeriodMutex.RUnlock()
 	fake.setStateClientMutex.RLock()
 	defer fake.setStateClientMutex.RUnlock()
 	fake.stateClientTimeoutMutex.RLock()
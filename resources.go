@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"sort"
 	"strings"
+	"time"
 
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/executor/containermetrics"
@@ -27,6 +28,32 @@ type CellState struct {
 	VolumeDrivers          []string
 	PlacementTags          []string
 	OptionalPlacementTags  []string
+	DomainUsage            map[string]Resources `json:"domain_usage,omitempty"`
+	Canary                 *CanaryResult        `json:"canary,omitempty"`
+	Daemons                []Daemon             `json:"daemons,omitempty"`
+}
+
+// CanaryResult describes the outcome of the most recent canary container run
+// by the cell to check that workloads can be started.
+type CanaryResult struct {
+	Healthy             bool          `json:"healthy"`
+	Succeeded           bool          `json:"succeeded"`
+	Error               string        `json:"error,omitempty"`
+	StartedAt           time.Time     `json:"started_at"`
+	StartDuration       time.Duration `json:"start_duration,omitempty"`
+	TotalDuration       time.Duration `json:"total_duration"`
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+}
+
+// Daemon describes a system daemon container that the rep runs on the cell
+// outside of the auction. Its resources are reserved from the cell's
+// capacity whether or not the container currently exists.
+type Daemon struct {
+	Name          string `json:"name"`
+	ContainerGuid string `json:"container_guid"`
+	State         string `json:"state"`
+	MemoryMB      int32  `json:"memory_mb"`
+	DiskMB        int32  `json:"disk_mb"`
 }
 
 func NewCellState(
@@ -203,6 +230,12 @@ func (r *Resources) Subtract(res *Resource) {
 	r.Containers -= 1
 }
 
+func (r *Resources) Add(res *Resource) {
+	r.MemoryMB += res.MemoryMB
+	r.DiskMB += res.DiskMB
+	r.Containers += 1
+}
+
 func (r *Resources) ComputeScore(total *Resources) float64 {
 	fractionUsedMemory := 1.0 - float64(r.MemoryMB)/float64(total.MemoryMB)
 	fractionUsedDisk := 1.0 - float64(r.DiskMB)/float64(total.DiskMB)
@@ -210,6 +243,35 @@ func (r *Resources) ComputeScore(total *Resources) float64 {
 	return (fractionUsedMemory + fractionUsedDisk + fractionUsedContainers) / 3.0
 }
 
+// DomainQuota caps the resources that workloads of a single domain may
+// consume on a cell. A zero value for any field means that field is not
+// limited.
+type DomainQuota struct {
+	MemoryMB   int32 `json:"memory_mb,omitempty"`
+	DiskMB     int32 `json:"disk_mb,omitempty"`
+	Containers int   `json:"containers,omitempty"`
+}
+
+type DomainQuotas map[string]DomainQuota
+
+// Exceeds returns the resources for which usage would exceed the quota if res
+// were added to it.
+func (q DomainQuota) Exceeds(usage Resources, res *Resource) []string {
+	problems := []string{}
+
+	if q.MemoryMB > 0 && usage.MemoryMB+res.MemoryMB > q.MemoryMB {
+		problems = append(problems, "memory")
+	}
+	if q.DiskMB > 0 && usage.DiskMB+res.DiskMB > q.DiskMB {
+		problems = append(problems, "disk")
+	}
+	if q.Containers > 0 && usage.Containers+1 > q.Containers {
+		problems = append(problems, "containers")
+	}
+
+	return problems
+}
+
 type Resource struct {
 	MemoryMB int32
 	DiskMB   int32
@@ -182,6 +182,47 @@ var _ = Describe("Resources", func() {
 			})
 		})
 	})
+
+	Describe("DomainQuota", func() {
+		var (
+			quota    rep.DomainQuota
+			usage    rep.Resources
+			resource rep.Resource
+		)
+
+		BeforeEach(func() {
+			quota = rep.DomainQuota{MemoryMB: 100, DiskMB: 200, Containers: 3}
+			usage = rep.NewResources(50, 100, 2)
+			resource = rep.NewResource(10, 10, 10)
+		})
+
+		Context("when the resource fits within the quota", func() {
+			It("reports no problems", func() {
+				Expect(quota.Exceeds(usage, &resource)).To(BeEmpty())
+			})
+		})
+
+		Context("when the resource would exceed the quota", func() {
+			BeforeEach(func() {
+				usage = rep.NewResources(95, 195, 3)
+			})
+
+			It("reports every exceeded resource", func() {
+				Expect(quota.Exceeds(usage, &resource)).To(ConsistOf("memory", "disk", "containers"))
+			})
+		})
+
+		Context("when a limit is zero", func() {
+			BeforeEach(func() {
+				quota = rep.DomainQuota{}
+				usage = rep.NewResources(1000, 1000, 1000)
+			})
+
+			It("does not limit that resource", func() {
+				Expect(quota.Exceeds(usage, &resource)).To(BeEmpty())
+			})
+		})
+	})
 })
 
 func buildLRP(instanceGuid,
@@ -14,6 +14,11 @@ const (
 
 	PingRoute     = "Ping"
 	EvacuateRoute = "Evacuate"
+	HealthRoute   = "Health"
+
+	ReconciliationReportRoute = "ReconciliationReport"
+	OperationHistoryRoute     = "OperationHistory"
+	SyncRoute                 = "Sync"
 )
 
 func NewRoutes(networkAccessible bool) rata.Routes {
@@ -34,6 +39,10 @@ func NewRoutes(networkAccessible bool) rata.Routes {
 		routes = append(routes,
 			rata.Route{Path: "/ping", Method: "GET", Name: PingRoute},
 			rata.Route{Path: "/evacuate", Method: "POST", Name: EvacuateRoute},
+			rata.Route{Path: "/health", Method: "GET", Name: HealthRoute},
+			rata.Route{Path: "/reconciliation", Method: "GET", Name: ReconciliationReportRoute},
+			rata.Route{Path: "/operations", Method: "GET", Name: OperationHistoryRoute},
+			rata.Route{Path: "/sync", Method: "POST", Name: SyncRoute},
 		)
 	}
 	return routes
@@ -0,0 +1,24 @@
+package simulation
+
+import (
+	"code.cloudfoundry.org/rep/auctioncellrep"
+)
+
+// CellRep is an AuctionCellRep backed by a simulated executor. Unlike a real
+// cell it can be reset, which removes all of its containers.
+type CellRep struct {
+	*auctioncellrep.AuctionCellRep
+	executor *Executor
+}
+
+func NewCellRep(cellRep *auctioncellrep.AuctionCellRep, executor *Executor) *CellRep {
+	return &CellRep{
+		AuctionCellRep: cellRep,
+		executor:       executor,
+	}
+}
+
+func (r *CellRep) Reset() error {
+	r.executor.Reset()
+	return nil
+}
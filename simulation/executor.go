@@ -0,0 +1,164 @@
+package simulation
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/executor/containermetrics"
+	"code.cloudfoundry.org/lager"
+)
+
+// Executor is an in-memory stand-in for the executor, holding containers
+// without running anything. Allocated containers are immediately reported as
+// running. Only the methods needed to serve the auction and presence are
+// implemented; calling any other executor.Client method panics.
+type Executor struct {
+	executor.Client
+
+	clock             clock.Clock
+	totalResources    executor.ExecutorResources
+	volumeDrivers     []string
+	allocationLatency time.Duration
+
+	lock       sync.RWMutex
+	containers map[string]executor.Container
+}
+
+func NewExecutor(
+	clock clock.Clock,
+	totalResources executor.ExecutorResources,
+	volumeDrivers []string,
+	allocationLatency time.Duration,
+) *Executor {
+	return &Executor{
+		clock:             clock,
+		totalResources:    totalResources,
+		volumeDrivers:     volumeDrivers,
+		allocationLatency: allocationLatency,
+		containers:        map[string]executor.Container{},
+	}
+}
+
+func (e *Executor) Ping(logger lager.Logger) error {
+	return nil
+}
+
+func (e *Executor) Healthy(logger lager.Logger) bool {
+	return true
+}
+
+func (e *Executor) AllocateContainers(logger lager.Logger, requests []executor.AllocationRequest) []executor.AllocationFailure {
+	if e.allocationLatency > 0 {
+		e.clock.Sleep(e.allocationLatency)
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	failures := []executor.AllocationFailure{}
+	for i := range requests {
+		request := &requests[i]
+
+		if _, exists := e.containers[request.Guid]; exists {
+			failures = append(failures, executor.NewAllocationFailure(request, executor.ErrContainerGuidNotAvailable.Error()))
+			continue
+		}
+
+		remaining := e.remainingResources()
+		if remaining.Containers < 1 || remaining.MemoryMB < request.MemoryMB || remaining.DiskMB < request.DiskMB {
+			failures = append(failures, executor.NewAllocationFailure(request, executor.ErrInsufficientResourcesAvailable.Error()))
+			continue
+		}
+
+		e.containers[request.Guid] = executor.Container{
+			Guid:     request.Guid,
+			Resource: request.Resource,
+			Tags:     request.Tags,
+			State:    executor.StateRunning,
+		}
+	}
+
+	return failures
+}
+
+func (e *Executor) GetContainer(logger lager.Logger, guid string) (executor.Container, error) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	container, ok := e.containers[guid]
+	if !ok {
+		return executor.Container{}, executor.ErrContainerNotFound
+	}
+	return container, nil
+}
+
+func (e *Executor) ListContainers(logger lager.Logger) ([]executor.Container, error) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	containers := make([]executor.Container, 0, len(e.containers))
+	for _, container := range e.containers {
+		containers = append(containers, container)
+	}
+	return containers, nil
+}
+
+// StopContainer removes the container right away, since nothing needs to
+// observe it completing.
+func (e *Executor) StopContainer(logger lager.Logger, guid string) error {
+	return e.DeleteContainer(logger, guid)
+}
+
+func (e *Executor) DeleteContainer(logger lager.Logger, guid string) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if _, ok := e.containers[guid]; !ok {
+		return executor.ErrContainerNotFound
+	}
+	delete(e.containers, guid)
+	return nil
+}
+
+func (e *Executor) TotalResources(logger lager.Logger) (executor.ExecutorResources, error) {
+	return e.totalResources, nil
+}
+
+func (e *Executor) RemainingResources(logger lager.Logger) (executor.ExecutorResources, error) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	return e.remainingResources(), nil
+}
+
+func (e *Executor) VolumeDrivers(logger lager.Logger) ([]string, error) {
+	return e.volumeDrivers, nil
+}
+
+func (e *Executor) Cleanup(logger lager.Logger) {}
+
+// Reset removes every container.
+func (e *Executor) Reset() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.containers = map[string]executor.Container{}
+}
+
+// Metrics reports no container metrics, as simulated containers use no
+// resources.
+func (e *Executor) Metrics() map[string]*containermetrics.CachedContainerMetrics {
+	return map[string]*containermetrics.CachedContainerMetrics{}
+}
+
+func (e *Executor) remainingResources() executor.ExecutorResources {
+	remaining := e.totalResources
+	for _, container := range e.containers {
+		remaining.MemoryMB -= container.MemoryMB
+		remaining.DiskMB -= container.DiskMB
+		remaining.Containers--
+	}
+	return remaining
+}
@@ -0,0 +1,129 @@
+package simulation_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/rep/simulation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Executor", func() {
+	var (
+		logger    *lagertest.TestLogger
+		fakeClock *fakeclock.FakeClock
+		latency   time.Duration
+		simExec   *simulation.Executor
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		latency = 0
+	})
+
+	JustBeforeEach(func() {
+		simExec = simulation.NewExecutor(
+			fakeClock,
+			executor.ExecutorResources{MemoryMB: 1024, DiskMB: 2048, Containers: 2},
+			[]string{"some-driver"},
+			latency,
+		)
+	})
+
+	allocationRequest := func(guid string, memoryMB, diskMB int) executor.AllocationRequest {
+		resource := executor.NewResource(memoryMB, diskMB, 10, "rootfs")
+		return executor.NewAllocationRequest(guid, &resource, nil)
+	}
+
+	It("reports its total resources and volume drivers", func() {
+		total, err := simExec.TotalResources(logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(total).To(Equal(executor.ExecutorResources{MemoryMB: 1024, DiskMB: 2048, Containers: 2}))
+
+		drivers, err := simExec.VolumeDrivers(logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drivers).To(ConsistOf("some-driver"))
+	})
+
+	Describe("AllocateContainers", func() {
+		It("holds allocated containers as running and deducts their resources", func() {
+			failures := simExec.AllocateContainers(logger, []executor.AllocationRequest{
+				allocationRequest("guid-1", 512, 1024),
+			})
+			Expect(failures).To(BeEmpty())
+
+			container, err := simExec.GetContainer(logger, "guid-1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(container.State).To(Equal(executor.StateRunning))
+
+			remaining, err := simExec.RemainingResources(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remaining).To(Equal(executor.ExecutorResources{MemoryMB: 512, DiskMB: 1024, Containers: 1}))
+		})
+
+		It("fails requests that do not fit or reuse a guid", func() {
+			failures := simExec.AllocateContainers(logger, []executor.AllocationRequest{
+				allocationRequest("guid-1", 512, 1024),
+				allocationRequest("guid-1", 1, 1),
+				allocationRequest("guid-2", 1024, 1),
+			})
+			Expect(failures).To(HaveLen(2))
+			Expect(failures[0].ErrorMsg).To(Equal(executor.ErrContainerGuidNotAvailable.Error()))
+			Expect(failures[1].ErrorMsg).To(Equal(executor.ErrInsufficientResourcesAvailable.Error()))
+
+			containers, err := simExec.ListContainers(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(containers).To(HaveLen(1))
+		})
+
+		Context("when an allocation latency is configured", func() {
+			BeforeEach(func() {
+				latency = time.Second
+			})
+
+			It("waits before allocating", func() {
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					simExec.AllocateContainers(logger, []executor.AllocationRequest{
+						allocationRequest("guid-1", 1, 1),
+					})
+				}()
+
+				Eventually(fakeClock.WatcherCount).Should(Equal(1))
+				Consistently(done).ShouldNot(BeClosed())
+				fakeClock.Increment(time.Second)
+				Eventually(done).Should(BeClosed())
+			})
+		})
+	})
+
+	Describe("removing containers", func() {
+		JustBeforeEach(func() {
+			Expect(simExec.AllocateContainers(logger, []executor.AllocationRequest{
+				allocationRequest("guid-1", 1, 1),
+				allocationRequest("guid-2", 1, 1),
+			})).To(BeEmpty())
+		})
+
+		It("deletes stopped containers", func() {
+			Expect(simExec.StopContainer(logger, "guid-1")).To(Succeed())
+			_, err := simExec.GetContainer(logger, "guid-1")
+			Expect(err).To(Equal(executor.ErrContainerNotFound))
+
+			Expect(simExec.DeleteContainer(logger, "guid-1")).To(Equal(executor.ErrContainerNotFound))
+		})
+
+		It("removes every container on reset", func() {
+			simExec.Reset()
+			containers, err := simExec.ListContainers(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(containers).To(BeEmpty())
+		})
+	})
+})
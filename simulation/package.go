@@ -0,0 +1 @@
+package simulation // import "code.cloudfoundry.org/rep/simulation"